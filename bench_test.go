@@ -36,7 +36,7 @@ func BenchmarkParallelEval(b *testing.B) {
 	n := 100000
 	units := make([]*Unit, n)
 	for i := 0; i < n; i++ {
-		units[i] = NewUnitFromConfig(config)
+		units[i] = defaultMutator.NewUnit(config)
 	}
 
 	cpus := runtime.NumCPU()
@@ -80,7 +80,7 @@ func BenchmarkParallelEval(b *testing.B) {
 				defer wg.Done()
 				var local []*Unit
 				for _, unit := range chunk {
-					local = append(local, unit.Mitosis(nil, nil))
+					local = append(local, unit.Mitosis(nil, nil, nil))
 				}
 				chunks[idx].units = local
 			}(i, units[start:end])
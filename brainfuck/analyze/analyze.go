@@ -0,0 +1,358 @@
+package analyze
+
+import (
+	"fmt"
+
+	bf "nickandperla.net/brainfuck"
+)
+
+// Static analysis of BF programs. Nothing in here runs a Machine; the
+// analyzer walks the program once, tracking what it can prove about the
+// memory pointer and the current cell, and gives up (UNKNOWN) as soon as the
+// answer would depend on the input. Anything it does claim holds for every
+// input, every memory size, cell width and overflow mode.
+
+// NO_INDEX marks a tape index that doesn't exist, e.g. no op ever fails.
+const NO_INDEX = -1
+
+// Abstract cell values. Anything >= 0 is an exact value.
+const (
+	UNKNOWN = -2
+	NONZERO = -1
+)
+
+// Exact values are only tracked up to the smallest cell maximum, so an
+// increment never has to guess the overflow mode.
+const maxExact = 255
+
+type Loop struct {
+	Open     int  // Tape index of the OP_WHILE
+	Close    int  // Tape index of the matching OP_WHILE_END
+	Drift    int  // Net pointer movement of the body's own ops, nested loops excluded
+	Stable   bool // The pointer provably ends each pass where it started
+	Infinite bool // Once entered the loop never exits normally; it fails or hits the execution limit
+	bookmark bool // The body stores or jumps to the bookmark
+}
+
+type Report struct {
+	Length          int
+	Balanced        bool
+	UnmatchedOpens  []int // Tape indexes of OP_WHILE without a partner
+	UnmatchedCloses []int // Tape indexes of OP_WHILE_END without a partner
+	Loops           []*Loop
+	PointerDrift    int // Net OP_POINTER_RIGHT minus OP_POINTER_LEFT over the whole program
+	BookmarkStores  int
+	BookmarkJumps   int
+	BookmarkSelects int  // OP_NEXT_BOOKMARK and OP_FIRST_BOOKMARK
+	OpaqueOps       int  // Registered ops the analyzer has no model of
+	JumpBeforeStore bool // An OP_JUMP comes before any OP_BOOKMARK, so it jumps to cell 0
+
+	// AlwaysFails is set when every run of the program ends in an exception,
+	// either on the op at FailIndex or by looping forever in the loop that
+	// opens at FailIndex. Ops from Unreachable onwards can never execute.
+	AlwaysFails bool
+	FailIndex   int
+	Unreachable int
+}
+
+func (r *Report) String() string {
+	return fmt.Sprintf("Length: %d, Balanced: %v, Unmatched opens: %v, Unmatched closes: %v, Loops: %d, Pointer drift: %d, Bookmark stores: %d, Bookmark jumps: %d, Bookmark selects: %d, Opaque ops: %d, Jump before store: %v, Always fails: %v, Fail index: %d, Unreachable: %d",
+		r.Length, r.Balanced, r.UnmatchedOpens, r.UnmatchedCloses, len(r.Loops), r.PointerDrift, r.BookmarkStores, r.BookmarkJumps, r.BookmarkSelects, r.OpaqueOps, r.JumpBeforeStore, r.AlwaysFails, r.FailIndex, r.Unreachable)
+}
+
+// Analyze reports static properties of program. Unknown ops panic, just like
+// they do when compiling or running the program.
+func Analyze(program string) *Report {
+	a := &analyzer{
+		program: program,
+		match:   make([]int, len(program)),
+		loops:   make(map[int]*Loop),
+	}
+	r := &Report{
+		Length:      len(program),
+		FailIndex:   NO_INDEX,
+		Unreachable: NO_INDEX,
+	}
+
+	opens := make([]int, 0, bf.WHILE_STACK_CAP)
+	for i := 0; i < len(program); i++ {
+		a.match[i] = NO_INDEX
+		switch program[i] {
+		case bf.OP_POINTER_LEFT:
+			r.PointerDrift--
+		case bf.OP_POINTER_RIGHT:
+			r.PointerDrift++
+		case bf.OP_INC, bf.OP_DEC, bf.NO_OP, bf.OP_CMP_SWAP:
+		case bf.OP_WHILE:
+			opens = append(opens, i)
+		case bf.OP_WHILE_END:
+			if len(opens) == 0 {
+				r.UnmatchedCloses = append(r.UnmatchedCloses, i)
+				continue
+			}
+			open := opens[len(opens)-1]
+			opens = opens[:len(opens)-1]
+			a.match[open] = i
+			a.match[i] = open
+		case bf.OP_JUMP:
+			if r.BookmarkStores == 0 {
+				r.JumpBeforeStore = true
+			}
+			r.BookmarkJumps++
+		case bf.OP_BOOKMARK:
+			r.BookmarkStores++
+		case bf.OP_NEXT_BOOKMARK, bf.OP_FIRST_BOOKMARK:
+			r.BookmarkSelects++
+		default:
+			if bf.OpBySymbol(program[i]) == nil {
+				panic(fmt.Sprintf("Unknown OP [%v] encountered!", program[i]))
+			}
+			r.OpaqueOps++
+		}
+	}
+	r.UnmatchedOpens = opens
+	r.Balanced = len(r.UnmatchedOpens) == 0 && len(r.UnmatchedCloses) == 0
+
+	for i := 0; i < len(program); i++ {
+		if program[i] == bf.OP_WHILE && a.match[i] != NO_INDEX {
+			r.Loops = append(r.Loops, a.loop(i))
+		}
+	}
+
+	// An empty program fails to fetch its first instruction
+	if len(program) == 0 {
+		r.AlwaysFails = true
+		r.FailIndex = 0
+		return r
+	}
+
+	s := newState(true)
+	if fail := a.walk(0, len(program), s); fail != NO_INDEX {
+		r.AlwaysFails = true
+		r.FailIndex = fail
+		r.Unreachable = fail + 1
+		if program[fail] == bf.OP_WHILE && a.match[fail] != NO_INDEX {
+			r.Unreachable = a.match[fail] + 1
+		}
+		if r.Unreachable >= len(program) {
+			r.Unreachable = NO_INDEX
+		}
+	}
+
+	return r
+}
+
+type analyzer struct {
+	program string
+	match   []int // Tape index of the partner bracket, or NO_INDEX
+	loops   map[int]*Loop
+}
+
+// state is what is known about the Machine at some point of a walk. While
+// the pointer is known, cells holds every known cell by index; once it is
+// lost only the current cell is tracked in cur.
+type state struct {
+	absolute      bool // Indexes are real memory indexes rather than relative to a loop entry
+	ptr           int
+	ptrKnown      bool
+	bookmark      int
+	bookmarkKnown bool
+	cells         map[int]int
+	cur           int
+}
+
+func newState(absolute bool) *state {
+	return &state{
+		absolute:      absolute,
+		ptrKnown:      true,
+		bookmarkKnown: absolute, // The uninitialized bookmark is always zero
+		cells:         make(map[int]int),
+		cur:           UNKNOWN,
+	}
+}
+
+func (s *state) get() int {
+	if !s.ptrKnown {
+		return s.cur
+	}
+	if v, ok := s.cells[s.ptr]; ok {
+		return v
+	}
+	return UNKNOWN
+}
+
+func (s *state) set(v int) {
+	if s.ptrKnown {
+		s.cells[s.ptr] = v
+		return
+	}
+	// A write somewhere unknown may have hit any known cell
+	s.cells = make(map[int]int)
+	s.cur = v
+}
+
+func (s *state) forget() {
+	s.cells = make(map[int]int)
+	s.cur = UNKNOWN
+}
+
+func (s *state) move(delta int) {
+	if s.ptrKnown {
+		s.ptr = s.ptr + delta
+		return
+	}
+	s.cur = UNKNOWN
+}
+
+func (s *state) losePointer() {
+	s.ptrKnown = false
+	s.cur = UNKNOWN
+}
+
+// walk abstractly executes program[from:to] and returns the tape index of the
+// op every run fails on, or NO_INDEX if some run gets through.
+func (a *analyzer) walk(from, to int, s *state) int {
+	for i := from; i < to; i++ {
+		switch a.program[i] {
+		case bf.OP_POINTER_LEFT:
+			if s.absolute && s.ptrKnown && s.ptr == 0 {
+				return i
+			}
+			s.move(-1)
+		case bf.OP_POINTER_RIGHT:
+			s.move(1)
+		case bf.OP_INC:
+			v := s.get()
+			if v >= 0 && v < maxExact {
+				s.set(v + 1)
+			} else {
+				s.set(UNKNOWN)
+			}
+		case bf.OP_DEC:
+			v := s.get()
+			if v > 0 {
+				s.set(v - 1)
+			} else {
+				s.set(UNKNOWN)
+			}
+		case bf.OP_WHILE:
+			v := s.get()
+			close := a.match[i]
+			if close == NO_INDEX {
+				// Entered, the rest of the program just runs inside it.
+				// Skipped, there is no OP_WHILE_END to skip to.
+				if v == 0 {
+					return i
+				}
+				if v == UNKNOWN {
+					s.set(NONZERO)
+				}
+				continue
+			}
+			if v == 0 {
+				i = close
+				continue
+			}
+			loop := a.loop(i)
+			if loop.Infinite {
+				if v != UNKNOWN {
+					return i
+				}
+				// Only runs that skip the loop get any further
+				s.set(0)
+				i = close
+				continue
+			}
+			if !loop.Stable {
+				s.losePointer()
+			}
+			if loop.bookmark {
+				s.bookmarkKnown = false
+			}
+			s.forget()
+			s.set(0)
+			i = close
+		case bf.OP_WHILE_END:
+			// Matched brackets are handled as a whole by their OP_WHILE
+			v := s.get()
+			if v != 0 && v != UNKNOWN {
+				return i
+			}
+			s.set(0)
+		case bf.OP_JUMP:
+			s.ptr, s.bookmark = s.bookmark, s.ptr
+			s.ptrKnown, s.bookmarkKnown = s.bookmarkKnown, s.ptrKnown
+			if !s.ptrKnown {
+				s.cur = UNKNOWN
+			}
+		case bf.OP_BOOKMARK:
+			s.bookmark = s.ptr
+			s.bookmarkKnown = s.ptrKnown
+		case bf.OP_NEXT_BOOKMARK, bf.OP_FIRST_BOOKMARK:
+			// Registers other than the selected one aren't tracked
+			s.bookmarkKnown = false
+		case bf.OP_CMP_SWAP:
+			// Either cell may now hold the other's value
+			if s.ptrKnown && s.bookmarkKnown {
+				delete(s.cells, s.bookmark)
+				s.set(UNKNOWN)
+			} else {
+				s.forget()
+			}
+		case bf.NO_OP:
+		default:
+			// An opaque op may have done anything but fail
+			s.losePointer()
+			s.forget()
+			s.bookmarkKnown = false
+		}
+	}
+	return NO_INDEX
+}
+
+// loop analyzes the loop opening at tape index open by walking its body once
+// from the most general state a pass can start in: the current cell is
+// non-zero and nothing else is known. Whatever holds at the end of that walk
+// holds at the end of every pass.
+func (a *analyzer) loop(open int) *Loop {
+	if loop, ok := a.loops[open]; ok {
+		return loop
+	}
+
+	close := a.match[open]
+	loop := &Loop{Open: open, Close: close}
+	for i := open + 1; i < close; i++ {
+		switch a.program[i] {
+		case bf.OP_POINTER_LEFT:
+			loop.Drift--
+		case bf.OP_POINTER_RIGHT:
+			loop.Drift++
+		case bf.OP_WHILE:
+			i = a.match[i]
+		case bf.OP_JUMP, bf.OP_BOOKMARK, bf.OP_NEXT_BOOKMARK, bf.OP_FIRST_BOOKMARK:
+			loop.bookmark = true
+		case bf.OP_INC, bf.OP_DEC, bf.NO_OP, bf.OP_WHILE_END, bf.OP_CMP_SWAP:
+		default:
+			loop.bookmark = true
+		}
+	}
+
+	s := newState(false)
+	s.cells[0] = NONZERO
+	if fail := a.walk(open+1, close, s); fail != NO_INDEX {
+		loop.Infinite = true
+	} else {
+		loop.Stable = s.ptrKnown && s.ptr == 0
+		v := s.get()
+		loop.Infinite = loop.Stable && v != 0 && v != UNKNOWN
+	}
+	// Nested loops touching the bookmark leave it unknown for this one too
+	for i := open + 1; i < close; i++ {
+		if a.program[i] == bf.OP_WHILE && a.loop(i).bookmark {
+			loop.bookmark = true
+		}
+	}
+
+	a.loops[open] = loop
+	return loop
+}
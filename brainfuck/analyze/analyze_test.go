@@ -0,0 +1,126 @@
+package analyze
+
+import (
+	"reflect"
+	"testing"
+
+	bf "nickandperla.net/brainfuck"
+)
+
+func TestAnalyzeBalance(t *testing.T) {
+	r := Analyze("[[-]]>]<[")
+	if r.Balanced {
+		t.Errorf("Expected unbalanced program")
+	}
+	if !reflect.DeepEqual(r.UnmatchedCloses, []int{6}) {
+		t.Errorf("Unmatched closes [%v], expected [[6]]", r.UnmatchedCloses)
+	}
+	if !reflect.DeepEqual(r.UnmatchedOpens, []int{8}) {
+		t.Errorf("Unmatched opens [%v], expected [[8]]", r.UnmatchedOpens)
+	}
+	if len(r.Loops) != 2 || r.Loops[0].Open != 0 || r.Loops[0].Close != 4 || r.Loops[1].Open != 1 || r.Loops[1].Close != 3 {
+		t.Errorf("Unexpected loops %v", r.Loops)
+	}
+
+	for _, p := range bf.PREFAB_OPSETS {
+		if r := Analyze(p); !r.Balanced {
+			t.Errorf("Prefab [%s] reported unbalanced", p)
+		}
+	}
+}
+
+func TestAnalyzeDriftAndBookmarks(t *testing.T) {
+	r := Analyze(bf.SWAP_LEFT)
+	if r.PointerDrift != 2 {
+		t.Errorf("Pointer drift [%d], expected [2]", r.PointerDrift)
+	}
+	if r.BookmarkStores != 1 || r.BookmarkJumps != 6 || r.JumpBeforeStore {
+		t.Errorf("Unexpected bookmark usage: %v", r)
+	}
+
+	r = Analyze("^>*")
+	if !r.JumpBeforeStore {
+		t.Errorf("Expected jump before store")
+	}
+
+	r = Analyze("[>>+<]")
+	if r.Loops[0].Drift != 1 || r.Loops[0].Stable {
+		t.Errorf("Unexpected loop %+v", r.Loops[0])
+	}
+}
+
+func TestAnalyzeInfiniteLoops(t *testing.T) {
+	tests := []struct {
+		program  string
+		infinite bool
+	}{
+		{"[]", true},
+		{"[#*]", true},
+		{"[><]", true},
+		{"[[-]+]", true},
+		{"[>+<]", true},
+		{"[-]", false},
+		{"[>]", false},
+		{"[-^+^]", false},
+		{"[+]", false},
+		{"[[-]]", false},
+	}
+
+	for _, tt := range tests {
+		r := Analyze(tt.program)
+		if r.Loops[0].Infinite != tt.infinite {
+			t.Errorf("Program [%s] infinite [%v], expected [%v]", tt.program, r.Loops[0].Infinite, tt.infinite)
+		}
+	}
+}
+
+func TestAnalyzeAlwaysFails(t *testing.T) {
+	tests := []struct {
+		program     string
+		fails       bool
+		failIndex   int
+		unreachable int
+	}{
+		{"", true, 0, NO_INDEX},
+		{"<+>", true, 0, 1},
+		{">><<<", true, 4, NO_INDEX},
+		{"[-]+[]>>", true, 4, 6},
+		{"[-]+]+", true, 4, 5},
+		{"[-][", true, 3, NO_INDEX},
+		{"[-]]+", false, NO_INDEX, NO_INDEX},
+		{"[]+", false, NO_INDEX, NO_INDEX},
+		{"+[]", false, NO_INDEX, NO_INDEX},
+		{"[>]<<", false, NO_INDEX, NO_INDEX},
+		{bf.SWAP_RIGHT, false, NO_INDEX, NO_INDEX},
+		{"[-]+>[-]<*>%<[]", false, NO_INDEX, NO_INDEX},
+	}
+
+	for _, tt := range tests {
+		r := Analyze(tt.program)
+		if r.AlwaysFails != tt.fails || r.FailIndex != tt.failIndex || r.Unreachable != tt.unreachable {
+			t.Errorf("Program [%s] fails [%v] at [%d] unreachable from [%d], expected [%v] at [%d] from [%d]",
+				tt.program, r.AlwaysFails, r.FailIndex, r.Unreachable, tt.fails, tt.failIndex, tt.unreachable)
+		}
+	}
+}
+
+// Whatever the analyzer claims must hold on a real Machine
+func TestAnalyzeAgreesWithMachine(t *testing.T) {
+	programs := []string{"", "<+>", "[-]+[]>>", "[-]+]+", "[-][", "[-]]+", "+[]", "[>]<<", "[[-]+]", "[-]+>[-]<*>%<[]"}
+	inputs := [][]uint32{{0, 0, 0}, {1, 2, 3}, {255, 0, 7}}
+
+	for _, p := range programs {
+		r := Analyze(p)
+		if !r.AlwaysFails {
+			continue
+		}
+		for _, input := range inputs {
+			m := bf.NewMachine(&bf.MachineConfig{MaxInstructionExecutionCount: 1000, MemoryCellCount: 3})
+			m.LoadProgram(p)
+			m.LoadMemory(input)
+			if ok, _ := m.Run(); ok {
+				t.Errorf("Program [%s] reported to always fail, but ran on input %v", p, input)
+			}
+		}
+	}
+}
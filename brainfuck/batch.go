@@ -0,0 +1,92 @@
+package brainfuck
+
+// A Batch runs one compiled Program over many inputs on a single Machine.
+// The program is compiled once and every run starts from LoadCompiled, so
+// runs share all decoding work. Results and their outputs live in buffers
+// owned by the Batch that are only grown, never reallocated per run, so a
+// warmed up Batch allocates nothing unless a run fails (for its error) or the
+// Machine is profiling (for the copy of the Profile).
+//
+// With BACKEND_CLOSURE the program is also closure compiled, once per
+// program, unless the Machine is profiling, which only the interpreter does.
+//
+// Inputs load at InputOffset and outputs are read from OutputOffset. With
+// ReadOnlyInput set, each run's input cells are read only for that run;
+// otherwise all of memory is writable.
+type Batch struct {
+	Machine       *Machine
+	Backend       Backend
+	InputOffset   uint
+	OutputOffset  uint
+	ReadOnlyInput bool
+	Results       []BatchResult
+	outputs       []uint32
+	closures      *ClosureProgram
+}
+
+type BatchResult struct {
+	Ok               bool
+	Err              error    // The exception that halted the run, if any
+	InstructionCount uint     // Source ops executed
+	Output           []uint32 // The output cells after the run
+	Profile          *Profile // Copy of Machine.Profile after the run, nil unless profiling
+}
+
+func NewBatch(m *Machine) *Batch {
+	return &Batch{Machine: m}
+}
+
+// Run loads program, runs it once per input and returns a result per input.
+// The returned results, outputs included, are only valid until the next Run.
+func (b *Batch) Run(program *Program, inputs [][]uint32, outputCells uint) ([]BatchResult, error) {
+	m := b.Machine
+	if b.OutputOffset+outputCells > uint(len(m.Memory.Cells)) {
+		_, _, err := m.ReadMemoryAt(b.OutputOffset, outputCells)
+		return nil, err
+	}
+
+	if cap(b.Results) < len(inputs) {
+		b.Results = make([]BatchResult, len(inputs))
+	}
+	b.Results = b.Results[:len(inputs)]
+	size := uint(len(inputs)) * outputCells
+	if uint(cap(b.outputs)) < size {
+		b.outputs = make([]uint32, size)
+	}
+	b.outputs = b.outputs[:size]
+
+	closures := b.Backend == BACKEND_CLOSURE && m.Profile == nil
+	if closures && (b.closures == nil || b.closures.Program != program) {
+		b.closures = CompileClosures(program)
+	}
+
+	for i, input := range inputs {
+		m.LoadCompiled(program)
+		if ok, err := m.LoadMemoryAt(b.InputOffset, input); !ok {
+			return nil, err
+		}
+		if b.ReadOnlyInput {
+			m.SetReadOnly(b.InputOffset, uint(len(input)))
+		} else {
+			m.SetReadOnly(0, 0)
+		}
+		var ok bool
+		var err error
+		if closures {
+			ok, err = b.closures.Run(m)
+		} else {
+			ok, err = m.Run()
+		}
+
+		r := &b.Results[i]
+		r.Ok, r.Err, r.InstructionCount = ok, err, m.InstructionCount
+		r.Output = b.outputs[uint(i)*outputCells : uint(i+1)*outputCells]
+		copy(r.Output, m.Memory.Cells[b.OutputOffset:])
+		r.Profile = nil
+		if m.Profile != nil {
+			r.Profile = m.Profile.Clone()
+		}
+	}
+
+	return b.Results, nil
+}
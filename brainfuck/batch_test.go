@@ -0,0 +1,106 @@
+package brainfuck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBatchRun(t *testing.T) {
+	config := &MachineConfig{MaxInstructionExecutionCount: 200, MemoryCellCount: 4}
+	program := Compile(SWAP_RIGHT + "<")
+	inputs := [][]uint32{{3, 7}, {0, 5}, {9, 2, 0, 1}, {}}
+
+	for _, backend := range []Backend{BACKEND_INTERPRETER, BACKEND_CLOSURE} {
+		batch := NewBatch(NewMachine(config))
+		batch.Backend = backend
+		results, err := batch.Run(program, inputs, 3)
+		if err != nil {
+			t.Fatalf("Unexpected failure running batch. %v", err)
+		}
+		if len(results) != len(inputs) {
+			t.Fatalf("Batch returned %d results for %d inputs", len(results), len(inputs))
+		}
+
+		// Every result must match a run of its own
+		for i, input := range inputs {
+			m := NewMachine(config)
+			m.LoadCompiled(program)
+			m.LoadMemory(input)
+			ok, err := m.Run()
+
+			r := results[i]
+			if r.Ok != ok || !reflect.DeepEqual(r.Err, err) || r.InstructionCount != m.InstructionCount || !reflect.DeepEqual(r.Output, m.Memory.Cells[:3]) {
+				t.Errorf("%v batch result %d {%v %v %d %v} differs from a single run {%v %v %d %v}",
+					backend, i, r.Ok, r.Err, r.InstructionCount, r.Output, ok, err, m.InstructionCount, m.Memory.Cells[:3])
+			}
+		}
+	}
+
+	if _, err := NewBatch(NewMachine(config)).Run(program, inputs, 5); err == nil {
+		t.Errorf("Unexpected success reading more output cells than memory has")
+	}
+	if _, err := NewBatch(NewMachine(config)).Run(program, [][]uint32{{1, 2, 3, 4, 5}}, 1); err == nil {
+		t.Errorf("Unexpected success loading an input larger than memory")
+	}
+}
+
+func TestBatchRunRegions(t *testing.T) {
+	config := &MachineConfig{MaxInstructionExecutionCount: 1000, MemoryCellCount: 6}
+	// Moves the two cells from the pointer on three cells right
+	move := "[->>>+<<<]>[->>>+<<<]"
+	inputs := [][]uint32{{3, 7}, {0, 5}}
+
+	for _, backend := range []Backend{BACKEND_INTERPRETER, BACKEND_CLOSURE} {
+		batch := NewBatch(NewMachine(config))
+		batch.Backend = backend
+		batch.InputOffset, batch.OutputOffset = 1, 4
+		results, err := batch.Run(Compile(">"+move), inputs, 2)
+		if err != nil {
+			t.Fatalf("Unexpected failure running batch. %v", err)
+		}
+		for i, input := range inputs {
+			if !results[i].Ok || !reflect.DeepEqual(results[i].Output, input) {
+				t.Errorf("%v batch result %d {%v %v %v}, expected output %v", backend, i, results[i].Ok, results[i].Err, results[i].Output, input)
+			}
+		}
+
+		batch.InputOffset, batch.OutputOffset, batch.ReadOnlyInput = 0, 3, true
+		results, err = batch.Run(Compile(move), [][]uint32{{3, 7}, {0, 0}}, 2)
+		if err != nil {
+			t.Fatalf("Unexpected failure running batch. %v", err)
+		}
+		if results[0].Ok || ErrorKindOf(results[0].Err) != ERR_READ_ONLY_VIOLATION {
+			t.Errorf("%v batch moved read only input: {%v %v}", backend, results[0].Ok, results[0].Err)
+		}
+		if !results[1].Ok {
+			t.Errorf("%v batch failed on an input it never changes: %v", backend, results[1].Err)
+		}
+
+		batch.ReadOnlyInput = false
+		if results, _ := batch.Run(Compile(move), inputs, 2); !results[0].Ok || !reflect.DeepEqual(results[0].Output, inputs[0]) {
+			t.Errorf("%v batch result {%v %v %v} after read only input was turned off", backend, results[0].Ok, results[0].Err, results[0].Output)
+		}
+	}
+
+	batch := NewBatch(NewMachine(config))
+	batch.OutputOffset = 5
+	if _, err := batch.Run(Compile(move), inputs, 2); err == nil {
+		t.Errorf("Unexpected success reading output cells past the memory end")
+	}
+}
+
+func TestBatchRunDoesNotAllocate(t *testing.T) {
+	program := Compile(SWAP_RIGHT)
+	inputs := [][]uint32{{3, 7}, {5, 1, 4}, {9, 2, 0, 1}}
+
+	for _, backend := range []Backend{BACKEND_INTERPRETER, BACKEND_CLOSURE} {
+		batch := NewBatch(NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 8}))
+		batch.Backend = backend
+		allocs := testing.AllocsPerRun(100, func() {
+			batch.Run(program, inputs, 4)
+		})
+		if allocs != 0 {
+			t.Errorf("%v Batch.Run allocated %v times per call", backend, allocs)
+		}
+	}
+}
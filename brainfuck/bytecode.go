@@ -0,0 +1,70 @@
+package brainfuck
+
+import (
+	"fmt"
+)
+
+// A Program is the compiled form of a string of BF ops. Compiling folds runs
+// of each Foldable op (OP_INC, OP_DEC, OP_POINTER_LEFT and OP_POINTER_RIGHT
+// out of the box) into a single Bytecode and resolves every OP_WHILE/OP_WHILE_END pair up front, so the
+// Machine never has to scan the instructions looking for a bracket.
+
+// NO_MATCH marks a bracket that has no partner in the program. Unbalanced
+// brackets are legal to compile; they only fail at run time when the Machine
+// actually needs to jump through them, same as the Tape.
+const NO_MATCH = -1
+
+type Bytecode struct {
+	Op    byte
+	Count uint // Number of folded source ops. Always 1 for unfolded ops.
+	Jump  int  // Code index of the matching bracket, or NO_MATCH
+	Index int  // Tape index of the first source op folded into this Bytecode
+}
+
+type Program struct {
+	Instructions string
+	Code         []Bytecode
+}
+
+// Compile turns a BF program into bytecode. Unknown ops panic, just like
+// Tape.Execute does when it runs into one.
+func Compile(instructions string) *Program {
+	code := make([]Bytecode, 0, len(instructions))
+	opens := make([]int, 0, WHILE_STACK_CAP)
+
+	for i := 0; i < len(instructions); i++ {
+		o := instructions[i]
+		def := OpBySymbol(o)
+		if def == nil {
+			panic(fmt.Sprintf("Unknown OP [%v] encountered!", o))
+		}
+		switch {
+		case def.Foldable:
+			last := len(code) - 1
+			if last >= 0 && code[last].Op == o {
+				code[last].Count++
+				continue
+			}
+			code = append(code, Bytecode{Op: o, Count: 1, Jump: NO_MATCH, Index: i})
+		case o == OP_WHILE:
+			opens = append(opens, len(code))
+			code = append(code, Bytecode{Op: o, Count: 1, Jump: NO_MATCH, Index: i})
+		case o == OP_WHILE_END:
+			bc := Bytecode{Op: o, Count: 1, Jump: NO_MATCH, Index: i}
+			if len(opens) > 0 {
+				open := opens[len(opens)-1]
+				opens = opens[:len(opens)-1]
+				bc.Jump = open
+				code[open].Jump = len(code)
+			}
+			code = append(code, bc)
+		default:
+			code = append(code, Bytecode{Op: o, Count: 1, Jump: NO_MATCH, Index: i})
+		}
+	}
+
+	return &Program{
+		Instructions: instructions,
+		Code:         code,
+	}
+}
@@ -0,0 +1,55 @@
+package brainfuck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompileFoldsRuns(t *testing.T) {
+	program := Compile("+++>>--<#")
+
+	expected := []Bytecode{
+		{Op: OP_INC, Count: 3, Jump: NO_MATCH, Index: 0},
+		{Op: OP_POINTER_RIGHT, Count: 2, Jump: NO_MATCH, Index: 3},
+		{Op: OP_DEC, Count: 2, Jump: NO_MATCH, Index: 5},
+		{Op: OP_POINTER_LEFT, Count: 1, Jump: NO_MATCH, Index: 7},
+		{Op: NO_OP, Count: 1, Jump: NO_MATCH, Index: 8},
+	}
+
+	if !reflect.DeepEqual(program.Code, expected) {
+		t.Errorf("Compiled code [%v] is not equal to expected [%v]", program.Code, expected)
+	}
+}
+
+func TestCompileMatchesBrackets(t *testing.T) {
+	program := Compile("[[-]>]")
+
+	expected := []Bytecode{
+		{Op: OP_WHILE, Count: 1, Jump: 5, Index: 0},
+		{Op: OP_WHILE, Count: 1, Jump: 3, Index: 1},
+		{Op: OP_DEC, Count: 1, Jump: NO_MATCH, Index: 2},
+		{Op: OP_WHILE_END, Count: 1, Jump: 1, Index: 3},
+		{Op: OP_POINTER_RIGHT, Count: 1, Jump: NO_MATCH, Index: 4},
+		{Op: OP_WHILE_END, Count: 1, Jump: 0, Index: 5},
+	}
+
+	if !reflect.DeepEqual(program.Code, expected) {
+		t.Errorf("Compiled code [%v] is not equal to expected [%v]", program.Code, expected)
+	}
+}
+
+func TestCompileUnbalancedBrackets(t *testing.T) {
+	program := Compile("][[]")
+
+	if program.Code[0].Jump != NO_MATCH {
+		t.Errorf("Unmatched OP_WHILE_END has Jump [%d], expected NO_MATCH", program.Code[0].Jump)
+	}
+
+	if program.Code[1].Jump != NO_MATCH {
+		t.Errorf("Unmatched OP_WHILE has Jump [%d], expected NO_MATCH", program.Code[1].Jump)
+	}
+
+	if program.Code[2].Jump != 3 || program.Code[3].Jump != 2 {
+		t.Errorf("Matched brackets have unexpected jumps [%d] and [%d]", program.Code[2].Jump, program.Code[3].Jump)
+	}
+}
@@ -0,0 +1,257 @@
+package brainfuck
+
+import (
+	"fmt"
+)
+
+// The closure backend compiles a Program once more, into a tree of Go
+// closures: every Bytecode becomes a closure and every matched loop a closure
+// running its body in a Go for loop, so nothing is decoded, dispatched or
+// looked up while the program runs. It pays off when a program runs many
+// times, e.g. once per evaluation round.
+//
+// It runs programs to the end in one go on a Machine's Memory and leaves the
+// same cells, InstructionCount and exception as Machine.Run. It knows nothing
+// about breakpoints, observers, profiles or stepping; use the interpreter
+// for those.
+
+// Backend picks how an Evaluator or a Batch runs programs.
+type Backend uint8
+
+const (
+	BACKEND_INTERPRETER Backend = iota // Machine.Run
+	BACKEND_CLOSURE                    // ClosureProgram.Run
+)
+
+func (b Backend) String() string {
+	switch b {
+	case BACKEND_INTERPRETER:
+		return "interpreter"
+	case BACKEND_CLOSURE:
+		return "closure"
+	}
+	return fmt.Sprintf("Backend(%d)", uint8(b))
+}
+
+func (b Backend) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *Backend) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "interpreter":
+		*b = BACKEND_INTERPRETER
+	case "closure":
+		*b = BACKEND_CLOSURE
+	default:
+		return fmt.Errorf("Unknown backend [%s]. Expected one of interpreter, closure", text)
+	}
+	return nil
+}
+
+type ClosureProgram struct {
+	Program *Program
+	body    []closure
+}
+
+// A closure runs one Bytecode, or a whole loop, and counts the source ops it
+// executed on the Machine.
+type closure func(m *Machine) error
+
+func CompileClosures(program *Program) *ClosureProgram {
+	return &ClosureProgram{Program: program, body: compileClosures(program.Code, 0, len(program.Code))}
+}
+
+// Run runs the program on m from the start, so m should be freshly loaded,
+// and leaves it halted.
+func (c *ClosureProgram) Run(m *Machine) (bool, error) {
+	m.halted = true
+	m.loop = NO_MATCH
+	if len(c.Program.Code) == 0 {
+		return m.runEmpty()
+	}
+	for _, f := range c.body {
+		if err := f(m); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// compileClosures compiles the Bytecode in [from, to). Matched brackets
+// inside the range are always matched inside it.
+func compileClosures(code []Bytecode, from, to int) []closure {
+	closures := make([]closure, 0, to-from)
+	for pc := from; pc < to; pc++ {
+		bc := code[pc]
+		switch {
+		case bc.Op == OP_WHILE && bc.Jump != NO_MATCH:
+			closures = append(closures, loopClosure(bc.Index, code[bc.Jump].Index, compileClosures(code, pc+1, bc.Jump)))
+			pc = bc.Jump
+		case bc.Op == OP_WHILE:
+			closures = append(closures, unmatchedOpenClosure(bc.Index))
+		case bc.Op == OP_WHILE_END:
+			closures = append(closures, unmatchedCloseClosure(bc.Index))
+		case bc.Op == NO_OP:
+			index := bc.Index
+			closures = append(closures, func(m *Machine) error {
+				return account(m, 1, index, nil)
+			})
+		default:
+			closures = append(closures, opClosure(bc))
+		}
+	}
+	return closures
+}
+
+// account counts executed source ops, the last of them at tape index last,
+// and enforces the execution limit the way Machine.cycle does: reaching it
+// takes precedence over err.
+func account(m *Machine, executed uint, last int, err error) error {
+	m.InstructionCount = m.InstructionCount + executed
+	if m.InstructionCount >= m.Config.MaxInstructionExecutionCount {
+		limit := newMachineError(ERR_EXECUTION_LIMIT, last, m.Memory, ErrMaxInstructionExecutionCountReached)
+		limit.Loop = m.loop
+		return limit
+	}
+	return err
+}
+
+// budget returns how many of n folded ops may run before the execution
+// limit, like Machine.execute.
+func budget(m *Machine, n uint) uint {
+	b := m.Config.MaxInstructionExecutionCount - m.InstructionCount
+	if b == 0 {
+		b = 1
+	}
+	if n > b {
+		return b
+	}
+	return n
+}
+
+// The Machine pushes and pops its while stack around loops; a loop closure
+// keeps that state, and its iteration count, on the Go stack instead. m.loop
+// follows the loop the Machine's innermostLoop would report.
+func loopClosure(open, end int, body []closure) closure {
+	return func(m *Machine) error {
+		memory, outer := m.Memory, m.loop
+		if memory.Cells[memory.MemoryPointer] == 0 {
+			return account(m, 1, open, nil)
+		}
+		m.loop = open
+		if err := account(m, 1, open, nil); err != nil {
+			return err
+		}
+		max := m.Config.MaxLoopIterations
+		for iterations := uint(1); ; {
+			for _, f := range body {
+				if err := f(m); err != nil {
+					return err
+				}
+			}
+			if memory.Cells[memory.MemoryPointer] == 0 {
+				m.loop = outer
+				return account(m, 1, end, nil)
+			}
+			if err := account(m, 1, end, nil); err != nil {
+				return err
+			}
+			// Back to the OP_WHILE, which always reenters
+			iterations++
+			if max > 0 && iterations > max {
+				return account(m, 1, open, loopLimitError(open, iterations, memory))
+			}
+			if err := account(m, 1, open, nil); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func unmatchedOpenClosure(index int) closure {
+	return func(m *Machine) error {
+		memory := m.Memory
+		if memory.Cells[memory.MemoryPointer] == 0 {
+			return account(m, 1, index, newMachineError(ERR_UNMATCHED_BRACKET, index, memory, fmt.Errorf("OP_WHILE at tape index [%d] failed to advance to OP_WHILE_END instruction. Tape end reached.", index)))
+		}
+		// The Machine pushes it and never pops it, unmatched brackets are
+		// never inside a matched loop
+		m.loop = index
+		return account(m, 1, index, nil)
+	}
+}
+
+func unmatchedCloseClosure(index int) closure {
+	return func(m *Machine) error {
+		memory := m.Memory
+		if memory.Cells[memory.MemoryPointer] != 0 {
+			return account(m, 1, index, newMachineError(ERR_UNMATCHED_BRACKET, index, memory, fmt.Errorf("OP_WHILE_END at tape index [%d] failed to fallback. Failed to pop while stack.", index)))
+		}
+		return account(m, 1, index, nil)
+	}
+}
+
+// opClosure runs every other op. Folded runs of the built in arithmetic and
+// pointer ops that stay inside their bounds are applied in one step; anything
+// else, and any run that would fail or overflow, goes through the op's Exec
+// one source op at a time so errors and overflow match the Machine exactly.
+func opClosure(bc Bytecode) closure {
+	def := OpBySymbol(bc.Op)
+	if def == nil || def.Exec == nil {
+		panic(fmt.Sprintf("Unknown OP [%v] encountered!", bc.Op))
+	}
+	index, count := bc.Index, bc.Count
+
+	switch bc.Op {
+	case OP_POINTER_LEFT:
+		return func(m *Machine) error {
+			n, memory := budget(m, count), m.Memory
+			if memory.MemoryPointer >= n {
+				memory.MemoryPointer = memory.MemoryPointer - n
+				return account(m, n, index+int(n)-1, nil)
+			}
+			return execEach(m, def, index, n)
+		}
+	case OP_POINTER_RIGHT:
+		return func(m *Machine) error {
+			n, memory := budget(m, count), m.Memory
+			if memory.MemoryPointer+n <= memory.CellCount-1 {
+				memory.MemoryPointer = memory.MemoryPointer + n
+				return account(m, n, index+int(n)-1, nil)
+			}
+			return execEach(m, def, index, n)
+		}
+	case OP_INC:
+		return func(m *Machine) error {
+			n, memory := budget(m, count), m.Memory
+			if val := memory.Cells[memory.MemoryPointer]; uint64(val)+uint64(n) <= uint64(memory.MaxValue) && !memory.ReadOnly(memory.MemoryPointer) {
+				memory.Cells[memory.MemoryPointer] = val + uint32(n)
+				return account(m, n, index+int(n)-1, nil)
+			}
+			return execEach(m, def, index, n)
+		}
+	case OP_DEC:
+		return func(m *Machine) error {
+			n, memory := budget(m, count), m.Memory
+			if val := memory.Cells[memory.MemoryPointer]; uint64(n) <= uint64(val) && !memory.ReadOnly(memory.MemoryPointer) {
+				memory.Cells[memory.MemoryPointer] = val - uint32(n)
+				return account(m, n, index+int(n)-1, nil)
+			}
+			return execEach(m, def, index, n)
+		}
+	}
+	return func(m *Machine) error {
+		return execEach(m, def, index, budget(m, count))
+	}
+}
+
+// execEach runs n source ops of def one at a time, like Machine.execute.
+func execEach(m *Machine, def *OpDef, index int, n uint) error {
+	for k := uint(0); k < n; k++ {
+		if ok, err := def.Exec(m.Memory, index+int(k)); !ok {
+			return account(m, k+1, index+int(k), asMachineError(err, index+int(k), m.Memory))
+		}
+	}
+	return account(m, n, index+int(n)-1, nil)
+}
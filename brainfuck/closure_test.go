@@ -0,0 +1,187 @@
+package brainfuck
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// runTape runs a program on the Tape to the end, counting ops and enforcing
+// the execution limit the way the Machine does.
+func runTape(program string, config *MachineConfig, input []uint32) ([]uint32, uint, error) {
+	tape, memory := NewTape(program), NewMemoryWithWidth(config.MemoryCellCount, config.CellWidth)
+	memory.Overflow = config.OverflowMode
+	memory.SetBookmarkCount(config.BookmarkRegisters)
+	copy(memory.Cells, input)
+
+	var count uint
+	for {
+		index := tape.InstructionPointer
+		ok, err := tape.Execute(memory)
+		count++
+		if count >= config.MaxInstructionExecutionCount {
+			return memory.Cells, count, newMachineError(ERR_EXECUTION_LIMIT, index, memory, ErrMaxInstructionExecutionCountReached)
+		}
+		if !ok {
+			return memory.Cells, count, err
+		}
+		if !tape.Advance() {
+			return memory.Cells, count, nil
+		}
+	}
+}
+
+func runClosures(program string, config *MachineConfig, input []uint32) (*Machine, bool, error) {
+	m := NewMachine(config)
+	m.LoadProgram(program)
+	m.LoadMemory(input)
+	ok, err := CompileClosures(m.Program).Run(m)
+	return m, ok, err
+}
+
+func TestClosureProgramMatchesTape(t *testing.T) {
+	programs := []string{"", "#", "<", ">>>>>>>>", "-", "+]", "[", "+[]", "+[>+]", "[-]+[", "+[-]+]",
+		"+[[]", "++[>+++[>++<-]<-]>>[<<+>>-]", "*>>+++%<<^%", "+&>*&>>*@^-^&^", "++++++++[>++++[>++>+++>+++>+<<<<-]>+>+>->>+[<]<-]"}
+	programs = append(programs, PREFAB_OPSETS[:]...)
+	programs = append(programs, BOOKMARK_PREFAB_OPSETS[:]...)
+	programs = append(programs, CMP_SWAP_PREFAB_OPSETS[:]...)
+
+	r := rand.New(rand.NewSource(15))
+	ops := OpSet(&MachineConfig{BookmarkRegisters: 2, CmpSwap: true})
+	for i := 0; i < 300; i++ {
+		p := make([]byte, 1+r.Intn(80))
+		for j := range p {
+			p[j] = ops[r.Intn(len(ops))]
+		}
+		programs = append(programs, string(p))
+	}
+
+	for i, program := range programs {
+		config := &MachineConfig{
+			MaxInstructionExecutionCount: []uint{0, 1, 9, 100, 5000}[i%5],
+			MemoryCellCount:              uint(1 + r.Intn(10)),
+			OverflowMode:                 OverflowMode(i % 3),
+			CellWidth:                    []CellWidth{CELL_WIDTH_8, CELL_WIDTH_16}[i%2],
+			BookmarkRegisters:            uint(i % 4),
+		}
+		input := make([]uint32, config.MemoryCellCount)
+		for j := range input {
+			input[j] = []uint32{0, 1, 2, config.CellWidth.MaxValue(), uint32(r.Intn(256))}[r.Intn(5)]
+		}
+
+		cells, count, tapeErr := runTape(program, config, input)
+		m, ok, err := runClosures(program, config, input)
+		if ok != (err == nil) {
+			t.Errorf("Program [%s] returned %v with error %v", program, ok, err)
+		}
+		if !reflect.DeepEqual(m.Memory.Cells, cells) || m.InstructionCount != count || fmt.Sprint(err) != fmt.Sprint(tapeErr) {
+			t.Errorf("Program [%s] with %+v on %v\nClosures: %v %d %v\nTape:     %v %d %v",
+				program, config, input, m.Memory.Cells, m.InstructionCount, err, cells, count, tapeErr)
+			continue
+		}
+		var got, want *MachineError
+		if tapeErr != nil && (!errors.As(err, &got) || !errors.As(tapeErr, &want) ||
+			got.Kind != want.Kind || got.TapeIndex != want.TapeIndex || got.MemoryPointer != want.MemoryPointer) {
+			t.Errorf("Program [%s] raised %+v, the Tape raised %+v", program, got, want)
+		}
+	}
+}
+
+func TestClosureProgramMatchesMachine(t *testing.T) {
+	config := &MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 10, OverflowMode: OVERFLOW_WRAP}
+	for _, program := range []string{SWAP_RIGHT, "+++[>+++++<-]>[<++>-]<", "-[>-<---]>"} {
+		m := NewMachine(config)
+		m.LoadProgram(program)
+		m.LoadMemory([]uint32{4, 2})
+		mok, merr := m.Run()
+
+		c, cok, cerr := runClosures(program, config, []uint32{4, 2})
+		if cok != mok || !reflect.DeepEqual(cerr, merr) || c.InstructionCount != m.InstructionCount || !reflect.DeepEqual(c.Memory.Cells, m.Memory.Cells) {
+			t.Errorf("Program [%s] closures {%v %v %d %v} differ from the Machine {%v %v %d %v}",
+				program, cok, cerr, c.InstructionCount, c.Memory.Cells, mok, merr, m.InstructionCount, m.Memory.Cells)
+		}
+		if !c.Halted() {
+			t.Errorf("Program [%s] left the Machine running", program)
+		}
+	}
+}
+
+// TestClosureProgramMatchesMachineLoopLimits checks the limits, and the loop
+// they report, on nested loops stopped at every op and on random loop heavy
+// programs.
+func TestClosureProgramMatchesMachineLoopLimits(t *testing.T) {
+	type limitCase struct {
+		program string
+		config  MachineConfig
+	}
+	var cases []limitCase
+	for _, program := range []string{"+[-]+++", "++[>++[-]<-]>+", "+++[>+[-]+<-]+", "++[>+<-]>[-<++>]<[>]", "+[[-]>+]+"} {
+		for exec := uint(1); exec <= 60; exec++ {
+			for _, loops := range []uint{0, 1, 2} {
+				cases = append(cases, limitCase{program, MachineConfig{MaxInstructionExecutionCount: exec, MaxLoopIterations: loops}})
+			}
+		}
+	}
+	r := rand.New(rand.NewSource(17))
+	ops := []byte("<>+-[[]]")
+	for i := 0; i < 500; i++ {
+		p := make([]byte, 1+r.Intn(40))
+		for j := range p {
+			p[j] = ops[r.Intn(len(ops))]
+		}
+		cases = append(cases, limitCase{string(p), MachineConfig{
+			MaxInstructionExecutionCount: []uint{uint(1 + r.Intn(60)), 5000}[i%2],
+			MaxLoopIterations:            []uint{0, 1, 2, 5, 50}[i%5],
+		}})
+	}
+
+	for _, tc := range cases {
+		config := tc.config
+		config.MemoryCellCount, config.OverflowMode = uint(1+r.Intn(6)), OVERFLOW_WRAP
+		input := make([]uint32, config.MemoryCellCount)
+		for j := range input {
+			input[j] = uint32(r.Intn(4))
+		}
+
+		m := NewMachine(&config)
+		m.LoadProgram(tc.program)
+		m.LoadMemory(input)
+		mok, merr := m.Run()
+
+		c, cok, cerr := runClosures(tc.program, &config, input)
+		if cok != mok || !reflect.DeepEqual(cerr, merr) || c.InstructionCount != m.InstructionCount || !reflect.DeepEqual(c.Memory.Cells, m.Memory.Cells) {
+			t.Errorf("Program [%s] with %+v on %v\nClosures: {%v %v loop %d %d %v}\nMachine:  {%v %v loop %d %d %v}",
+				tc.program, config, input, cok, cerr, LoopOf(cerr), c.InstructionCount, c.Memory.Cells, mok, merr, LoopOf(merr), m.InstructionCount, m.Memory.Cells)
+		}
+	}
+}
+
+func TestBackendText(t *testing.T) {
+	for _, b := range []Backend{BACKEND_INTERPRETER, BACKEND_CLOSURE} {
+		text, _ := b.MarshalText()
+		var back Backend
+		if err := back.UnmarshalText(text); err != nil || back != b {
+			t.Errorf("Backend [%v] round tripped to [%v], %v", b, back, err)
+		}
+	}
+	var b Backend
+	if err := b.UnmarshalText([]byte("jit")); err == nil {
+		t.Errorf("Unexpected success unmarshalling an unknown backend")
+	}
+}
+
+func BenchmarkBatchBackends(b *testing.B) {
+	program := Compile(SWAP_RIGHT + SWAP_LEFT + "++++[>++++<-]>[-]")
+	inputs := [][]uint32{{3, 7, 1, 9}, {5, 1, 4}, {9, 2, 0, 1}, {1, 1, 1, 0, 8}}
+	for _, backend := range []Backend{BACKEND_INTERPRETER, BACKEND_CLOSURE} {
+		b.Run(backend.String(), func(b *testing.B) {
+			batch := NewBatch(NewMachine(&MachineConfig{MaxInstructionExecutionCount: 100000, MemoryCellCount: 10}))
+			batch.Backend = backend
+			for i := 0; i < b.N; i++ {
+				batch.Run(program, inputs, 4)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package brainfuck
+
+import (
+	"fmt"
+)
+
+// Step-wise execution for tooling. Run stays the fast path; Step and
+// Continue execute one source op at a time so breakpoints can land inside a
+// folded run of ops and observers see every intermediate state.
+
+var ErrBreakpointReached error = fmt.Errorf("Breakpoint reached")
+
+// MachineState is a copy of the Machine's state, safe to keep after the
+// Machine moves on.
+type MachineState struct {
+	Op               byte // The op that was just executed, NO_OP before the first step
+	TapeIndex        int  // Tape index of the next op to execute
+	Cells            []uint32
+	MemoryPointer    uint
+	BookmarkRegister uint   // The selected bookmark register
+	Bookmarks        []uint // Every bookmark register, the selected one included
+	SelectedBookmark uint
+	WhileIndexStack  []int
+	LoopIterations   []uint // Iterations each open loop started
+	InstructionCount uint
+	Halted           bool
+}
+
+// An Observer receives the Machine's state after each op executed through
+// Step or Continue.
+type Observer func(state *MachineState)
+
+// A Breakpoint is checked before each op executed through Continue. Returning
+// true pauses the Machine in front of that op.
+type Breakpoint func(m *Machine) bool
+
+// BreakAtTapeIndex pauses before the op at the given tape index.
+func BreakAtTapeIndex(index int) Breakpoint {
+	return func(m *Machine) bool {
+		return m.TapeIndex() == index
+	}
+}
+
+// BreakOnOp pauses before any occurrence of op.
+func BreakOnOp(op byte) Breakpoint {
+	return func(m *Machine) bool {
+		ok, o := m.CurrentOp()
+		return ok && o == op
+	}
+}
+
+// BreakOnMemory pauses before the next op whenever condition holds for the
+// current memory.
+func BreakOnMemory(condition func(memory *Memory) bool) Breakpoint {
+	return func(m *Machine) bool {
+		return condition(m.Memory)
+	}
+}
+
+// TapeIndex returns the tape index of the next op to execute, or the program
+// length once the Machine has run off the end.
+func (m *Machine) TapeIndex() int {
+	if m.ProgramCounter >= len(m.Program.Code) {
+		return len(m.Program.Instructions)
+	}
+	return m.Program.Code[m.ProgramCounter].Index + int(m.FoldOffset)
+}
+
+// CurrentOp returns the next op to execute. Returns false once the Machine has
+// run off the end of its program.
+func (m *Machine) CurrentOp() (bool, byte) {
+	if m.ProgramCounter >= len(m.Program.Code) {
+		return false, NO_OP
+	}
+	return true, m.Program.Code[m.ProgramCounter].Op
+}
+
+// State returns a copy of the Machine's current state.
+func (m *Machine) State() *MachineState {
+	state := &MachineState{
+		Op:               m.lastOp,
+		TapeIndex:        m.TapeIndex(),
+		Cells:            make([]uint32, len(m.Memory.Cells)),
+		MemoryPointer:    m.Memory.MemoryPointer,
+		BookmarkRegister: m.Memory.BookmarkRegister,
+		Bookmarks:        make([]uint, len(m.Memory.Bookmarks)),
+		SelectedBookmark: m.Memory.SelectedBookmark,
+		WhileIndexStack:  make([]int, len(m.WhileIndexStack)),
+		LoopIterations:   make([]uint, len(m.LoopIterations)),
+		InstructionCount: m.InstructionCount,
+		Halted:           m.Halted(),
+	}
+	copy(state.Cells, m.Memory.Cells)
+	copy(state.WhileIndexStack, m.WhileIndexStack)
+	copy(state.LoopIterations, m.LoopIterations)
+	for i := range state.Bookmarks {
+		state.Bookmarks[i] = m.Memory.Bookmark(uint(i))
+	}
+	return state
+}
+
+// AddBreakpoint registers a Breakpoint checked by Continue.
+func (m *Machine) AddBreakpoint(b Breakpoint) {
+	m.Breakpoints = append(m.Breakpoints, b)
+}
+
+// ClearBreakpoints removes all registered Breakpoints.
+func (m *Machine) ClearBreakpoints() {
+	m.Breakpoints = nil
+}
+
+// Step executes a single source op and notifies the Observer. Returns false
+// once the Machine halts, along with the exception that halted it, if any.
+// Stepping a halted Machine does nothing.
+func (m *Machine) Step() (bool, error) {
+	if len(m.Program.Code) == 0 && !m.halted {
+		return m.runEmpty()
+	}
+	if m.Halted() {
+		return false, nil
+	}
+
+	m.paused = false
+	m.lastOp = m.Program.Code[m.ProgramCounter].Op
+	running, err := m.cycle(1)
+
+	if m.Observer != nil {
+		m.Observer(m.State())
+	}
+
+	return running, err
+}
+
+// Continue steps the Machine until it halts or a Breakpoint fires. When the
+// Machine is paused on a breakpoint, Continue resumes past it. Returns
+// ErrBreakpointReached when paused.
+func (m *Machine) Continue() (bool, error) {
+	for !m.Halted() {
+		if !m.paused {
+			for _, b := range m.Breakpoints {
+				if b(m) {
+					m.paused = true
+					return false, ErrBreakpointReached
+				}
+			}
+		}
+
+		if running, err := m.Step(); !running {
+			if err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,132 @@
+package brainfuck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMachineStep(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 3})
+	m.LoadProgram("++>")
+
+	var states []*MachineState
+	m.Observer = func(state *MachineState) {
+		states = append(states, state)
+	}
+
+	for {
+		running, err := m.Step()
+		if err != nil {
+			t.Fatalf("Unexpected failure calling Machine.Step(). %v", err)
+		}
+		if !running {
+			break
+		}
+	}
+
+	if len(states) != 3 {
+		t.Fatalf("Observer saw [%d] states, expected [3]", len(states))
+	}
+
+	expectedIndexes := []int{1, 2, 3}
+	for i, state := range states {
+		if state.TapeIndex != expectedIndexes[i] {
+			t.Errorf("State [%d] has tape index [%d], expected [%d]", i, state.TapeIndex, expectedIndexes[i])
+		}
+	}
+
+	if !reflect.DeepEqual(states[0].Cells, []uint32{1, 0, 0}) {
+		t.Errorf("State [0] cells [%v] not equal to expected [%v]", states[0].Cells, []uint32{1, 0, 0})
+	}
+
+	if states[2].Op != OP_POINTER_RIGHT || states[2].MemoryPointer != 1 || !states[2].Halted {
+		t.Errorf("Final state is unexpected: %+v", states[2])
+	}
+
+	if m.InstructionCount != 3 {
+		t.Errorf("Instruction count [%d] is not 3", m.InstructionCount)
+	}
+
+	if running, err := m.Step(); running || err != nil {
+		t.Errorf("Stepping a halted machine returned running [%v] and err [%v]", running, err)
+	}
+}
+
+func TestMachineStepError(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 3})
+	m.LoadProgram("<")
+
+	if running, err := m.Step(); running || err == nil {
+		t.Errorf("Expected failure calling Machine.Step(), got running [%v] and err [%v]", running, err)
+	}
+
+	if !m.Halted() {
+		t.Errorf("Machine isn't halted after failure")
+	}
+}
+
+func TestMachineBreakAtTapeIndex(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 3})
+	m.LoadProgram("++++>+")
+	m.AddBreakpoint(BreakAtTapeIndex(2))
+
+	if ok, err := m.Continue(); ok || err != ErrBreakpointReached {
+		t.Fatalf("Expected breakpoint, got ok [%v] and err [%v]", ok, err)
+	}
+
+	if m.TapeIndex() != 2 || m.Memory.Cells[0] != 2 {
+		t.Errorf("Paused at tape index [%d] with cell value [%d], expected [2] and [2]", m.TapeIndex(), m.Memory.Cells[0])
+	}
+
+	if ok, err := m.Continue(); !ok {
+		t.Fatalf("Unexpected failure resuming with Machine.Continue(). %v", err)
+	}
+
+	if !reflect.DeepEqual(m.Memory.Cells, []uint32{4, 1, 0}) {
+		t.Errorf("Memory [%v] not equal to expected [%v]", m.Memory.Cells, []uint32{4, 1, 0})
+	}
+}
+
+func TestMachineBreakOnOp(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 3})
+	m.LoadProgram(SWAP_RIGHT)
+	m.AddBreakpoint(BreakOnOp(OP_JUMP))
+
+	if ok, err := m.LoadMemory([]uint32{1, 2}); !ok {
+		t.Fatalf("Unexpected failure calling Machine.LoadMemory(). %v", err)
+	}
+
+	if ok, err := m.Continue(); ok || err != ErrBreakpointReached {
+		t.Fatalf("Expected breakpoint, got ok [%v] and err [%v]", ok, err)
+	}
+
+	if m.TapeIndex() != 4 {
+		t.Errorf("Paused at tape index [%d], expected [4]", m.TapeIndex())
+	}
+
+	m.ClearBreakpoints()
+
+	if ok, err := m.Continue(); !ok {
+		t.Fatalf("Unexpected failure resuming with Machine.Continue(). %v", err)
+	}
+
+	if !reflect.DeepEqual(m.Memory.Cells, []uint32{2, 1, 0}) {
+		t.Errorf("Memory [%v] not equal to expected [%v]", m.Memory.Cells, []uint32{2, 1, 0})
+	}
+}
+
+func TestMachineBreakOnMemory(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 3})
+	m.LoadProgram("+++[>++<-]")
+	m.AddBreakpoint(BreakOnMemory(func(memory *Memory) bool {
+		return memory.Cells[1] == 4
+	}))
+
+	if ok, err := m.Continue(); ok || err != ErrBreakpointReached {
+		t.Fatalf("Expected breakpoint, got ok [%v] and err [%v]", ok, err)
+	}
+
+	if m.Memory.Cells[0] != 2 {
+		t.Errorf("Paused with cell value [%d], expected [2]", m.Memory.Cells[0])
+	}
+}
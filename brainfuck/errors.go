@@ -0,0 +1,124 @@
+package brainfuck
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exceptions raised while running a program are *MachineErrors, so callers
+// can tell what went wrong without parsing messages. The message itself is
+// unchanged; MachineError only adds the classification and where it
+// happened. Errors from loading or reading memory aren't exceptions and stay
+// plain errors.
+
+// ErrorKind classifies a MachineError. The values are persisted, so new
+// kinds only ever go at the end.
+type ErrorKind uint8
+
+const (
+	ERR_NONE                  ErrorKind = iota // No error
+	ERR_OTHER                                  // Anything not classified below, e.g. from a registered op
+	ERR_POINTER_UNDERFLOW                      // Moved the memory pointer left of cell 0
+	ERR_POINTER_OVERFLOW                       // Moved the memory pointer right of the last cell
+	ERR_INCREMENT_OVERFLOW                     // Incremented a cell past its maximum in OVERFLOW_ERROR mode
+	ERR_DECREMENT_UNDERFLOW                    // Decremented a cell past zero in OVERFLOW_ERROR mode
+	ERR_UNMATCHED_BRACKET                      // Needed the partner of a bracket that has none
+	ERR_BOOKMARK_OUT_OF_RANGE                  // A bookmark op found a pointer outside memory
+	ERR_EXECUTION_LIMIT                        // Reached MaxInstructionExecutionCount
+	ERR_EMPTY_PROGRAM                          // There was no op to execute
+	ERR_READ_ONLY_VIOLATION                    // Tried to change a read only memory cell
+	ERR_LOOP_LIMIT                             // A loop reached MaxLoopIterations
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ERR_NONE:
+		return "none"
+	case ERR_OTHER:
+		return "other"
+	case ERR_POINTER_UNDERFLOW:
+		return "pointer_underflow"
+	case ERR_POINTER_OVERFLOW:
+		return "pointer_overflow"
+	case ERR_INCREMENT_OVERFLOW:
+		return "increment_overflow"
+	case ERR_DECREMENT_UNDERFLOW:
+		return "decrement_underflow"
+	case ERR_UNMATCHED_BRACKET:
+		return "unmatched_bracket"
+	case ERR_BOOKMARK_OUT_OF_RANGE:
+		return "bookmark_out_of_range"
+	case ERR_EXECUTION_LIMIT:
+		return "execution_limit"
+	case ERR_EMPTY_PROGRAM:
+		return "empty_program"
+	case ERR_READ_ONLY_VIOLATION:
+		return "read_only_violation"
+	case ERR_LOOP_LIMIT:
+		return "loop_limit"
+	}
+	return fmt.Sprintf("ErrorKind(%d)", uint8(k))
+}
+
+type MachineError struct {
+	Kind          ErrorKind
+	TapeIndex     int  // Tape index of the op that raised it
+	MemoryPointer uint // Memory pointer when it was raised
+	Loop          int  // Tape index of the innermost running loop's OP_WHILE for the limit kinds, else NO_MATCH
+	Err           error
+}
+
+func (e *MachineError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *MachineError) Unwrap() error {
+	return e.Err
+}
+
+func newMachineError(kind ErrorKind, index int, memory *Memory, err error) *MachineError {
+	return &MachineError{Kind: kind, TapeIndex: index, MemoryPointer: memory.MemoryPointer, Loop: NO_MATCH, Err: err}
+}
+
+// asMachineError classifies err as ERR_OTHER unless it already is a
+// MachineError.
+func asMachineError(err error, index int, memory *Memory) error {
+	var me *MachineError
+	if err == nil || errors.As(err, &me) {
+		return err
+	}
+	return newMachineError(ERR_OTHER, index, memory, err)
+}
+
+// writeErrorKind classifies the error of an op that writes memory: kind,
+// unless the op failed on a read only cell.
+func writeErrorKind(err error, kind ErrorKind) ErrorKind {
+	if errors.Is(err, ErrReadOnly) {
+		return ERR_READ_ONLY_VIOLATION
+	}
+	return kind
+}
+
+// ErrorKindOf returns the kind of a MachineError anywhere in err's chain,
+// ERR_NONE for nil and ERR_OTHER for any other error.
+func ErrorKindOf(err error) ErrorKind {
+	if err == nil {
+		return ERR_NONE
+	}
+	var me *MachineError
+	if errors.As(err, &me) {
+		return me.Kind
+	}
+	return ERR_OTHER
+}
+
+// LoopOf returns the Loop of a MachineError anywhere in err's chain: the
+// loop that was running when the execution or loop limit was reached. It is
+// NO_MATCH for any other error, and for limits reached outside loops.
+func LoopOf(err error) int {
+	var me *MachineError
+	if errors.As(err, &me) {
+		return me.Loop
+	}
+	return NO_MATCH
+}
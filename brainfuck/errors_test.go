@@ -0,0 +1,64 @@
+package brainfuck
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMachineErrorKinds(t *testing.T) {
+	tests := []struct {
+		program   string
+		input     []uint32
+		kind      ErrorKind
+		tapeIndex int
+		pointer   uint
+	}{
+		{"><<", nil, ERR_POINTER_UNDERFLOW, 2, 0},
+		{">>>", nil, ERR_POINTER_OVERFLOW, 2, 2},
+		{"+>-", nil, ERR_DECREMENT_UNDERFLOW, 2, 1},
+		{"+", []uint32{255}, ERR_INCREMENT_OVERFLOW, 0, 0},
+		{"+]", nil, ERR_UNMATCHED_BRACKET, 1, 0},
+		{">[", nil, ERR_UNMATCHED_BRACKET, 1, 1},
+		{"+>[]<[]", nil, ERR_EXECUTION_LIMIT, 6, 0},
+		{"", nil, ERR_EMPTY_PROGRAM, 0, 0},
+	}
+
+	for _, tt := range tests {
+		m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 20, MemoryCellCount: 3})
+		m.LoadProgram(tt.program)
+		m.LoadMemory(tt.input)
+		ok, err := m.Run()
+
+		var me *MachineError
+		if ok || !errors.As(err, &me) {
+			t.Errorf("Program [%s] returned %v, %v, expected a MachineError", tt.program, ok, err)
+			continue
+		}
+		if me.Kind != tt.kind || me.TapeIndex != tt.tapeIndex || me.MemoryPointer != tt.pointer {
+			t.Errorf("Program [%s] raised %v at [%d] with pointer [%d], expected %v at [%d] with pointer [%d]",
+				tt.program, me.Kind, me.TapeIndex, me.MemoryPointer, tt.kind, tt.tapeIndex, tt.pointer)
+		}
+		if ErrorKindOf(err) != tt.kind {
+			t.Errorf("ErrorKindOf() returned %v, expected %v", ErrorKindOf(err), tt.kind)
+		}
+
+		// The Tape raises the same errors, bar the ones only the Machine has
+		if tt.kind == ERR_EXECUTION_LIMIT {
+			continue
+		}
+		tape, mem := NewTape(tt.program), NewMemory(3)
+		copy(mem.Cells, tt.input)
+		for {
+			if ok, err = tape.Execute(mem); !ok || !tape.Advance() {
+				break
+			}
+		}
+		if ok || ErrorKindOf(err) != tt.kind || err.Error() != me.Error() {
+			t.Errorf("Tape running [%s] returned %v, %v (%v), expected %v", tt.program, ok, err, ErrorKindOf(err), tt.kind)
+		}
+	}
+
+	if ErrorKindOf(nil) != ERR_NONE || ErrorKindOf(ErrBreakpointReached) != ERR_OTHER {
+		t.Errorf("Unexpected kinds for non MachineErrors")
+	}
+}
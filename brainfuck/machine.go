@@ -2,90 +2,331 @@ package brainfuck
 
 import (
 	"fmt"
+	"os"
 )
 
+// ErrMaxInstructionExecutionCountReached is wrapped in the ERR_EXECUTION_LIMIT
+// MachineError; check for it with errors.Is.
 var ErrMaxInstructionExecutionCountReached error = fmt.Errorf("Instruction execution count limit reached")
 
+// ErrMaxLoopIterationsReached is wrapped in the ERR_LOOP_LIMIT MachineError;
+// check for it with errors.Is.
+var ErrMaxLoopIterationsReached error = fmt.Errorf("Loop iteration limit reached")
+
 type Machine struct {
-	Tape             *Tape
+	Program          *Program
+	ProgramCounter   int
+	FoldOffset       uint // Source ops of the current folded Bytecode already executed
+	WhileIndexStack  []int
+	LoopIterations   []uint // Iterations each open loop started, see innermostLoop
 	Memory           *Memory
 	Config           *MachineConfig
 	InstructionCount uint
+	Breakpoints      []Breakpoint
+	Observer         Observer
+	Profile          *Profile // Optional, nil turns profiling off
+	halted           bool
+	paused           bool
+	lastOp           byte
+	loop             int // Tape index of the innermost open loop of a closure run
 }
 
 type MachineConfig struct {
-	MaxInstructionExecutionCount uint `toml:"max_instruction_execution_count"`
-	MemoryCellCount              uint `toml:"memory_cell_count"`
+	MaxInstructionExecutionCount uint         `toml:"max_instruction_execution_count"`
+	MemoryCellCount              uint         `toml:"memory_cell_count"`
+	OverflowMode                 OverflowMode `toml:"overflow_mode"`
+	CellWidth                    CellWidth    `toml:"cell_width"`
+	BookmarkRegisters            uint         `toml:"bookmark_registers"`  // 0 means 1
+	CmpSwap                      bool         `toml:"cmp_swap"`            // Mutations draw OP_CMP_SWAP
+	MaxLoopIterations            uint         `toml:"max_loop_iterations"` // Iterations one loop may start per entry, 0 means no limit
+}
+
+// Check returns an error if NewMachine can't build a Machine from mc.
+func (mc *MachineConfig) Check() error {
+	return mc.CellWidth.Check()
 }
 
 func NewMachine(mc *MachineConfig) *Machine {
+	memory := NewMemoryWithWidth(mc.MemoryCellCount, mc.CellWidth)
+	memory.Overflow = mc.OverflowMode
+	memory.SetBookmarkCount(mc.BookmarkRegisters)
 	return &Machine{
-		Memory: NewMemory(mc.MemoryCellCount),
-		Config: mc,
+		Memory:          memory,
+		WhileIndexStack: make([]int, 0, WHILE_STACK_CAP),
+		LoopIterations:  make([]uint, 0, WHILE_STACK_CAP),
+		Config:          mc,
 	}
 }
 
 func (m *Machine) Reset() {
-	m.Tape.Reset()
+	m.ProgramCounter = 0
+	m.FoldOffset = 0
+	m.halted = false
+	m.paused = false
+	m.lastOp = NO_OP
+	m.WhileIndexStack = m.WhileIndexStack[:0]
+	m.LoopIterations = m.LoopIterations[:0]
 	m.Memory.Reset()
+	if m.Profile != nil && m.Program != nil {
+		m.Profile.reset(len(m.Program.Instructions), len(m.Memory.Cells))
+	}
 }
 
+// LoadProgram compiles instructions and loads the result. Reloading the
+// program that is already loaded skips the compile step.
 func (m *Machine) LoadProgram(instructions string) {
-	if m.Tape == nil {
-		m.Tape = NewTape(instructions)
-	} else {
-		m.Tape.Instructions = instructions
-		m.Tape.Reset()
+	if m.Program == nil || m.Program.Instructions != instructions {
+		m.Program = Compile(instructions)
 	}
-	m.Memory.Reset()
+	m.Reset()
 	m.InstructionCount = 0
 }
 
-func (m *Machine) LoadMemory(input []uint8) (bool, error) {
+// LoadCompiled loads an already compiled Program.
+func (m *Machine) LoadCompiled(program *Program) {
+	m.Program = program
+	m.Reset()
+	m.InstructionCount = 0
+}
 
-	if uint(len(input)) > m.Config.MemoryCellCount {
+func (m *Machine) LoadMemory(input []uint32) (bool, error) {
+	return m.LoadMemoryAt(0, input)
+}
+
+// LoadMemoryAt loads input into the cells starting at offset.
+func (m *Machine) LoadMemoryAt(offset uint, input []uint32) (bool, error) {
+
+	if offset+uint(len(input)) > uint(len(m.Memory.Cells)) {
+		if offset > 0 {
+			return false, fmt.Errorf("Failed to load memory. Input length [%d] at offset [%d] is greater than memory capacity [%d]", len(input), offset, len(m.Memory.Cells))
+		}
 		return false, fmt.Errorf("Failed to load memory. Input length [%d] is greater than memory capacity [%d]", len(input), len(m.Memory.Cells))
 	}
 
 	for i, val := range input {
-		m.Memory.Cells[i] = val
+		if val > m.Memory.MaxValue {
+			return false, fmt.Errorf("Failed to load memory. Input value [%d] at index [%d] is greater than cell maximum [%d]", val, i, m.Memory.MaxValue)
+		}
+		m.Memory.Cells[offset+uint(i)] = val
 	}
 	return true, nil
 }
 
-func (m *Machine) ReadMemory(count uint) (bool, []uint8, error) {
+func (m *Machine) ReadMemory(count uint) (bool, []uint32, error) {
+	return m.ReadMemoryAt(0, count)
+}
+
+// ReadMemoryAt returns count cells starting at offset. The slice shares the
+// Machine's memory.
+func (m *Machine) ReadMemoryAt(offset, count uint) (bool, []uint32, error) {
 
-	if count > uint(len(m.Memory.Cells)) {
-		return false, []uint8{}, fmt.Errorf("Failed to read memory. Read count [%d] is greater than memory capacity [%d]", count, len(m.Memory.Cells))
+	if offset+count > uint(len(m.Memory.Cells)) {
+		if offset > 0 {
+			return false, []uint32{}, fmt.Errorf("Failed to read memory. Read count [%d] at offset [%d] is greater than memory capacity [%d]", count, offset, len(m.Memory.Cells))
+		}
+		return false, []uint32{}, fmt.Errorf("Failed to read memory. Read count [%d] is greater than memory capacity [%d]", count, len(m.Memory.Cells))
 	}
 
-	return true, m.Memory.Cells[0:count], nil
+	return true, m.Memory.Cells[offset : offset+count], nil
+}
+
+// SetReadOnly makes count cells starting at offset read only, and every other
+// cell writable. A count of 0 makes all of memory writable.
+func (m *Machine) SetReadOnly(offset, count uint) (bool, error) {
+	if offset+count > uint(len(m.Memory.Cells)) {
+		return false, fmt.Errorf("Failed to set read only cells. Cell count [%d] at offset [%d] is greater than memory capacity [%d]", count, offset, len(m.Memory.Cells))
+	}
+	m.Memory.ReadOnlyStart, m.Memory.ReadOnlyEnd = offset, offset+count
+	return true, nil
 }
 
 func (m *Machine) Run() (bool, error) {
 
+	if len(m.Program.Code) == 0 {
+		return m.runEmpty()
+	}
+
+	for {
+		if running, err := m.cycle(m.Config.MaxInstructionExecutionCount - m.InstructionCount); !running {
+			if err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+}
+
+// runEmpty mirrors the Tape, which fails to fetch an instruction from an
+// empty program.
+func (m *Machine) runEmpty() (bool, error) {
+	var exception error = newMachineError(ERR_EMPTY_PROGRAM, 0, m.Memory, fmt.Errorf("InstructionPointer [%d] out of bounds (Instruction length: [%d]", 0, 0))
+	m.InstructionCount = m.InstructionCount + 1
+	if m.InstructionCount >= m.Config.MaxInstructionExecutionCount {
+		exception = newMachineError(ERR_EXECUTION_LIMIT, 0, m.Memory, ErrMaxInstructionExecutionCountReached)
+	}
+	m.halted = true
+	return false, exception
+}
+
+// Halted reports whether the Machine has stopped, either by running off the
+// end of its program or on an exception.
+func (m *Machine) Halted() bool {
+	return m.halted || m.ProgramCounter >= len(m.Program.Code)
+}
+
+// cycle executes up to budget source ops, counts them, and enforces the
+// execution limit. Returns false once the Machine halts, along with the
+// exception that halted it, if any.
+func (m *Machine) cycle(budget uint) (bool, error) {
 	var exception error
 
-	halt := false
-	for !halt {
-		if ok, err := m.Tape.Execute(m.Memory); !ok {
-			halt = true
-			exception = err
+	running := true
+	bc, offset := &m.Program.Code[m.ProgramCounter], m.FoldOffset
+	executed, err := m.execute(bc, budget)
+	if m.Profile != nil {
+		m.Profile.record(m, bc, offset, executed, err != nil)
+	}
+	if err != nil {
+		running = false
+		exception = err
+	}
+	m.InstructionCount = m.InstructionCount + executed
+	if m.InstructionCount >= m.Config.MaxInstructionExecutionCount {
+		running = false
+		limit := newMachineError(ERR_EXECUTION_LIMIT, bc.Index+int(offset+executed)-1, m.Memory, ErrMaxInstructionExecutionCountReached)
+		limit.Loop = m.innermostLoop()
+		exception = limit
+	}
+
+	if m.ProgramCounter >= len(m.Program.Code) {
+		running = false
+	}
+
+	m.halted = !running
+
+	return running, exception
+}
+
+func loopLimitError(index int, iterations uint, memory *Memory) *MachineError {
+	err := newMachineError(ERR_LOOP_LIMIT, index, memory, fmt.Errorf("OP_WHILE at tape index [%d] failed to start iteration [%d]. %w", index, iterations, ErrMaxLoopIterationsReached))
+	err.Loop = index
+	return err
+}
+
+// innermostLoop returns the tape index of the OP_WHILE of the innermost open
+// loop, or NO_MATCH outside loops. LoopIterations keeps an entry per open
+// loop, like WhileIndexStack, except that an OP_WHILE_END jumping back pops
+// the loop off WhileIndexStack but leaves its iterations for the OP_WHILE to
+// pick up. Until then the ProgramCounter is on that OP_WHILE.
+func (m *Machine) innermostLoop() int {
+	if len(m.LoopIterations) > len(m.WhileIndexStack) {
+		return m.Program.Code[m.ProgramCounter].Index
+	}
+	if len(m.WhileIndexStack) > 0 {
+		return m.Program.Code[m.WhileIndexStack[len(m.WhileIndexStack)-1]].Index
+	}
+	return NO_MATCH
+}
+
+// execute runs a single Bytecode and moves the ProgramCounter. Folded ops run
+// at most budget times so the Machine stops on exactly the same source op the
+// Tape would have; a partially executed Bytecode is resumed from FoldOffset.
+// Returns the number of source ops executed, including the one that failed.
+func (m *Machine) execute(bc *Bytecode, budget uint) (uint, error) {
+	memory := m.Memory
+
+	if budget == 0 {
+		budget = 1
+	}
+	remaining := bc.Count - m.FoldOffset
+	n := remaining
+	if n > budget {
+		n = budget
+	}
+	index := bc.Index + int(m.FoldOffset)
+
+	switch bc.Op {
+	case OP_WHILE:
+		ok, val, err := memory.GetCurrentCell()
+		if !ok {
+			return 1, newMachineError(ERR_OTHER, bc.Index, memory, fmt.Errorf("OP_WHILE at tape index [%d] failed to get current memory cell at index [%d] during OP_WHILE evaluation. %v", bc.Index, memory.MemoryPointer, err))
 		}
-		m.InstructionCount = m.InstructionCount + 1
-		if m.InstructionCount >= m.Config.MaxInstructionExecutionCount {
-			halt = true
-			exception = ErrMaxInstructionExecutionCountReached
+		// An iteration of this loop is still open if its OP_WHILE_END just
+		// jumped back here
+		reentered := len(m.LoopIterations) > len(m.WhileIndexStack)
+		if val != 0 {
+			iterations := uint(1)
+			if reentered {
+				iterations = m.LoopIterations[len(m.LoopIterations)-1] + 1
+			}
+			if max := m.Config.MaxLoopIterations; max > 0 && iterations > max {
+				return 1, loopLimitError(bc.Index, iterations, memory)
+			}
+			if reentered {
+				m.LoopIterations = m.LoopIterations[:len(m.LoopIterations)-1]
+			}
+			m.WhileIndexStack = append(m.WhileIndexStack, m.ProgramCounter)
+			m.LoopIterations = append(m.LoopIterations, iterations)
+		} else {
+			if reentered {
+				m.LoopIterations = m.LoopIterations[:len(m.LoopIterations)-1]
+			}
+			if bc.Jump == NO_MATCH {
+				return 1, newMachineError(ERR_UNMATCHED_BRACKET, bc.Index, memory, fmt.Errorf("OP_WHILE at tape index [%d] failed to advance to OP_WHILE_END instruction. Tape end reached.", bc.Index))
+			}
+			// Skip the whole loop, including its OP_WHILE_END
+			m.ProgramCounter = bc.Jump + 1
+			return 1, nil
 		}
-
-		if !m.Tape.Advance() {
-			halt = true
+	case OP_WHILE_END:
+		ok, val, err := memory.GetCurrentCell()
+		if !ok {
+			return 1, newMachineError(ERR_OTHER, bc.Index, memory, fmt.Errorf("OP_WHILE_END at tape index [%d] failed to get current memory cell at index [%d] during OP_WHILE_END evaluation. %v", bc.Index, memory.MemoryPointer, err))
+		}
+		if val != 0 && (len(m.WhileIndexStack) == 0 || bc.Jump == NO_MATCH) {
+			return 1, newMachineError(ERR_UNMATCHED_BRACKET, bc.Index, memory, fmt.Errorf("OP_WHILE_END at tape index [%d] failed to fallback. Failed to pop while stack.", bc.Index))
+		}
+		if len(m.WhileIndexStack) > 0 {
+			m.WhileIndexStack = m.WhileIndexStack[:len(m.WhileIndexStack)-1]
+			if val == 0 {
+				m.LoopIterations = m.LoopIterations[:len(m.LoopIterations)-1]
+			}
+		}
+		if val != 0 {
+			// Fall back to the matching OP_WHILE so it gets reevaluated
+			m.ProgramCounter = bc.Jump
+			return 1, nil
+		}
+	case NO_OP:
+		if DEBUG {
+			fmt.Fprintf(os.Stderr, "\n---\nMACHINE STATE:\nMEMORY DUMP: %v\nMEMORY POINTER: %v\nINSTRUCTION DUMP: %v\nINSTRUCTION POINTER: %v\nWHILE STACK: %v\nBOOKMARK: %v\n", memory.Cells, memory.MemoryPointer, m.Program.Instructions, bc.Index, m.WhileIndexStack, memory.BookmarkRegister)
+		}
+	case OP_CMP_SWAP:
+		ok, swapped, err := compareSwap(memory, index)
+		if !ok {
+			return 1, err
+		}
+		if swapped && m.Profile != nil {
+			m.Profile.recordSwap(m)
+		}
+	default:
+		def := OpBySymbol(bc.Op)
+		if def == nil || def.Exec == nil {
+			panic(fmt.Sprintf("Unknown OP [%v] encountered!", bc.Op))
+		}
+		for k := uint(0); k < n; k++ {
+			if ok, err := def.Exec(memory, index+int(k)); !ok {
+				return k + 1, asMachineError(err, index+int(k), memory)
+			}
 		}
 	}
 
-	if exception != nil {
-		return false, exception
+	if n < remaining {
+		m.FoldOffset = m.FoldOffset + n
+		return n, nil
 	}
 
-	return true, nil
+	m.FoldOffset = 0
+	m.ProgramCounter = m.ProgramCounter + 1
+	return n, nil
 }
@@ -1,6 +1,7 @@
 package brainfuck
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -16,7 +17,7 @@ func TestBasicMachine(t *testing.T) {
 func TestLoadMemory(t *testing.T) {
 	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 1})
 
-	if ok, err := m.LoadMemory([]uint8{1}); !ok {
+	if ok, err := m.LoadMemory([]uint32{1}); !ok {
 		t.Errorf("Unexpected failure calling Machine.LoadMemory. %v", err)
 	}
 
@@ -24,7 +25,7 @@ func TestLoadMemory(t *testing.T) {
 		t.Errorf("Failed to store value. Expected memory cell index [0] value [%d] isn't [1]", m.Memory.Cells[0])
 	}
 
-	if ok, err := m.LoadMemory([]uint8{1, 2}); ok {
+	if ok, err := m.LoadMemory([]uint32{1, 2}); ok {
 		t.Errorf("Unexpected success calling Machine.LoadMemory. CellCount 1, input lenth is 2")
 	} else {
 		if err.Error() != "Failed to load memory. Input length [2] is greater than memory capacity [1]" {
@@ -59,12 +60,78 @@ func TestReadMemory(t *testing.T) {
 	}
 }
 
+func TestMemoryAtOffset(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 5})
+
+	if ok, err := m.LoadMemoryAt(2, []uint32{7, 8}); !ok {
+		t.Errorf("Unexpected failure calling Machine.LoadMemoryAt. %v", err)
+	}
+	if !reflect.DeepEqual(m.Memory.Cells, []uint32{0, 0, 7, 8, 0}) {
+		t.Errorf("LoadMemoryAt left %v", m.Memory.Cells)
+	}
+	if ok, values, err := m.ReadMemoryAt(3, 2); !ok || !reflect.DeepEqual(values, []uint32{8, 0}) {
+		t.Errorf("ReadMemoryAt returned [%v, %v, %v]", ok, values, err)
+	}
+
+	if ok, err := m.LoadMemoryAt(4, []uint32{1, 2}); ok {
+		t.Errorf("Unexpected success calling Machine.LoadMemoryAt past the memory end")
+	} else if err.Error() != "Failed to load memory. Input length [2] at offset [4] is greater than memory capacity [5]" {
+		t.Errorf("Error string doesn't match: %v", err)
+	}
+	if ok, _, err := m.ReadMemoryAt(3, 3); ok {
+		t.Errorf("Unexpected success calling Machine.ReadMemoryAt past the memory end")
+	} else if err.Error() != "Failed to read memory. Read count [3] at offset [3] is greater than memory capacity [5]" {
+		t.Errorf("Error string doesn't match: %v", err)
+	}
+	if ok, _ := m.SetReadOnly(4, 2); ok {
+		t.Errorf("Unexpected success making cells past the memory end read only")
+	}
+}
+
+func TestReadOnlyMachineLoadRunRead(t *testing.T) {
+	config := &MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 4, CmpSwap: true}
+	tests := []struct {
+		program string
+		index   int
+	}{
+		{"+", 0},
+		{">>-", 2},
+		{">>>-*<<%", 7},
+	}
+
+	for _, tt := range tests {
+		m := NewMachine(config)
+		m.LoadProgram(tt.program)
+		m.LoadMemoryAt(1, []uint32{3, 1, 2})
+		m.SetReadOnly(0, 3)
+
+		_, err := m.Run()
+		var me *MachineError
+		if !errors.As(err, &me) || me.Kind != ERR_READ_ONLY_VIOLATION || me.TapeIndex != tt.index {
+			t.Errorf("Running [%s] on read only cells returned [%v], expected a read only violation at tape index [%d]", tt.program, err, tt.index)
+		}
+
+		m.LoadProgram(tt.program)
+		m.LoadMemoryAt(1, []uint32{3, 1, 2})
+		if _, err := CompileClosures(m.Program).Run(m); ErrorKindOf(err) != ERR_READ_ONLY_VIOLATION {
+			t.Errorf("Running closure compiled [%s] on read only cells returned [%v]", tt.program, err)
+		}
+
+		m.LoadProgram(tt.program)
+		m.LoadMemoryAt(1, []uint32{3, 1, 2})
+		m.SetReadOnly(0, 0)
+		if ok, err := m.Run(); !ok {
+			t.Errorf("Running [%s] with all of memory writable failed: %v", tt.program, err)
+		}
+	}
+}
+
 func TestBasicMachineLoadRunRead(t *testing.T) {
 	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 100})
 
 	m.LoadProgram(SET_TO_ZERO)
 
-	if ok, err := m.LoadMemory([]uint8{1}); !ok {
+	if ok, err := m.LoadMemory([]uint32{1}); !ok {
 		t.Errorf("Unexpected failure calling Machine.LoadMemory(). %v", err)
 	}
 
@@ -90,7 +157,7 @@ func TestNestedLoopMachineLoadRunRead(t *testing.T) {
 
 	m.LoadProgram("[[[-]+-]+-]>+")
 
-	if ok, err := m.LoadMemory([]uint8{1}); !ok {
+	if ok, err := m.LoadMemory([]uint32{1}); !ok {
 		t.Errorf("Unexpected failure calling Machine.LoadMemory(). %v", err)
 	}
 
@@ -105,7 +172,7 @@ func TestNestedLoopMachineLoadRunRead(t *testing.T) {
 			t.Errorf("Return values length [%d] is not 2", len(val))
 		}
 
-		if !reflect.DeepEqual(val, []uint8{0, 1}) {
+		if !reflect.DeepEqual(val, []uint32{0, 1}) {
 			t.Errorf("Returned value [%v] is not equal to expected [%v]", val, []int{0, 1})
 		}
 	}
@@ -115,7 +182,7 @@ func TestSimpleLoopWithPointerMovesLoadRunRead(t *testing.T) {
 	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 100})
 	m.LoadProgram("++++[>+>+>+>+<<<<-]")
 
-	if ok, err := m.LoadMemory([]uint8{0}); !ok {
+	if ok, err := m.LoadMemory([]uint32{0}); !ok {
 		t.Errorf("Unexpected failure calling Machine.LoadMemory(). %v", err)
 	}
 
@@ -130,7 +197,7 @@ func TestSimpleLoopWithPointerMovesLoadRunRead(t *testing.T) {
 			t.Errorf("Return values length [%d] is not 5", len(val))
 		}
 
-		if !reflect.DeepEqual(val, []uint8{0, 4, 4, 4, 4}) {
+		if !reflect.DeepEqual(val, []uint32{0, 4, 4, 4, 4}) {
 			t.Errorf("Returned value [%v] is not equal to expected [%v]", val, []uint{0, 4, 4, 4, 4})
 		}
 	}
@@ -140,7 +207,7 @@ func TestSimpleNestedLoopWithPointerMovesLoadRunRead(t *testing.T) {
 	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 100})
 	m.LoadProgram("++[#>++++[#>+>+>+>+<<<<-#]<-#]")
 
-	if ok, err := m.LoadMemory([]uint8{0}); !ok {
+	if ok, err := m.LoadMemory([]uint32{0}); !ok {
 		t.Errorf("Unexpected failure calling Machine.LoadMemory(). %v", err)
 	}
 
@@ -155,7 +222,7 @@ func TestSimpleNestedLoopWithPointerMovesLoadRunRead(t *testing.T) {
 			t.Errorf("Return values length [%d] is not 6", len(val))
 		}
 
-		if !reflect.DeepEqual(val, []uint8{0, 0, 8, 8, 8, 8}) {
+		if !reflect.DeepEqual(val, []uint32{0, 0, 8, 8, 8, 8}) {
 			t.Errorf("Returned value [%v] is not equal to expected [%v]", val, []uint{0, 0, 8, 8, 8, 8})
 		}
 	}
@@ -165,7 +232,7 @@ func TestHelloWorldMachineLoadRunRead(t *testing.T) {
 	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 100})
 	m.LoadProgram("++++++++[>++++[>++>+++>+++>+<<<<-]>+>+>->>+[<]<-]")
 
-	if ok, err := m.LoadMemory([]uint8{0}); !ok {
+	if ok, err := m.LoadMemory([]uint32{0}); !ok {
 		t.Errorf("Unexpected failure calling Machine.LoadMemory(). %v", err)
 	}
 
@@ -180,7 +247,7 @@ func TestHelloWorldMachineLoadRunRead(t *testing.T) {
 			t.Errorf("Return values length [%d] is not 7", len(val))
 		}
 
-		if !reflect.DeepEqual(val, []uint8{0, 0, 72, 104, 88, 32, 8}) {
+		if !reflect.DeepEqual(val, []uint32{0, 0, 72, 104, 88, 32, 8}) {
 			t.Errorf("Returned value [%v] is not equal to expected [%v]", val, []uint{0, 0, 72, 104, 88, 32, 8})
 		}
 	}
@@ -190,7 +257,7 @@ func TestBookmarkJumpsMachineLoadRunRead(t *testing.T) {
 	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 100})
 	m.LoadProgram(SWAP_RIGHT)
 
-	if ok, err := m.LoadMemory([]uint8{20, 40}); !ok {
+	if ok, err := m.LoadMemory([]uint32{20, 40}); !ok {
 		t.Errorf("Unexpected failure calling Machine.LoadMemory(). %v", err)
 	}
 
@@ -205,7 +272,7 @@ func TestBookmarkJumpsMachineLoadRunRead(t *testing.T) {
 			t.Errorf("Return values length [%d] is not 3", len(val))
 		}
 
-		if !reflect.DeepEqual(val, []uint8{40, 20, 0}) {
+		if !reflect.DeepEqual(val, []uint32{40, 20, 0}) {
 			t.Errorf("Returned value [%v] is not equal to expected [%v]", val, []uint{40, 20, 0})
 		}
 	}
@@ -215,7 +282,7 @@ func TestBookmarkJumps2MachineLoadRunRead(t *testing.T) {
 	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 100})
 	m.LoadProgram(SWAP_LEFT)
 
-	if ok, err := m.LoadMemory([]uint8{10, 20, 30}); !ok {
+	if ok, err := m.LoadMemory([]uint32{10, 20, 30}); !ok {
 		t.Errorf("Unexpected failure calling Machine.LoadMemory(). %v", err)
 	}
 
@@ -230,7 +297,7 @@ func TestBookmarkJumps2MachineLoadRunRead(t *testing.T) {
 			t.Errorf("Return values length [%d] is not 3", len(val))
 		}
 
-		if !reflect.DeepEqual(val, []uint8{20, 10, 30}) {
+		if !reflect.DeepEqual(val, []uint32{20, 10, 30}) {
 			t.Errorf("Returned value [%v] is not equal to expected [%v]", val, []uint{20, 10, 30})
 		}
 	}
@@ -240,15 +307,183 @@ func TestMaxInstructionExecutionCountMachineLoadRunRead(t *testing.T) {
 	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 251, MemoryCellCount: 100})
 	m.LoadProgram(SWAP_LEFT)
 
-	if ok, err := m.LoadMemory([]uint8{10, 20, 30}); !ok {
+	if ok, err := m.LoadMemory([]uint32{10, 20, 30}); !ok {
 		t.Errorf("Unexpected failure calling Machine.LoadMemory(). %v", err)
 	}
 
 	if ok, err := m.Run(); ok {
 		t.Errorf("Unexpected success calling Machine.Run(). %v \nINSTRUCTION COUNTER: %v \nMEMORY DUMP:\n%v\n", err, m.InstructionCount, m.Memory.Cells)
 	} else {
-		if err != ErrMaxInstructionExecutionCountReached {
+		if !errors.Is(err, ErrMaxInstructionExecutionCountReached) {
+			t.Errorf("Error string doesn't match: %v", err)
+		}
+	}
+}
+
+func TestSkipNestedLoopMachineLoadRunRead(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 100})
+	m.LoadProgram("[[-]+]+")
+
+	if ok, err := m.Run(); !ok {
+		t.Errorf("Unexpected failure calling Machine.Run(). %v \nINSTRUCTION COUNTER: %v \nMEMORY DUMP:\n%v\n", err, m.InstructionCount, m.Memory.Cells)
+	}
+
+	if m.Memory.Cells[0] != 1 {
+		t.Errorf("Returned value [%d] is not 1", m.Memory.Cells[0])
+	}
+
+	if m.InstructionCount != 2 {
+		t.Errorf("Instruction count [%d] is not 2", m.InstructionCount)
+	}
+}
+
+func TestSkipInnerLoopMachineLoadRunRead(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 100})
+	m.LoadProgram("++[>[-]<-]")
+
+	if ok, err := m.Run(); !ok {
+		t.Errorf("Unexpected failure calling Machine.Run(). %v \nINSTRUCTION COUNTER: %v \nMEMORY DUMP:\n%v\n", err, m.InstructionCount, m.Memory.Cells)
+	}
+
+	if len(m.WhileIndexStack) != 0 {
+		t.Errorf("While index stack [%v] is not empty", m.WhileIndexStack)
+	}
+}
+
+func TestFoldedOpFailureMachineLoadRunRead(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 100})
+	m.LoadProgram(">>---")
+
+	if ok, err := m.Run(); ok {
+		t.Errorf("Unexpected success calling Machine.Run()")
+	} else {
+		if err.Error() != "OP_DEC at tape index [2] failed to decrement memory cell index [2]. Decrement failed. Cell value [0] at LowerBound [0]" {
 			t.Errorf("Error string doesn't match: %v", err)
 		}
 	}
+
+	if m.InstructionCount != 3 {
+		t.Errorf("Instruction count [%d] is not 3", m.InstructionCount)
+	}
+}
+
+func TestFoldedOpExecutionLimitMachineLoadRunRead(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 3, MemoryCellCount: 100})
+	m.LoadProgram("+++++")
+
+	if ok, err := m.Run(); ok {
+		t.Errorf("Unexpected success calling Machine.Run()")
+	} else if !errors.Is(err, ErrMaxInstructionExecutionCountReached) {
+		t.Errorf("Error string doesn't match: %v", err)
+	}
+
+	if m.Memory.Cells[0] != 3 {
+		t.Errorf("Returned value [%d] is not 3", m.Memory.Cells[0])
+	}
+}
+
+func TestLoopLimitMachineLoadRunRead(t *testing.T) {
+	tests := []struct {
+		program    string
+		execLimit  uint
+		loopLimit  uint
+		kind       ErrorKind
+		index      int
+		loop       int
+		iterations []uint // LoopIterations when the Machine halted
+	}{
+		// The fourth iteration fails to start, on the eighth op
+		{"+[]", 100, 3, ERR_LOOP_LIMIT, 1, 1, []uint{3}},
+		{"+[]", 10, 0, ERR_EXECUTION_LIMIT, 1, 1, []uint{5}},
+		// Iterations count per entry, so the inner loop starts over each time
+		{"++[>++[-]<-]", 1000, 2, ERR_NONE, 0, NO_MATCH, []uint{}},
+		{"+++[>++[-]<-]", 1000, 2, ERR_LOOP_LIMIT, 3, 3, []uint{2}},
+		{"++[>+++[-]<-]", 1000, 2, ERR_LOOP_LIMIT, 7, 7, []uint{1, 2}},
+		{"+[-]+++", 7, 0, ERR_EXECUTION_LIMIT, 6, NO_MATCH, []uint{}},
+	}
+
+	for _, tt := range tests {
+		m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: tt.execLimit, MemoryCellCount: 3, MaxLoopIterations: tt.loopLimit})
+		m.LoadProgram(tt.program)
+		_, err := m.Run()
+
+		if ErrorKindOf(err) != tt.kind {
+			t.Errorf("Program [%s] returned [%v], expected kind [%v]", tt.program, err, tt.kind)
+			continue
+		}
+		if err != nil {
+			var me *MachineError
+			errors.As(err, &me)
+			if me.TapeIndex != tt.index || me.Loop != tt.loop || LoopOf(err) != tt.loop {
+				t.Errorf("Program [%s] raised at tape index [%d] in loop [%d], expected [%d] in loop [%d]", tt.program, me.TapeIndex, me.Loop, tt.index, tt.loop)
+			}
+		}
+		if !reflect.DeepEqual(m.LoopIterations, tt.iterations) {
+			t.Errorf("Program [%s] left loop iterations %v, expected %v", tt.program, m.LoopIterations, tt.iterations)
+		}
+	}
+
+	if LoopOf(errors.New("not a machine error")) != NO_MATCH {
+		t.Errorf("LoopOf found a loop in a plain error")
+	}
+}
+
+func TestWrappingOverflowMachineLoadRunRead(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 2, OverflowMode: OVERFLOW_WRAP})
+	m.LoadProgram("->+")
+
+	if ok, err := m.Run(); !ok {
+		t.Errorf("Unexpected failure calling Machine.Run(). %v", err)
+	}
+
+	if !reflect.DeepEqual(m.Memory.Cells, []uint32{255, 1}) {
+		t.Errorf("Returned value [%v] is not equal to expected [%v]", m.Memory.Cells, []uint32{255, 1})
+	}
+}
+
+func TestWideCellLoadMemory(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 2, CellWidth: CELL_WIDTH_32})
+
+	if ok, err := m.LoadMemory([]uint32{70000, 4000000000}); !ok {
+		t.Errorf("Unexpected failure calling Machine.LoadMemory. %v", err)
+	}
+
+	m = NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 2})
+
+	if ok, err := m.LoadMemory([]uint32{1, 256}); ok {
+		t.Errorf("Unexpected success calling Machine.LoadMemory with a value wider than the cells")
+	} else if err.Error() != "Failed to load memory. Input value [256] at index [1] is greater than cell maximum [255]" {
+		t.Errorf("Error string doesn't match: %v", err)
+	}
+}
+
+func TestBookmarkPrefabsMachineLoadRunRead(t *testing.T) {
+	config := &MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 6, BookmarkRegisters: 2}
+	single, marked := NewMachine(config), NewMachine(config)
+
+	single.LoadProgram(SWAP_RIGHT)
+	single.LoadMemory([]uint32{3, 7, 1})
+	marked.LoadProgram(SWAP_RIGHT_MARKED)
+	marked.LoadMemory([]uint32{3, 7, 1})
+
+	for _, m := range []*Machine{single, marked} {
+		if ok, err := m.Run(); !ok {
+			t.Fatalf("Unexpected failure running [%s]. %v", m.Program.Instructions, err)
+		}
+	}
+	if !reflect.DeepEqual(single.Memory.Cells, marked.Memory.Cells) {
+		t.Errorf("SWAP_RIGHT_MARKED left %v, SWAP_RIGHT left %v", marked.Memory.Cells, single.Memory.Cells)
+	}
+	if marked.Memory.SelectedBookmark != 0 {
+		t.Errorf("SWAP_RIGHT_MARKED left register [%d] selected", marked.Memory.SelectedBookmark)
+	}
+
+	marked.LoadProgram(MARK_ZERO_RIGHT + MOVE_TO_NEXT_BOOKMARK)
+	marked.LoadMemory([]uint32{3, 7, 1})
+	if ok, err := marked.Run(); !ok {
+		t.Fatalf("Unexpected failure running [%s]. %v", marked.Program.Instructions, err)
+	}
+	if !reflect.DeepEqual(marked.Memory.Cells, []uint32{0, 7, 1, 3, 0, 0}) {
+		t.Errorf("Moving to the marked zero cell left %v", marked.Memory.Cells)
+	}
 }
@@ -1,24 +1,147 @@
 package brainfuck
 
 import (
+	"errors"
 	"fmt"
 	"math"
 )
 
+// OverflowMode decides what Increment and Decrement do when a cell would pass
+// its upper or lower bound.
+type OverflowMode uint8
+
+const (
+	OVERFLOW_ERROR    OverflowMode = iota // Fail the op (the original behavior)
+	OVERFLOW_WRAP                         // Wrap around modulo the cell width
+	OVERFLOW_SATURATE                     // Clamp to the bound
+)
+
+func (o OverflowMode) String() string {
+	switch o {
+	case OVERFLOW_ERROR:
+		return "error"
+	case OVERFLOW_WRAP:
+		return "wrap"
+	case OVERFLOW_SATURATE:
+		return "saturate"
+	}
+	return fmt.Sprintf("OverflowMode(%d)", uint8(o))
+}
+
+func (o OverflowMode) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *OverflowMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "error":
+		*o = OVERFLOW_ERROR
+	case "wrap":
+		*o = OVERFLOW_WRAP
+	case "saturate":
+		*o = OVERFLOW_SATURATE
+	default:
+		return fmt.Errorf("Unknown overflow mode [%s]. Expected one of error, wrap, saturate", text)
+	}
+	return nil
+}
+
+// CellWidth is the number of bits in a memory cell. Cells are always stored
+// as uint32; the width only sets the upper bound a cell can reach. The zero
+// value means CELL_WIDTH_8 so configs from before wider cells keep working.
+type CellWidth uint8
+
+const (
+	CELL_WIDTH_8  CellWidth = 8
+	CELL_WIDTH_16 CellWidth = 16
+	CELL_WIDTH_32 CellWidth = 32
+)
+
+// Check returns an error if w is not a supported width.
+func (w CellWidth) Check() error {
+	switch w {
+	case 0, CELL_WIDTH_8, CELL_WIDTH_16, CELL_WIDTH_32:
+		return nil
+	}
+	return fmt.Errorf("Unsupported cell width [%d]. Expected one of 8, 16, 32", w)
+}
+
+// MaxValue returns the largest value a cell of this width can hold.
+// Unsupported widths panic, so widths from configs should be Checked first.
+func (w CellWidth) MaxValue() uint32 {
+	switch w {
+	case 0, CELL_WIDTH_8:
+		return math.MaxUint8
+	case CELL_WIDTH_16:
+		return math.MaxUint16
+	case CELL_WIDTH_32:
+		return math.MaxUint32
+	}
+	panic(fmt.Sprintf("Unsupported cell width [%d]", w))
+}
+
+// ErrReadOnly is wrapped by the errors of ops that would change a read only
+// cell.
+var ErrReadOnly = errors.New("Memory cell is read only")
+
+// Memory has one or more bookmark registers. BookmarkRegister is always the
+// selected one, so OP_BOOKMARK and OP_JUMP never have to look it up. The rest
+// wait in Bookmarks; the selected register's own slot there is only brought
+// up to date when another register gets selected.
+//
+// Cells in [ReadOnlyStart, ReadOnlyEnd) are read only: Increment and
+// Decrement fail on them, and so does a CompareSwap that would swap one.
+// Loading memory ignores the range and Reset keeps it.
 type Memory struct {
-	Cells            []uint8
+	Cells            []uint32
 	CellCount        uint
 	MemoryPointer    uint
 	BookmarkRegister uint
+	Bookmarks        []uint
+	SelectedBookmark uint
+	Overflow         OverflowMode
+	MaxValue         uint32
+	ReadOnlyStart    uint
+	ReadOnlyEnd      uint
 }
 
 func NewMemory(cell_count uint) *Memory {
+	return NewMemoryWithWidth(cell_count, CELL_WIDTH_8)
+}
+
+func NewMemoryWithWidth(cell_count uint, width CellWidth) *Memory {
 	return &Memory{
-		Cells:            make([]uint8, cell_count),
+		Cells:            make([]uint32, cell_count),
 		CellCount:        cell_count,
 		MemoryPointer:    0,
 		BookmarkRegister: 0,
+		Bookmarks:        make([]uint, 1),
+		MaxValue:         width.MaxValue(),
+	}
+}
+
+// SetBookmarkCount sets the number of bookmark registers and resets them
+// all. Anything below 1 means a single register.
+func (m *Memory) SetBookmarkCount(count uint) {
+	if count < 1 {
+		count = 1
+	}
+	m.Bookmarks = make([]uint, count)
+	m.SelectedBookmark = 0
+	m.BookmarkRegister = 0
+}
+
+// Bookmark returns the value of bookmark register index.
+func (m *Memory) Bookmark(index uint) uint {
+	if index == m.SelectedBookmark {
+		return m.BookmarkRegister
 	}
+	return m.Bookmarks[index]
+}
+
+// ReadOnly reports whether the cell at index is read only.
+func (m *Memory) ReadOnly(index uint) bool {
+	return index >= m.ReadOnlyStart && index < m.ReadOnlyEnd
 }
 
 func (m *Memory) Reset() {
@@ -27,9 +150,13 @@ func (m *Memory) Reset() {
 	}
 	m.MemoryPointer = 0
 	m.BookmarkRegister = 0
+	for i := range m.Bookmarks {
+		m.Bookmarks[i] = 0
+	}
+	m.SelectedBookmark = 0
 }
 
-func (m *Memory) GetCurrentCell() (bool, uint8, error) {
+func (m *Memory) GetCurrentCell() (bool, uint32, error) {
 	if m.MemoryPointer < 0 || m.MemoryPointer > m.CellCount-1 {
 		return false, 0, fmt.Errorf("Memory pointer [%d] out of bounds (Memory length: [%d])", m.MemoryPointer, len(m.Cells))
 	}
@@ -75,13 +202,67 @@ func (m *Memory) BookmarkJump() (bool, error) {
 	return true, nil
 }
 
+// CompareSwap orders the current cell and the cell at the bookmark: if the
+// one with the lower index holds the larger value, the two values swap.
+// Returns whether they swapped.
+func (m *Memory) CompareSwap() (bool, bool, error) {
+	if m.MemoryPointer < 0 || m.MemoryPointer > m.CellCount-1 {
+		return false, false, fmt.Errorf("Failed to compare with bookmark. Current memory pointer [%d] out of bounds (Memory length: [%d])", m.MemoryPointer, len(m.Cells))
+	}
+
+	if m.BookmarkRegister < 0 || m.BookmarkRegister > m.CellCount-1 {
+		return false, false, fmt.Errorf("Failed to compare with bookmark. Bookmark memory pointer [%d] out of bounds (Memory length: [%d])", m.BookmarkRegister, len(m.Cells))
+	}
+
+	low, high := m.MemoryPointer, m.BookmarkRegister
+	if low > high {
+		low, high = high, low
+	}
+	if m.Cells[low] <= m.Cells[high] {
+		return true, false, nil
+	}
+	if m.ReadOnly(low) || m.ReadOnly(high) {
+		return false, false, fmt.Errorf("Failed to swap memory cell indexes [%d] and [%d]. %w", low, high, ErrReadOnly)
+	}
+	m.Cells[low], m.Cells[high] = m.Cells[high], m.Cells[low]
+
+	return true, true, nil
+}
+
+// NextBookmark selects the next bookmark register, wrapping around to the
+// first. With a single register it does nothing.
+func (m *Memory) NextBookmark() {
+	m.selectBookmark((m.SelectedBookmark + 1) % uint(len(m.Bookmarks)))
+}
+
+// FirstBookmark selects the first bookmark register.
+func (m *Memory) FirstBookmark() {
+	m.selectBookmark(0)
+}
+
+func (m *Memory) selectBookmark(index uint) {
+	m.Bookmarks[m.SelectedBookmark] = m.BookmarkRegister
+	m.SelectedBookmark = index
+	m.BookmarkRegister = m.Bookmarks[index]
+}
+
 func (m *Memory) Increment() (bool, error) {
 	if ok, val, err := m.GetCurrentCell(); ok {
-		if val < math.MaxUint8 {
+		if m.ReadOnly(m.MemoryPointer) {
+			return false, fmt.Errorf("Increment failed. %w", ErrReadOnly)
+		}
+		if val < m.MaxValue {
 			m.Cells[m.MemoryPointer] = val + 1
 			return true, nil
-		} else {
-			return false, fmt.Errorf("Increment failed. Cell value [%d] at UpperBound [%d]", val, math.MaxUint8)
+		}
+		switch m.Overflow {
+		case OVERFLOW_WRAP:
+			m.Cells[m.MemoryPointer] = 0
+			return true, nil
+		case OVERFLOW_SATURATE:
+			return true, nil
+		default:
+			return false, fmt.Errorf("Increment failed. Cell value [%d] at UpperBound [%d]", val, m.MaxValue)
 		}
 	} else {
 		return false, err
@@ -90,10 +271,20 @@ func (m *Memory) Increment() (bool, error) {
 
 func (m *Memory) Decrement() (bool, error) {
 	if ok, val, err := m.GetCurrentCell(); ok {
+		if m.ReadOnly(m.MemoryPointer) {
+			return false, fmt.Errorf("Decrement failed. %w", ErrReadOnly)
+		}
 		if val > 0 {
 			m.Cells[m.MemoryPointer] = val - 1
 			return true, nil
-		} else {
+		}
+		switch m.Overflow {
+		case OVERFLOW_WRAP:
+			m.Cells[m.MemoryPointer] = m.MaxValue
+			return true, nil
+		case OVERFLOW_SATURATE:
+			return true, nil
+		default:
 			return false, fmt.Errorf("Decrement failed. Cell value [%d] at LowerBound [%d]", val, 0)
 		}
 	} else {
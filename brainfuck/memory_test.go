@@ -1,6 +1,7 @@
 package brainfuck
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -155,3 +156,131 @@ func TestBookmarks(t *testing.T) {
 		}
 	}
 }
+
+func TestIncrementOverflowModes(t *testing.T) {
+	memory := NewMemory(1)
+	memory.Cells[0] = 255
+
+	memory.Overflow = OVERFLOW_WRAP
+	if ok, err := memory.Increment(); !ok {
+		t.Errorf("Wrapping increment failed: %v", err)
+	}
+	if memory.Cells[0] != 0 {
+		t.Errorf("Wrapping increment left cell value [%d], expected [0]", memory.Cells[0])
+	}
+
+	memory.Cells[0] = 255
+	memory.Overflow = OVERFLOW_SATURATE
+	if ok, err := memory.Increment(); !ok {
+		t.Errorf("Saturating increment failed: %v", err)
+	}
+	if memory.Cells[0] != 255 {
+		t.Errorf("Saturating increment left cell value [%d], expected [255]", memory.Cells[0])
+	}
+}
+
+func TestDecrementOverflowModes(t *testing.T) {
+	memory := NewMemory(1)
+
+	memory.Overflow = OVERFLOW_WRAP
+	if ok, err := memory.Decrement(); !ok {
+		t.Errorf("Wrapping decrement failed: %v", err)
+	}
+	if memory.Cells[0] != 255 {
+		t.Errorf("Wrapping decrement left cell value [%d], expected [255]", memory.Cells[0])
+	}
+
+	memory.Cells[0] = 0
+	memory.Overflow = OVERFLOW_SATURATE
+	if ok, err := memory.Decrement(); !ok {
+		t.Errorf("Saturating decrement failed: %v", err)
+	}
+	if memory.Cells[0] != 0 {
+		t.Errorf("Saturating decrement left cell value [%d], expected [0]", memory.Cells[0])
+	}
+}
+
+func TestOverflowModeText(t *testing.T) {
+	for _, mode := range []OverflowMode{OVERFLOW_ERROR, OVERFLOW_WRAP, OVERFLOW_SATURATE} {
+		text, _ := mode.MarshalText()
+		var parsed OverflowMode
+		if err := parsed.UnmarshalText(text); err != nil || parsed != mode {
+			t.Errorf("Overflow mode [%v] did not round trip. Parsed [%v], err [%v]", mode, parsed, err)
+		}
+	}
+
+	var parsed OverflowMode
+	if err := parsed.UnmarshalText([]byte("explode")); err == nil {
+		t.Errorf("Unexpected success parsing unknown overflow mode")
+	}
+}
+
+func TestCellWidthCheck(t *testing.T) {
+	for _, width := range []CellWidth{0, CELL_WIDTH_8, CELL_WIDTH_16, CELL_WIDTH_32} {
+		if err := width.Check(); err != nil {
+			t.Errorf("Cell width [%d] failed its check: %v", width, err)
+		}
+	}
+	if err := CellWidth(12).Check(); err == nil {
+		t.Errorf("Unexpected success checking cell width [12]")
+	}
+	mc := &MachineConfig{MemoryCellCount: 4, CellWidth: 12}
+	if err := mc.Check(); err == nil {
+		t.Errorf("Unexpected success checking machine config with cell width [12]")
+	}
+}
+
+func TestWideCellIncrement(t *testing.T) {
+	memory := NewMemoryWithWidth(1, CELL_WIDTH_16)
+	memory.Cells[0] = 255
+	if ok, err := memory.Increment(); !ok {
+		t.Errorf("Increment failed: %v", err)
+	}
+	if memory.Cells[0] != 256 {
+		t.Errorf("Increment left cell value [%d], expected [256]", memory.Cells[0])
+	}
+
+	memory.Cells[0] = 65535
+	if ok, err := memory.Increment(); ok {
+		t.Errorf("Increment succeeded when it shouldn't.")
+	} else if err.Error() != "Increment failed. Cell value [65535] at UpperBound [65535]" {
+		t.Errorf("Error string doesn't match: %v", err)
+	}
+
+	memory.Overflow = OVERFLOW_WRAP
+	memory.Cells[0] = 0
+	if ok, err := memory.Decrement(); !ok || memory.Cells[0] != 65535 {
+		t.Errorf("Wrapping decrement left cell value [%d], expected [65535]. %v", memory.Cells[0], err)
+	}
+}
+
+func TestReadOnlyCells(t *testing.T) {
+	memory := NewMemory(3)
+	memory.Cells[0], memory.Cells[1] = 5, 2
+	memory.ReadOnlyStart, memory.ReadOnlyEnd = 0, 2
+
+	if ok, err := memory.Increment(); ok || !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Increment of a read only cell returned [%v, %v]", ok, err)
+	}
+	if ok, err := memory.Decrement(); ok || !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Decrement of a read only cell returned [%v, %v]", ok, err)
+	}
+
+	// Cells already in order don't swap, so a compare is fine
+	memory.MemoryPointer, memory.BookmarkRegister = 2, 1
+	memory.Cells[2] = 4
+	if ok, swapped, err := memory.CompareSwap(); !ok || swapped {
+		t.Errorf("CompareSwap of ordered cells returned [%v, %v, %v]", ok, swapped, err)
+	}
+	memory.Cells[2] = 1
+	if ok, _, err := memory.CompareSwap(); ok || !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CompareSwap into a read only cell returned [%v, %v]", ok, err)
+	}
+
+	if ok, err := memory.Increment(); !ok {
+		t.Errorf("Increment of a writable cell failed: %v", err)
+	}
+	if memory.Cells[0] != 5 || memory.Cells[1] != 2 || memory.Cells[2] != 2 {
+		t.Errorf("Read only failures changed memory: %v", memory.Cells)
+	}
+}
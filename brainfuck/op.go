@@ -32,6 +32,32 @@ import ()
 
 // [-^+^] == very concise 0:N swap
 
+// There can be more than one bookmark register. & selects the next register
+// (wrapping around) and @ selects the first one; * and ^ always work on the
+// selected register. With a single register both are no-ops.
+
+//  r0  r1
+//  *   *
+// [3][1][0][0]
+// @*[>]&*@^
+// Mark the start in r0, find a zero cell, mark it in r1, jump back. Both
+// registers now point at the zero cell, ready for [-^+^]
+
+// % is an optional sorting primitive. It compares the current cell with the
+// cell at the bookmark and swaps them if they are out of order, so the lower
+// index ends up with the smaller value. Machines only draw it for mutations
+// with MachineConfig.CmpSwap set.
+
+//  *  %
+// [7][3]
+// *>%<
+// Bookmark, step right, order the two cells, step back
+
+//  *  %
+// [3][7]
+
+// The symbols of the built in ops. Each is declared in the op registry,
+// see registry.go.
 const (
 	OP_POINTER_LEFT  = '<'
 	OP_POINTER_RIGHT = '>'
@@ -42,6 +68,11 @@ const (
 	OP_JUMP          = '^'
 	OP_BOOKMARK      = '*'
 	NO_OP            = '#'
+
+	OP_NEXT_BOOKMARK  = '&'
+	OP_FIRST_BOOKMARK = '@'
+
+	OP_CMP_SWAP = '%'
 )
 
 const (
@@ -54,17 +85,31 @@ const (
 	MOVE_TO_ZERO_LEFT  = `*[<]^[-^+^]`
 )
 
-var OP_SET [9]byte = [...]byte{
-	OP_POINTER_LEFT,
-	OP_POINTER_RIGHT,
-	OP_INC,
-	OP_DEC,
-	OP_WHILE,
-	OP_WHILE_END,
-	OP_JUMP,
-	OP_BOOKMARK,
-	NO_OP,
-}
+// Prefabs using more than one bookmark register. They all start by
+// selecting the first register and leave it selected.
+const (
+	MARK_ZERO_RIGHT       = `@*[>]&*@^`
+	MOVE_TO_NEXT_BOOKMARK = `@&[-^+^]@`
+	SWAP_RIGHT_MARKED     = `@*[>]&*@^[-^+^]>[-<+>]&^[-^+^]@`
+)
+
+// Prefabs using OP_CMP_SWAP.
+const (
+	CMP_SWAP_RIGHT = `*>%<`
+	CMP_SWAP_LEFT  = `*<%>`
+)
+
+// OP_SET is the default dialect of the built in ops, filled in from the op
+// registry once they are registered.
+//
+// Deprecated: Use OpSet, which also has the ops a MachineConfig enables.
+var OP_SET [9]byte
+
+// BOOKMARK_OP_SET holds the register selecting ops that join OP_SET with
+// more than one bookmark register, filled in from the op registry.
+//
+// Deprecated: Use OpSet with BookmarkRegisters above 1.
+var BOOKMARK_OP_SET [2]byte
 
 var PREFAB_OPSETS [7]string = [...]string{
 	SET_TO_ZERO,
@@ -75,3 +120,27 @@ var PREFAB_OPSETS [7]string = [...]string{
 	MOVE_TO_ZERO_RIGHT,
 	MOVE_TO_ZERO_LEFT,
 }
+
+var BOOKMARK_PREFAB_OPSETS [3]string = [...]string{
+	MARK_ZERO_RIGHT,
+	MOVE_TO_NEXT_BOOKMARK,
+	SWAP_RIGHT_MARKED,
+}
+
+var CMP_SWAP_PREFAB_OPSETS [2]string = [...]string{
+	CMP_SWAP_RIGHT,
+	CMP_SWAP_LEFT,
+}
+
+// PrefabOpSets returns the prefabs for a Machine configured by mc. nil means
+// the default MachineConfig.
+func PrefabOpSets(mc *MachineConfig) []string {
+	prefabs := append([]string{}, PREFAB_OPSETS[:]...)
+	if mc != nil && multipleBookmarks(mc) {
+		prefabs = append(prefabs, BOOKMARK_PREFAB_OPSETS[:]...)
+	}
+	if mc != nil && mc.CmpSwap {
+		prefabs = append(prefabs, CMP_SWAP_PREFAB_OPSETS[:]...)
+	}
+	return prefabs
+}
@@ -1,6 +1,7 @@
 package brainfuck
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -155,8 +156,8 @@ func Test_OP_WHILE(t *testing.T) {
 		t.Errorf("Memory pointer [%d] is not at expected value [0].", mem.MemoryPointer)
 	}
 
-	if tape.InstructionPointer != 1 {
-		t.Errorf("Instruction pointer [%d] is not at expected value [1]", tape.InstructionPointer)
+	if tape.InstructionPointer != 2 {
+		t.Errorf("Instruction pointer [%d] is not at expected value [2]", tape.InstructionPointer)
 	}
 
 	if len(tape.WhileIndexStack) != 1 {
@@ -325,3 +326,91 @@ func Test_NO_OP(t *testing.T) {
 	}
 
 }
+
+func Test_OP_NEXT_BOOKMARK(t *testing.T) {
+	tape, mem := NewTape("&*&^"), NewMemory(10)
+	mem.SetBookmarkCount(2)
+	mem.MemoryPointer = 3
+
+	for i := 0; i < 4; i++ {
+		if ok, err := tape.Execute(mem); !ok {
+			t.Fatalf("Unexpected failure when calling Tape.Execute() at op [%d]. %v", i, err)
+		}
+		tape.Advance()
+	}
+
+	// Stored 3 in register 1, wrapped around to register 0 and jumped to it
+	if mem.SelectedBookmark != 0 || mem.MemoryPointer != 0 || mem.BookmarkRegister != 3 || mem.Bookmark(1) != 3 {
+		t.Errorf("Unexpected bookmarks: selected [%d], pointer [%d], registers [%d %d]", mem.SelectedBookmark, mem.MemoryPointer, mem.Bookmark(0), mem.Bookmark(1))
+	}
+
+	// A single register can't be switched away from
+	tape, mem = NewTape("*&"), NewMemory(10)
+	mem.MemoryPointer = 4
+	tape.Execute(mem)
+	tape.Advance()
+	tape.Execute(mem)
+	if mem.SelectedBookmark != 0 || mem.BookmarkRegister != 4 {
+		t.Errorf("OP_NEXT_BOOKMARK changed a single register: selected [%d], register [%d]", mem.SelectedBookmark, mem.BookmarkRegister)
+	}
+}
+
+func Test_OP_FIRST_BOOKMARK(t *testing.T) {
+	tape, mem := NewTape("&&*@"), NewMemory(10)
+	mem.SetBookmarkCount(3)
+	mem.MemoryPointer = 5
+
+	for i := 0; i < 4; i++ {
+		if ok, err := tape.Execute(mem); !ok {
+			t.Fatalf("Unexpected failure when calling Tape.Execute() at op [%d]. %v", i, err)
+		}
+		tape.Advance()
+	}
+
+	if mem.SelectedBookmark != 0 || mem.BookmarkRegister != 0 || mem.Bookmark(2) != 5 {
+		t.Errorf("Unexpected bookmarks: selected [%d], registers [%d %d %d]", mem.SelectedBookmark, mem.Bookmark(0), mem.Bookmark(1), mem.Bookmark(2))
+	}
+
+	mem.Reset()
+	if mem.Bookmark(2) != 0 {
+		t.Errorf("Bookmark register [2] didn't reset: %d", mem.Bookmark(2))
+	}
+}
+
+func Test_OP_CMP_SWAP(t *testing.T) {
+	tests := []struct {
+		cells    []uint32
+		pointer  uint
+		bookmark uint
+		expected []uint32
+	}{
+		{[]uint32{7, 3, 5}, 1, 0, []uint32{3, 7, 5}},
+		{[]uint32{7, 3, 5}, 0, 1, []uint32{3, 7, 5}},
+		{[]uint32{3, 7, 5}, 1, 0, []uint32{3, 7, 5}},
+		{[]uint32{7, 3, 5}, 2, 0, []uint32{5, 3, 7}},
+		{[]uint32{7, 3, 5}, 1, 1, []uint32{7, 3, 5}},
+	}
+
+	for _, tt := range tests {
+		tape, mem := NewTape("%"), NewMemory(3)
+		copy(mem.Cells, tt.cells)
+		mem.MemoryPointer, mem.BookmarkRegister = tt.pointer, tt.bookmark
+
+		if ok, err := tape.Execute(mem); !ok {
+			t.Fatalf("Unexpected failure when calling Tape.Execute(). %v", err)
+		}
+		if !reflect.DeepEqual(mem.Cells, tt.expected) || mem.MemoryPointer != tt.pointer || mem.BookmarkRegister != tt.bookmark {
+			t.Errorf("OP_CMP_SWAP on %v at [%d] with bookmark [%d] left %v, expected %v", tt.cells, tt.pointer, tt.bookmark, mem.Cells, tt.expected)
+		}
+	}
+
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 100, MemoryCellCount: 3})
+	m.LoadProgram(CMP_SWAP_RIGHT + ">" + CMP_SWAP_RIGHT + "<" + CMP_SWAP_RIGHT)
+	m.LoadMemory([]uint32{9, 4, 1})
+	if ok, err := m.Run(); !ok {
+		t.Fatalf("Unexpected failure running the OP_CMP_SWAP bubble pass. %v", err)
+	}
+	if !reflect.DeepEqual(m.Memory.Cells, []uint32{1, 4, 9}) {
+		t.Errorf("OP_CMP_SWAP bubble pass left %v", m.Memory.Cells)
+	}
+}
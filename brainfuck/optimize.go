@@ -0,0 +1,174 @@
+package brainfuck
+
+import (
+	"strings"
+)
+
+// A peephole optimizer for evolved programs. Evolution leaves a lot of noise
+// behind: NO_OPs, moves that undo each other, loops that can never run. The
+// rewrites below only remove ops whose absence can't be observed on Memory,
+// including which kind of op fails and the state Memory is left in when it
+// does. What does change is the number of ops executed, so a program that
+// ran into the execution limit may finish once optimized. Tape indexes in
+// error messages refer to the optimized program.
+//
+// Rewrites, repeated until nothing changes:
+//   - NO_OPs are dropped
+//   - Runs of pointer moves are shortened to the same sequence of new
+//     leftmost/rightmost positions. Only those can run off either end of
+//     Memory, so the same run still fails the same way.
+//   - Runs of OP_INC/OP_DEC are shortened the same way in OVERFLOW_ERROR
+//     mode and folded to their net change in OVERFLOW_WRAP mode. With
+//     OVERFLOW_SATURATE every step can clamp, so they are left alone.
+//   - A loop directly after an OP_WHILE_END is dropped. Falling out of a
+//     loop, matched or not, leaves the current cell zero, so the next loop
+//     is always skipped. This collapses repeated clears like [-][-].
+
+// Optimize applies the rewrites above to program until it stops shrinking.
+// mode must be the OverflowMode of the Memory the program will run on.
+func Optimize(program string, mode OverflowMode) string {
+	if len(program) == 0 {
+		return program
+	}
+	for {
+		optimized := dropDeadLoops(foldRuns(strings.ReplaceAll(program, string(NO_OP), ""), mode))
+		if len(optimized) == 0 {
+			// An empty program fails to run, a program of no-ops doesn't
+			return string(NO_OP)
+		}
+		if optimized == program {
+			return optimized
+		}
+		program = optimized
+	}
+}
+
+// foldRuns shortens every run of pointer moves and, if mode allows it, every
+// run of cell increments/decrements.
+func foldRuns(program string, mode OverflowMode) string {
+	var sb strings.Builder
+	sb.Grow(len(program))
+
+	for i := 0; i < len(program); {
+		o := program[i]
+		switch o {
+		case OP_POINTER_LEFT, OP_POINTER_RIGHT:
+			j := runEnd(program, i, OP_POINTER_LEFT, OP_POINTER_RIGHT)
+			sb.WriteString(foldExtremes(program[i:j], OP_POINTER_RIGHT, OP_POINTER_LEFT))
+			i = j
+		case OP_INC, OP_DEC:
+			j := runEnd(program, i, OP_INC, OP_DEC)
+			switch mode {
+			case OVERFLOW_WRAP:
+				sb.WriteString(foldNet(program[i:j], OP_INC, OP_DEC))
+			case OVERFLOW_SATURATE:
+				sb.WriteString(program[i:j])
+			default:
+				sb.WriteString(foldExtremes(program[i:j], OP_INC, OP_DEC))
+			}
+			i = j
+		default:
+			sb.WriteByte(o)
+			i++
+		}
+	}
+
+	return sb.String()
+}
+
+func runEnd(program string, start int, a, b byte) int {
+	i := start
+	for i < len(program) && (program[i] == a || program[i] == b) {
+		i++
+	}
+	return i
+}
+
+// foldExtremes rewrites a run of up/down steps into one that reaches each new
+// high and new low in the same order as the original, then ends on the same
+// offset. Whatever bound the original run crosses first, the rewritten run
+// crosses first too, and from the same side.
+func foldExtremes(run string, up, down byte) string {
+	var pos, high, low int
+	var turns []int
+	lastHigh := false
+
+	for i := 0; i < len(run); i++ {
+		if run[i] == up {
+			pos++
+		} else {
+			pos--
+		}
+		if pos > high {
+			high = pos
+			if len(turns) > 0 && lastHigh {
+				turns[len(turns)-1] = pos
+			} else {
+				turns = append(turns, pos)
+				lastHigh = true
+			}
+		} else if pos < low {
+			low = pos
+			if len(turns) > 0 && !lastHigh {
+				turns[len(turns)-1] = pos
+			} else {
+				turns = append(turns, pos)
+				lastHigh = false
+			}
+		}
+	}
+	turns = append(turns, pos)
+
+	var sb strings.Builder
+	at := 0
+	for _, t := range turns {
+		if t > at {
+			sb.WriteString(strings.Repeat(string(up), t-at))
+		} else {
+			sb.WriteString(strings.Repeat(string(down), at-t))
+		}
+		at = t
+	}
+	return sb.String()
+}
+
+// foldNet rewrites a run of up/down steps into its net change.
+func foldNet(run string, up, down byte) string {
+	net := strings.Count(run, string(up)) - strings.Count(run, string(down))
+	if net >= 0 {
+		return strings.Repeat(string(up), net)
+	}
+	return strings.Repeat(string(down), -net)
+}
+
+// dropDeadLoops removes every matched loop that directly follows an
+// OP_WHILE_END.
+func dropDeadLoops(program string) string {
+	closes := make([]int, len(program))
+	opens := make([]int, 0, WHILE_STACK_CAP)
+	for i := 0; i < len(program); i++ {
+		closes[i] = NO_MATCH
+		switch program[i] {
+		case OP_WHILE:
+			opens = append(opens, i)
+		case OP_WHILE_END:
+			if len(opens) > 0 {
+				closes[opens[len(opens)-1]] = i
+				opens = opens[:len(opens)-1]
+			}
+		}
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(program))
+	for i := 0; i < len(program); i++ {
+		if program[i] == OP_WHILE && i > 0 && program[i-1] == OP_WHILE_END && closes[i] != NO_MATCH {
+			// Skipped loops leave the cell alone, so a loop right after
+			// this one is dead too
+			i = closes[i]
+			continue
+		}
+		sb.WriteByte(program[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,84 @@
+package brainfuck
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		program  string
+		mode     OverflowMode
+		expected string
+	}{
+		{"", OVERFLOW_ERROR, ""},
+		{"##", OVERFLOW_ERROR, "#"},
+		{"+#-", OVERFLOW_WRAP, "#"},
+		{"+-", OVERFLOW_ERROR, "+-"},
+		{"+-+-", OVERFLOW_ERROR, "+-"},
+		{"+-+-", OVERFLOW_SATURATE, "+-+-"},
+		{"++-+", OVERFLOW_ERROR, "++"},
+		{"++-+", OVERFLOW_WRAP, "++"},
+		{"-+++", OVERFLOW_WRAP, "++"},
+		{"<>", OVERFLOW_WRAP, "<>"},
+		{"><><>", OVERFLOW_WRAP, ">"},
+		{"><><<>", OVERFLOW_ERROR, "><<>"},
+		{"[-][-]", OVERFLOW_ERROR, "[-]"},
+		{"[-][-][>]", OVERFLOW_ERROR, "[-]"},
+		{"[-]#[>+<]", OVERFLOW_ERROR, "[-]"},
+		{"[-]+-[>]", OVERFLOW_WRAP, "[-]"},
+		{"[-]][-]", OVERFLOW_ERROR, "[-]]"},
+		{"[-][", OVERFLOW_ERROR, "[-]["},
+		{SWAP_RIGHT, OVERFLOW_ERROR, SWAP_RIGHT},
+	}
+
+	for _, tt := range tests {
+		if got := Optimize(tt.program, tt.mode); got != tt.expected {
+			t.Errorf("Optimize(%q, %v) returned %q, expected %q", tt.program, tt.mode, got, tt.expected)
+		}
+	}
+}
+
+// Random programs must leave Memory in the same state, and fail or not,
+// before and after optimizing.
+func TestOptimizePreservesMemory(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	ops := "<>+-[]^*#%"
+	limit := uint(5000)
+
+	for _, mode := range []OverflowMode{OVERFLOW_ERROR, OVERFLOW_WRAP, OVERFLOW_SATURATE} {
+		config := &MachineConfig{MaxInstructionExecutionCount: limit, MemoryCellCount: 4, OverflowMode: mode}
+		original, optimized := NewMachine(config), NewMachine(config)
+
+		for n := 0; n < 20000; n++ {
+			b := make([]byte, r.Intn(20))
+			for i := range b {
+				b[i] = ops[r.Intn(len(ops))]
+			}
+			program := string(b)
+			input := []uint32{uint32(r.Intn(3)), uint32(r.Intn(256)), uint32(r.Intn(2)) * 255, uint32(r.Intn(256))}
+
+			original.LoadProgram(program)
+			original.LoadMemory(input)
+			ok, err := original.Run()
+			if errors.Is(err, ErrMaxInstructionExecutionCountReached) {
+				continue
+			}
+
+			o := Optimize(program, mode)
+			optimized.LoadProgram(o)
+			optimized.LoadMemory(input)
+			oOk, _ := optimized.Run()
+
+			if ok != oOk ||
+				!reflect.DeepEqual(original.Memory.Cells, optimized.Memory.Cells) ||
+				original.Memory.MemoryPointer != optimized.Memory.MemoryPointer ||
+				original.Memory.BookmarkRegister != optimized.Memory.BookmarkRegister {
+				t.Fatalf("Optimizing %q to %q changed behavior in %v mode on input %v. Before: %v %v. After: %v %v",
+					program, o, mode, input, ok, original.Memory.Cells, oOk, optimized.Memory.Cells)
+			}
+		}
+	}
+}
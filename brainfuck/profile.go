@@ -0,0 +1,96 @@
+package brainfuck
+
+// A Profile records where a Machine spent its time. Set Machine.Profile to
+// turn profiling on; every run through Run, Step or Continue then adds to it,
+// and loading a program or resetting the Machine clears it. Folded Bytecode
+// is counted per source op, so Executions lines up with the program string.
+type Profile struct {
+	Executions    []uint // Times each tape index was executed
+	CellReads     []uint // Reads of each memory cell
+	CellWrites    []uint // Writes to each memory cell
+	MaxWhileDepth int    // Deepest the while stack got
+}
+
+func NewProfile() *Profile {
+	return &Profile{}
+}
+
+// Clone returns a copy of the Profile that the Machine won't touch again.
+func (p *Profile) Clone() *Profile {
+	clone := &Profile{
+		Executions:    make([]uint, len(p.Executions)),
+		CellReads:     make([]uint, len(p.CellReads)),
+		CellWrites:    make([]uint, len(p.CellWrites)),
+		MaxWhileDepth: p.MaxWhileDepth,
+	}
+	copy(clone.Executions, p.Executions)
+	copy(clone.CellReads, p.CellReads)
+	copy(clone.CellWrites, p.CellWrites)
+	return clone
+}
+
+func (p *Profile) reset(programLength, cellCount int) {
+	p.Executions = resetCounts(p.Executions, programLength)
+	p.CellReads = resetCounts(p.CellReads, cellCount)
+	p.CellWrites = resetCounts(p.CellWrites, cellCount)
+	p.MaxWhileDepth = 0
+}
+
+func resetCounts(counts []uint, length int) []uint {
+	if cap(counts) < length {
+		return make([]uint, length)
+	}
+	counts = counts[:length]
+	for i := range counts {
+		counts[i] = 0
+	}
+	return counts
+}
+
+// fit sizes the counts for m's program and memory.
+func (p *Profile) fit(m *Machine) {
+	if len(p.Executions) != len(m.Program.Instructions) || len(p.CellReads) != len(m.Memory.Cells) {
+		// Attached after the program was loaded
+		p.reset(len(m.Program.Instructions), len(m.Memory.Cells))
+	}
+}
+
+// recordSwap accounts for the writes of an OP_CMP_SWAP that swapped the
+// current cell with the bookmarked one.
+func (p *Profile) recordSwap(m *Machine) {
+	p.fit(m)
+	p.CellWrites[m.Memory.MemoryPointer]++
+	p.CellWrites[m.Memory.BookmarkRegister]++
+}
+
+// record accounts for executed source ops of bc, starting offset ops into
+// the fold. failed is set when the last of them raised an exception.
+func (p *Profile) record(m *Machine, bc *Bytecode, offset, executed uint, failed bool) {
+	p.fit(m)
+	for k := uint(0); k < executed; k++ {
+		p.Executions[bc.Index+int(offset+k)]++
+	}
+
+	cell := m.Memory.MemoryPointer
+	if cell < uint(len(p.CellReads)) {
+		switch bc.Op {
+		case OP_INC, OP_DEC:
+			p.CellReads[cell] += executed
+			p.CellWrites[cell] += executed
+			if failed {
+				p.CellWrites[cell]--
+			}
+		case OP_WHILE, OP_WHILE_END:
+			p.CellReads[cell] += executed
+		case OP_CMP_SWAP:
+			p.CellReads[cell] += executed
+			if bookmark := m.Memory.BookmarkRegister; bookmark < uint(len(p.CellReads)) && bookmark != cell {
+				p.CellReads[bookmark] += executed
+			}
+		}
+	}
+
+	if depth := len(m.WhileIndexStack); depth > p.MaxWhileDepth {
+		p.MaxWhileDepth = depth
+	}
+}
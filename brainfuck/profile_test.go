@@ -0,0 +1,75 @@
+package brainfuck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMachineProfile(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 3})
+	m.Profile = NewProfile()
+	m.LoadProgram("[->+<]>>#")
+	m.LoadMemory([]uint32{2})
+
+	if ok, err := m.Run(); !ok {
+		t.Fatalf("Unexpected failure running profiled program. %v", err)
+	}
+
+	expected := &Profile{
+		Executions:    []uint{2, 2, 2, 2, 2, 2, 1, 1, 1},
+		CellReads:     []uint{6, 2, 0},
+		CellWrites:    []uint{2, 2, 0},
+		MaxWhileDepth: 1,
+	}
+	if !reflect.DeepEqual(m.Profile, expected) {
+		t.Errorf("Profile %+v, expected %+v", m.Profile, expected)
+	}
+
+	// Reloading starts a fresh profile
+	m.LoadProgram("[->+<]>>#")
+	if m.Profile.Executions[0] != 0 || m.Profile.MaxWhileDepth != 0 {
+		t.Errorf("Profile not cleared by LoadProgram: %+v", m.Profile)
+	}
+}
+
+func TestMachineProfileFolded(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 3})
+	m.LoadProgram("+++>--")
+	m.LoadMemory([]uint32{0, 1})
+	// Attaching after loading still sizes the profile to the program
+	m.Profile = NewProfile()
+
+	if ok, _ := m.Run(); ok {
+		t.Fatalf("Expected decrement failure")
+	}
+
+	if !reflect.DeepEqual(m.Profile.Executions, []uint{1, 1, 1, 1, 1, 1}) {
+		t.Errorf("Unexpected executions %v", m.Profile.Executions)
+	}
+	// The failed decrement read its cell but never wrote it
+	if m.Profile.CellReads[1] != 2 || m.Profile.CellWrites[1] != 1 {
+		t.Errorf("Unexpected cell 1 reads [%d] writes [%d]", m.Profile.CellReads[1], m.Profile.CellWrites[1])
+	}
+
+	clone := m.Profile.Clone()
+	m.Profile.Executions[0] = 42
+	if clone.Executions[0] != 1 {
+		t.Errorf("Clone shares its counts with the original")
+	}
+}
+
+func TestMachineProfileCmpSwap(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 3, CmpSwap: true})
+	m.Profile = NewProfile()
+	m.LoadProgram("*>%%")
+	m.LoadMemory([]uint32{3, 1})
+
+	if ok, err := m.Run(); !ok {
+		t.Fatalf("Unexpected failure running profiled program. %v", err)
+	}
+
+	// Both compares read both cells, only the first swapped them
+	if !reflect.DeepEqual(m.Profile.CellReads, []uint{2, 2, 0}) || !reflect.DeepEqual(m.Profile.CellWrites, []uint{1, 1, 0}) {
+		t.Errorf("Unexpected cell reads %v writes %v", m.Profile.CellReads, m.Profile.CellWrites)
+	}
+}
@@ -0,0 +1,192 @@
+package brainfuck
+
+import (
+	"fmt"
+)
+
+// The op registry is the single list of ops the dialect knows. The Tape and
+// the Machine dispatch through it, Compile folds by it, and everything that
+// packs programs or draws random ops derives from it. New primitives are
+// added with RegisterOp, usually from an init func, without touching the VM.
+//
+// OP_WHILE, OP_WHILE_END and NO_OP are registered like any other op but have
+// no Exec; brackets move the instruction pointer, which only the Tape and the
+// Machine can do, and NO_OP dumps their state in DEBUG mode.
+
+// Codes at or above EXTENDED_CODE take three nibbles in packed programs
+// instead of one.
+const EXTENDED_CODE = 0xF
+
+type OpDef struct {
+	Symbol   byte
+	Name     string // e.g. OP_INC, used in error messages
+	Code     byte   // Packed encoding of the op, unique and non-zero
+	Foldable bool   // Runs of the op compile into a single Bytecode
+
+	// Exec runs the op once on memory. index is the op's tape index, for
+	// error messages.
+	Exec func(memory *Memory, index int) (bool, error)
+
+	// Enabled reports whether random ops for a Machine configured by mc may
+	// be this op. nil means always. Disabled ops still run and pack.
+	Enabled func(mc *MachineConfig) bool
+}
+
+var (
+	registry    []*OpDef
+	opsBySymbol [256]*OpDef
+	opsByCode   [256]*OpDef
+)
+
+func init() {
+	for _, def := range []*OpDef{
+		{Symbol: OP_POINTER_LEFT, Name: "OP_POINTER_LEFT", Code: 1, Foldable: true, Exec: execPointerLeft},
+		{Symbol: OP_POINTER_RIGHT, Name: "OP_POINTER_RIGHT", Code: 2, Foldable: true, Exec: execPointerRight},
+		{Symbol: OP_INC, Name: "OP_INC", Code: 3, Foldable: true, Exec: execInc},
+		{Symbol: OP_DEC, Name: "OP_DEC", Code: 4, Foldable: true, Exec: execDec},
+		{Symbol: OP_WHILE, Name: "OP_WHILE", Code: 5},
+		{Symbol: OP_WHILE_END, Name: "OP_WHILE_END", Code: 6},
+		{Symbol: OP_JUMP, Name: "OP_JUMP", Code: 7, Exec: execJump},
+		{Symbol: OP_BOOKMARK, Name: "OP_BOOKMARK", Code: 8, Exec: execBookmark},
+		{Symbol: NO_OP, Name: "NO_OP", Code: 9},
+		{Symbol: OP_NEXT_BOOKMARK, Name: "OP_NEXT_BOOKMARK", Code: 10, Exec: execNextBookmark, Enabled: multipleBookmarks},
+		{Symbol: OP_FIRST_BOOKMARK, Name: "OP_FIRST_BOOKMARK", Code: 11, Exec: execFirstBookmark, Enabled: multipleBookmarks},
+		{Symbol: OP_CMP_SWAP, Name: "OP_CMP_SWAP", Code: 12, Exec: execCmpSwap, Enabled: cmpSwap},
+	} {
+		if err := register(def); err != nil {
+			panic(err)
+		}
+	}
+	copy(OP_SET[:], OpSet(nil))
+	copy(BOOKMARK_OP_SET[:], OpSet(&MachineConfig{BookmarkRegisters: 2})[len(OP_SET):])
+}
+
+// RegisterOp adds def to the dialect. The symbol and code must be unused and
+// def must have an Exec.
+func RegisterOp(def *OpDef) error {
+	if def.Exec == nil {
+		return fmt.Errorf("Failed to register op [%c]. No Exec function", def.Symbol)
+	}
+	return register(def)
+}
+
+func register(def *OpDef) error {
+	if def.Code == 0 {
+		return fmt.Errorf("Failed to register op [%c]. Code 0 is reserved for padding", def.Symbol)
+	}
+	if other := opsBySymbol[def.Symbol]; other != nil {
+		return fmt.Errorf("Failed to register op [%c]. Symbol already used by %s", def.Symbol, other.Name)
+	}
+	if other := opsByCode[def.Code]; other != nil {
+		return fmt.Errorf("Failed to register op [%c]. Code [%d] already used by %s", def.Symbol, def.Code, other.Name)
+	}
+	registry = append(registry, def)
+	opsBySymbol[def.Symbol] = def
+	opsByCode[def.Code] = def
+	return nil
+}
+
+// OpBySymbol returns the registered op for symbol, or nil.
+func OpBySymbol(symbol byte) *OpDef {
+	return opsBySymbol[symbol]
+}
+
+// OpByCode returns the registered op packed as code, or nil.
+func OpByCode(code byte) *OpDef {
+	return opsByCode[code]
+}
+
+// Ops returns every registered op in registration order.
+func Ops() []*OpDef {
+	return append([]*OpDef{}, registry...)
+}
+
+// OpSet returns the symbols of the ops enabled for a Machine configured by
+// mc, in registration order. nil means the default MachineConfig.
+func OpSet(mc *MachineConfig) []byte {
+	if mc == nil {
+		mc = &MachineConfig{}
+	}
+	ops := make([]byte, 0, len(registry))
+	for _, def := range registry {
+		if def.Enabled == nil || def.Enabled(mc) {
+			ops = append(ops, def.Symbol)
+		}
+	}
+	return ops
+}
+
+func multipleBookmarks(mc *MachineConfig) bool {
+	return mc.BookmarkRegisters > 1
+}
+
+func cmpSwap(mc *MachineConfig) bool {
+	return mc.CmpSwap
+}
+
+func execInc(memory *Memory, index int) (bool, error) {
+	if ok, err := memory.Increment(); !ok {
+		return false, newMachineError(writeErrorKind(err, ERR_INCREMENT_OVERFLOW), index, memory, fmt.Errorf("OP_INC at tape index [%d] failed to increment memory cell index [%d]. %v", index, memory.MemoryPointer, err))
+	}
+	return true, nil
+}
+
+func execDec(memory *Memory, index int) (bool, error) {
+	if ok, err := memory.Decrement(); !ok {
+		return false, newMachineError(writeErrorKind(err, ERR_DECREMENT_UNDERFLOW), index, memory, fmt.Errorf("OP_DEC at tape index [%d] failed to decrement memory cell index [%d]. %v", index, memory.MemoryPointer, err))
+	}
+	return true, nil
+}
+
+func execPointerLeft(memory *Memory, index int) (bool, error) {
+	if ok, err := memory.MovePointerLeft(); !ok {
+		return false, newMachineError(ERR_POINTER_UNDERFLOW, index, memory, fmt.Errorf("OP_POINTER_LEFT at tape index [%d] failed to move memory pointer left. %v", index, err))
+	}
+	return true, nil
+}
+
+func execPointerRight(memory *Memory, index int) (bool, error) {
+	if ok, err := memory.MovePointerRight(); !ok {
+		return false, newMachineError(ERR_POINTER_OVERFLOW, index, memory, fmt.Errorf("OP_POINTER_RIGHT at tape index [%d] failed to move memory pointer right. %v", index, err))
+	}
+	return true, nil
+}
+
+func execJump(memory *Memory, index int) (bool, error) {
+	if ok, err := memory.BookmarkJump(); !ok {
+		return false, newMachineError(ERR_BOOKMARK_OUT_OF_RANGE, index, memory, fmt.Errorf("OP_JUMP at tape index [%d] failed to jump. %v", index, err))
+	}
+	return true, nil
+}
+
+func execBookmark(memory *Memory, index int) (bool, error) {
+	if ok, err := memory.StoreBookmark(); !ok {
+		return false, newMachineError(ERR_BOOKMARK_OUT_OF_RANGE, index, memory, fmt.Errorf("OP_BOOKMARK at tape index [%d] failed to store. %v", index, err))
+	}
+	return true, nil
+}
+
+func execNextBookmark(memory *Memory, index int) (bool, error) {
+	memory.NextBookmark()
+	return true, nil
+}
+
+func execFirstBookmark(memory *Memory, index int) (bool, error) {
+	memory.FirstBookmark()
+	return true, nil
+}
+
+func execCmpSwap(memory *Memory, index int) (bool, error) {
+	ok, _, err := compareSwap(memory, index)
+	return ok, err
+}
+
+// compareSwap runs OP_CMP_SWAP like execCmpSwap, and also reports whether it
+// swapped the cells.
+func compareSwap(memory *Memory, index int) (bool, bool, error) {
+	ok, swapped, err := memory.CompareSwap()
+	if !ok {
+		return false, false, newMachineError(writeErrorKind(err, ERR_BOOKMARK_OUT_OF_RANGE), index, memory, fmt.Errorf("OP_CMP_SWAP at tape index [%d] failed to compare and swap. %v", index, err))
+	}
+	return true, swapped, nil
+}
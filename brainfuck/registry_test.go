@@ -0,0 +1,99 @@
+package brainfuck
+
+import (
+	"bytes"
+	"testing"
+)
+
+// OP_DOUBLE doubles the current cell. Registered for the tests only.
+const OP_DOUBLE = '!'
+
+func init() {
+	err := RegisterOp(&OpDef{
+		Symbol: OP_DOUBLE,
+		Name:   "OP_DOUBLE",
+		Code:   0x21,
+		Exec: func(memory *Memory, index int) (bool, error) {
+			_, val, _ := memory.GetCurrentCell()
+			for k := uint32(0); k < val; k++ {
+				if ok, err := memory.Increment(); !ok {
+					return false, err
+				}
+			}
+			return true, nil
+		},
+		Enabled: func(mc *MachineConfig) bool { return false },
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterOp(t *testing.T) {
+	tests := []*OpDef{
+		{Symbol: '~', Name: "NO_EXEC", Code: 0x22},
+		{Symbol: '~', Name: "PADDING", Code: 0, Exec: execInc},
+		{Symbol: OP_INC, Name: "TAKEN_SYMBOL", Code: 0x22, Exec: execInc},
+		{Symbol: '~', Name: "TAKEN_CODE", Code: 0x21, Exec: execInc},
+	}
+	for _, def := range tests {
+		if err := RegisterOp(def); err == nil {
+			t.Errorf("Unexpected success registering %s", def.Name)
+		}
+	}
+	if OpBySymbol('~') != nil {
+		t.Errorf("A failed registration left op [~] behind")
+	}
+	if OpByCode(0x21).Symbol != OP_DOUBLE {
+		t.Errorf("OpByCode(0x21) returned %v", OpByCode(0x21))
+	}
+}
+
+func TestRegisteredOpRuns(t *testing.T) {
+	tape, mem := NewTape("+++!"), NewMemory(2)
+	for {
+		if ok, err := tape.Execute(mem); !ok {
+			t.Fatalf("Unexpected failure when calling Tape.Execute(). %v", err)
+		}
+		if !tape.Advance() {
+			break
+		}
+	}
+	if mem.Cells[0] != 6 {
+		t.Errorf("Tape left cell [0] at %d, expected 6", mem.Cells[0])
+	}
+
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 100, MemoryCellCount: 2})
+	m.LoadProgram("+++!!")
+	if ok, err := m.Run(); !ok {
+		t.Fatalf("Unexpected failure running [+++!!]. %v", err)
+	}
+	if m.Memory.Cells[0] != 12 || m.InstructionCount != 5 {
+		t.Errorf("Machine left cell [0] at %d after %d ops, expected 12 after 5", m.Memory.Cells[0], m.InstructionCount)
+	}
+	if len(m.Program.Code) != 3 {
+		t.Errorf("Unfoldable op was folded: %v", m.Program.Code)
+	}
+}
+
+func TestOpSet(t *testing.T) {
+	base := []byte("<>+-[]^*#")
+	if ops := OpSet(nil); !bytes.Equal(ops, base) {
+		t.Errorf("OpSet(nil) returned %s, expected %s", ops, base)
+	}
+	if !bytes.Equal(OP_SET[:], base) || !bytes.Equal(BOOKMARK_OP_SET[:], []byte{OP_NEXT_BOOKMARK, OP_FIRST_BOOKMARK}) {
+		t.Errorf("Deprecated OP_SET %s and BOOKMARK_OP_SET %s don't match the registry", OP_SET[:], BOOKMARK_OP_SET[:])
+	}
+	if ops := OpSet(&MachineConfig{BookmarkRegisters: 2}); !bytes.Equal(ops, append(base, OP_NEXT_BOOKMARK, OP_FIRST_BOOKMARK)) {
+		t.Errorf("OpSet() with 2 bookmark registers returned %s", ops)
+	}
+	if ops := OpSet(&MachineConfig{CmpSwap: true}); !bytes.Equal(ops, append(base, OP_CMP_SWAP)) {
+		t.Errorf("OpSet() with OP_CMP_SWAP returned %s", ops)
+	}
+	if prefabs := PrefabOpSets(&MachineConfig{BookmarkRegisters: 2}); len(prefabs) != len(PREFAB_OPSETS)+len(BOOKMARK_PREFAB_OPSETS) {
+		t.Errorf("PrefabOpSets() with 2 bookmark registers returned %v", prefabs)
+	}
+	if prefabs := PrefabOpSets(&MachineConfig{CmpSwap: true}); len(prefabs) != len(PREFAB_OPSETS)+len(CMP_SWAP_PREFAB_OPSETS) {
+		t.Errorf("PrefabOpSets() with OP_CMP_SWAP returned %v", prefabs)
+	}
+}
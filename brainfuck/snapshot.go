@@ -0,0 +1,268 @@
+package brainfuck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Snapshots copy the full execution state of Memory, a Tape or a Machine so
+// it can be restored later, on the same value or a fresh one. They are plain
+// structs; a MachineSnapshot carries its program and MachineConfig, so it is
+// enough on its own to reproduce a run, and round trips through JSON with
+// Write and ReadMachineSnapshot.
+//
+// Machine snapshots use tape indexes rather than Bytecode indexes, so they
+// don't depend on how the program was compiled.
+
+type MemorySnapshot struct {
+	Cells            []uint32     `json:"cells"`
+	MemoryPointer    uint         `json:"memory_pointer"`
+	Bookmarks        []uint       `json:"bookmarks"` // Every bookmark register, the selected one included
+	SelectedBookmark uint         `json:"selected_bookmark"`
+	Overflow         OverflowMode `json:"overflow"`
+	MaxValue         uint32       `json:"max_value"`
+	ReadOnlyStart    uint         `json:"read_only_start"`
+	ReadOnlyEnd      uint         `json:"read_only_end"`
+}
+
+func (m *Memory) Snapshot() *MemorySnapshot {
+	s := &MemorySnapshot{
+		Cells:            make([]uint32, len(m.Cells)),
+		MemoryPointer:    m.MemoryPointer,
+		Bookmarks:        make([]uint, len(m.Bookmarks)),
+		SelectedBookmark: m.SelectedBookmark,
+		Overflow:         m.Overflow,
+		MaxValue:         m.MaxValue,
+		ReadOnlyStart:    m.ReadOnlyStart,
+		ReadOnlyEnd:      m.ReadOnlyEnd,
+	}
+	copy(s.Cells, m.Cells)
+	for i := range s.Bookmarks {
+		s.Bookmarks[i] = m.Bookmark(uint(i))
+	}
+	return s
+}
+
+// Restore replaces the Memory's state, cell count included, with the
+// snapshot's. An inconsistent snapshot leaves the Memory untouched.
+func (m *Memory) Restore(s *MemorySnapshot) (bool, error) {
+	cellCount := uint(len(s.Cells))
+	if cellCount == 0 {
+		return false, fmt.Errorf("Failed to restore memory. Snapshot has no cells")
+	}
+	if s.MemoryPointer > cellCount-1 {
+		return false, fmt.Errorf("Failed to restore memory. Memory pointer [%d] out of bounds (Memory length: [%d])", s.MemoryPointer, cellCount)
+	}
+	if len(s.Bookmarks) == 0 || s.SelectedBookmark > uint(len(s.Bookmarks))-1 {
+		return false, fmt.Errorf("Failed to restore memory. Selected bookmark [%d] out of bounds (Bookmark count: [%d])", s.SelectedBookmark, len(s.Bookmarks))
+	}
+	for i, b := range s.Bookmarks {
+		if b > cellCount-1 {
+			return false, fmt.Errorf("Failed to restore memory. Bookmark [%d] memory pointer [%d] out of bounds (Memory length: [%d])", i, b, cellCount)
+		}
+	}
+	if s.ReadOnlyStart > s.ReadOnlyEnd || s.ReadOnlyEnd > cellCount {
+		return false, fmt.Errorf("Failed to restore memory. Read only range [%d, %d) out of bounds (Memory length: [%d])", s.ReadOnlyStart, s.ReadOnlyEnd, cellCount)
+	}
+	for i, val := range s.Cells {
+		if val > s.MaxValue {
+			return false, fmt.Errorf("Failed to restore memory. Cell value [%d] at index [%d] is greater than cell maximum [%d]", val, i, s.MaxValue)
+		}
+	}
+
+	if uint(cap(m.Cells)) < cellCount {
+		m.Cells = make([]uint32, cellCount)
+	}
+	m.Cells = m.Cells[:cellCount]
+	copy(m.Cells, s.Cells)
+	m.CellCount = cellCount
+	m.MemoryPointer = s.MemoryPointer
+	m.Bookmarks = append(m.Bookmarks[:0], s.Bookmarks...)
+	m.SelectedBookmark = s.SelectedBookmark
+	m.BookmarkRegister = s.Bookmarks[s.SelectedBookmark]
+	m.Overflow = s.Overflow
+	m.MaxValue = s.MaxValue
+	m.ReadOnlyStart = s.ReadOnlyStart
+	m.ReadOnlyEnd = s.ReadOnlyEnd
+	return true, nil
+}
+
+type TapeSnapshot struct {
+	Instructions       string `json:"instructions"`
+	InstructionPointer int    `json:"instruction_pointer"`
+	WhileIndexStack    []int  `json:"while_index_stack"`
+}
+
+func (t *Tape) Snapshot() *TapeSnapshot {
+	s := &TapeSnapshot{
+		Instructions:       t.Instructions,
+		InstructionPointer: t.InstructionPointer,
+		WhileIndexStack:    make([]int, len(t.WhileIndexStack)),
+	}
+	copy(s.WhileIndexStack, t.WhileIndexStack)
+	return s
+}
+
+func (t *Tape) Restore(s *TapeSnapshot) (bool, error) {
+	if s.InstructionPointer < 0 || s.InstructionPointer > len(s.Instructions) {
+		return false, fmt.Errorf("Failed to restore tape. InstructionPointer [%d] out of bounds (Instruction length: [%d])", s.InstructionPointer, len(s.Instructions))
+	}
+	for _, i := range s.WhileIndexStack {
+		if i < 0 || i > len(s.Instructions)-1 || s.Instructions[i] != OP_WHILE {
+			return false, fmt.Errorf("Failed to restore tape. While stack index [%d] is not an OP_WHILE", i)
+		}
+	}
+
+	t.Instructions = s.Instructions
+	t.InstructionPointer = s.InstructionPointer
+	t.WhileIndexStack = append(t.WhileIndexStack[:0], s.WhileIndexStack...)
+	return true, nil
+}
+
+type MachineSnapshot struct {
+	Config           MachineConfig   `json:"config"`
+	Program          string          `json:"program"`
+	TapeIndex        int             `json:"tape_index"`        // Tape index of the next op to execute
+	WhileIndexStack  []int           `json:"while_index_stack"` // Tape indexes of the open OP_WHILEs
+	LoopIterations   []uint          `json:"loop_iterations"`   // Machine.LoopIterations
+	InstructionCount uint            `json:"instruction_count"`
+	LastOp           byte            `json:"last_op"`
+	Halted           bool            `json:"halted"`
+	Memory           *MemorySnapshot `json:"memory"`
+}
+
+func (m *Machine) Snapshot() *MachineSnapshot {
+	s := &MachineSnapshot{
+		Config:           *m.Config,
+		Program:          m.Program.Instructions,
+		TapeIndex:        m.TapeIndex(),
+		WhileIndexStack:  make([]int, len(m.WhileIndexStack)),
+		LoopIterations:   make([]uint, len(m.LoopIterations)),
+		InstructionCount: m.InstructionCount,
+		LastOp:           m.lastOp,
+		Halted:           m.halted,
+		Memory:           m.Memory.Snapshot(),
+	}
+	for i, pc := range m.WhileIndexStack {
+		s.WhileIndexStack[i] = m.Program.Code[pc].Index
+	}
+	copy(s.LoopIterations, m.LoopIterations)
+	return s
+}
+
+// Restore loads the snapshot's program and puts the Machine back in the
+// snapshot's state. The Machine keeps its own Config, Breakpoints and
+// Observer, so the snapshot must have as many cells as the Config; a Profile
+// starts over. An inconsistent snapshot leaves the
+// Machine untouched.
+func (m *Machine) Restore(s *MachineSnapshot) (bool, error) {
+	if s.Memory == nil {
+		return false, fmt.Errorf("Failed to restore machine. Snapshot has no memory")
+	}
+	if cellCount := uint(len(s.Memory.Cells)); cellCount != m.Config.MemoryCellCount {
+		return false, fmt.Errorf("Failed to restore machine. Snapshot has [%d] memory cells, the Machine [%d]", cellCount, m.Config.MemoryCellCount)
+	}
+	program := m.Program
+	if program == nil || program.Instructions != s.Program {
+		for i := 0; i < len(s.Program); i++ {
+			if OpBySymbol(s.Program[i]) == nil {
+				return false, fmt.Errorf("Failed to restore machine. Unknown op [%c] at tape index [%d]", s.Program[i], i)
+			}
+		}
+		program = Compile(s.Program)
+	}
+
+	pc, offset, ok := program.locate(s.TapeIndex)
+	if !ok {
+		return false, fmt.Errorf("Failed to restore machine. Tape index [%d] out of bounds (Instruction length: [%d])", s.TapeIndex, len(s.Program))
+	}
+	stack := make([]int, len(s.WhileIndexStack))
+	for i, index := range s.WhileIndexStack {
+		open, foldOffset, ok := program.locate(index)
+		if !ok || foldOffset != 0 || open >= len(program.Code) || program.Code[open].Op != OP_WHILE {
+			return false, fmt.Errorf("Failed to restore machine. While stack index [%d] is not an OP_WHILE", index)
+		}
+		stack[i] = open
+	}
+	iterations := s.LoopIterations
+	if iterations == nil {
+		// Snapshots from before loop limits. Every open loop starts over.
+		iterations = make([]uint, len(stack))
+		for i := range iterations {
+			iterations[i] = 1
+		}
+	}
+	switch len(iterations) - len(stack) {
+	case 0:
+	case 1:
+		if offset != 0 || pc >= len(program.Code) || program.Code[pc].Op != OP_WHILE {
+			return false, fmt.Errorf("Failed to restore machine. Loop iterations [%d] for [%d] open loops but tape index [%d] is not an OP_WHILE", len(iterations), len(stack), s.TapeIndex)
+		}
+	default:
+		return false, fmt.Errorf("Failed to restore machine. Loop iterations [%d] don't match [%d] open loops", len(iterations), len(stack))
+	}
+	if ok, err := m.Memory.Restore(s.Memory); !ok {
+		return false, err
+	}
+
+	m.Program = program
+	m.ProgramCounter = pc
+	m.FoldOffset = offset
+	m.WhileIndexStack = append(m.WhileIndexStack[:0], stack...)
+	m.LoopIterations = append(m.LoopIterations[:0], iterations...)
+	m.InstructionCount = s.InstructionCount
+	m.lastOp = s.LastOp
+	m.halted = s.Halted
+	m.paused = false
+	if m.Profile != nil {
+		m.Profile.reset(len(program.Instructions), len(m.Memory.Cells))
+	}
+	return true, nil
+}
+
+// NewMachineFromSnapshot creates a Machine with the snapshot's config and
+// restores the snapshot on it.
+func NewMachineFromSnapshot(s *MachineSnapshot) (*Machine, error) {
+	config := s.Config
+	if err := config.Check(); err != nil {
+		return nil, fmt.Errorf("Failed to restore machine. %w", err)
+	}
+	m := NewMachine(&config)
+	if ok, err := m.Restore(s); !ok {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Write encodes the snapshot as JSON.
+func (s *MachineSnapshot) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+// ReadMachineSnapshot decodes a snapshot written by MachineSnapshot.Write.
+func ReadMachineSnapshot(r io.Reader) (*MachineSnapshot, error) {
+	var s MachineSnapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("Failed to read machine snapshot. %v", err)
+	}
+	return &s, nil
+}
+
+// locate returns the code index and fold offset of the op at tape index. The
+// program length locates the end of the program.
+func (p *Program) locate(index int) (int, uint, bool) {
+	if index < 0 || index > len(p.Instructions) {
+		return 0, 0, false
+	}
+	if index == len(p.Instructions) {
+		return len(p.Code), 0, true
+	}
+	for pc := range p.Code {
+		bc := &p.Code[pc]
+		if index >= bc.Index && index < bc.Index+int(bc.Count) {
+			return pc, uint(index - bc.Index), true
+		}
+	}
+	return 0, 0, false
+}
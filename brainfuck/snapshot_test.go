@@ -0,0 +1,148 @@
+package brainfuck
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMachineSnapshotRestore(t *testing.T) {
+	config := &MachineConfig{MaxInstructionExecutionCount: 10000, MemoryCellCount: 6, BookmarkRegisters: 2}
+	input := []uint32{3, 7, 1}
+
+	for _, program := range []string{SWAP_RIGHT_MARKED, "+++[>++<-]>[-]", ""} {
+		// Run a reference Machine to the end, recording every state
+		reference := NewMachine(config)
+		reference.LoadProgram(program)
+		reference.LoadMemory(input)
+		var states []*MachineState
+		for {
+			running, _ := reference.Step()
+			states = append(states, reference.State())
+			if !running {
+				break
+			}
+		}
+
+		// Snapshot a second Machine partway, round trip the snapshot
+		// through JSON, and finish the run on a fresh Machine
+		for stop := 0; stop < len(states); stop += 7 {
+			m := NewMachine(config)
+			m.LoadProgram(program)
+			m.LoadMemory(input)
+			for i := 0; i <= stop; i++ {
+				m.Step()
+			}
+
+			var buf bytes.Buffer
+			if err := m.Snapshot().Write(&buf); err != nil {
+				t.Fatalf("Failed to write snapshot: %v", err)
+			}
+			s, err := ReadMachineSnapshot(&buf)
+			if err != nil {
+				t.Fatalf("Failed to read snapshot: %v", err)
+			}
+			restored, err := NewMachineFromSnapshot(s)
+			if err != nil {
+				t.Fatalf("Failed to restore snapshot of [%s] at step [%d]: %v", program, stop, err)
+			}
+
+			if !reflect.DeepEqual(restored.State(), states[stop]) {
+				t.Fatalf("Restored [%s] at step [%d] to %+v, expected %+v", program, stop, restored.State(), states[stop])
+			}
+			for i := stop + 1; i < len(states); i++ {
+				restored.Step()
+				if !reflect.DeepEqual(restored.State(), states[i]) {
+					t.Fatalf("Restored [%s] from step [%d] diverged at step [%d]: %+v, expected %+v", program, stop, i, restored.State(), states[i])
+				}
+			}
+		}
+	}
+}
+
+func TestMachineRestoreRejectsBadSnapshot(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 100, MemoryCellCount: 3})
+	m.LoadProgram("+[-]")
+	good := m.Snapshot()
+
+	bad := *good
+	bad.TapeIndex = 5
+	if ok, _ := m.Restore(&bad); ok {
+		t.Errorf("Restored a tape index past the program end")
+	}
+	bad = *good
+	bad.WhileIndexStack = []int{2}
+	if ok, _ := m.Restore(&bad); ok {
+		t.Errorf("Restored a while stack entry that isn't an OP_WHILE")
+	}
+	bad = *good
+	memory := *good.Memory
+	memory.Cells = []uint32{0, 300, 0}
+	bad.Memory = &memory
+	if ok, _ := m.Restore(&bad); ok {
+		t.Errorf("Restored a cell above the cell maximum")
+	}
+	bad = *good
+	memory = *good.Memory
+	memory.ReadOnlyStart, memory.ReadOnlyEnd = 1, 4
+	bad.Memory = &memory
+	if ok, _ := m.Restore(&bad); ok {
+		t.Errorf("Restored a read only range past the memory end")
+	}
+	bad = *good
+	bad.Program = "+[-]?"
+	if ok, _ := m.Restore(&bad); ok {
+		t.Errorf("Restored a program with an unknown op")
+	}
+
+	if m.Program.Instructions != "+[-]" || m.Memory.Cells[1] != 0 {
+		t.Errorf("A rejected snapshot changed the Machine")
+	}
+}
+
+func TestMachineRestoreCellCount(t *testing.T) {
+	m := NewMachine(&MachineConfig{MaxInstructionExecutionCount: 100, MemoryCellCount: 4})
+	m.LoadProgram("+")
+	s := m.Snapshot()
+	s.Memory.Cells = []uint32{1, 2}
+	if ok, _ := m.Restore(s); ok {
+		t.Fatalf("Restored [2] memory cells on a Machine configured for [4]")
+	}
+	if len(m.Memory.Cells) != 4 {
+		t.Errorf("A rejected snapshot left [%d] memory cells", len(m.Memory.Cells))
+	}
+}
+
+func TestTapeSnapshotRestore(t *testing.T) {
+	tape, mem := NewTape("++[>+<-]"), NewMemory(3)
+	for i := 0; i < 5; i++ {
+		tape.Execute(mem)
+		tape.Advance()
+	}
+	ts, ms := tape.Snapshot(), mem.Snapshot()
+
+	restoredTape, restoredMem := NewTape(""), NewMemory(1)
+	if ok, err := restoredTape.Restore(ts); !ok {
+		t.Fatalf("Failed to restore tape: %v", err)
+	}
+	if ok, err := restoredMem.Restore(ms); !ok {
+		t.Fatalf("Failed to restore memory: %v", err)
+	}
+
+	for _, pair := range []struct {
+		tape *Tape
+		mem  *Memory
+	}{{tape, mem}, {restoredTape, restoredMem}} {
+		for {
+			if ok, err := pair.tape.Execute(pair.mem); !ok {
+				t.Fatalf("Unexpected failure when calling Tape.Execute(). %v", err)
+			}
+			if !pair.tape.Advance() {
+				break
+			}
+		}
+	}
+	if !reflect.DeepEqual(mem, restoredMem) || !reflect.DeepEqual(tape, restoredTape) {
+		t.Errorf("Restored tape finished with %v, original with %v", restoredMem.Cells, mem.Cells)
+	}
+}
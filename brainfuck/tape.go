@@ -51,47 +51,46 @@ func (t *Tape) Execute(memory *Memory) (bool, error) {
 	ok, o, err := t.GetCurrentInstruction()
 
 	if !ok {
-		return false, err
+		if err != nil {
+			kind := ERR_OTHER
+			if len(t.Instructions) == 0 {
+				kind = ERR_EMPTY_PROGRAM
+			}
+			return false, newMachineError(kind, t.InstructionPointer, memory, err)
+		}
+		return false, nil
 	}
 
 	switch o {
-	case OP_INC:
-		if ok, err := memory.Increment(); !ok {
-			return false, fmt.Errorf("OP_INC at tape index [%d] failed to increment memory cell index [%d]. %v", t.InstructionPointer, memory.MemoryPointer, err)
-		}
-	case OP_DEC:
-		if ok, err := memory.Decrement(); !ok {
-			return false, fmt.Errorf("OP_DEC at tape index [%d] failed to decrement memory cell index [%d]. %v", t.InstructionPointer, memory.MemoryPointer, err)
-		}
-	case OP_POINTER_LEFT:
-		if ok, err := memory.MovePointerLeft(); !ok {
-			return false, fmt.Errorf("OP_POINTER_LEFT at tape index [%d] failed to move memory pointer left. %v", t.InstructionPointer, err)
-		}
-	case OP_POINTER_RIGHT:
-		if ok, err := memory.MovePointerRight(); !ok {
-			return false, fmt.Errorf("OP_POINTER_RIGHT at tape index [%d] failed to move memory pointer right. %v", t.InstructionPointer, err)
-		}
 	case OP_WHILE:
 		if ok, val, err := memory.GetCurrentCell(); ok {
 			if val != 0 {
 				t.WhileIndexStack = append(t.WhileIndexStack, t.InstructionPointer)
 			} else {
 				if t.InstructionPointer == len(t.Instructions)-1 {
-					return false, fmt.Errorf("OP_WHILE at tape index [%d] failed to advance to OP_WHILE_END instruction. Tape end reached.", t.InstructionPointer)
+					return false, newMachineError(ERR_UNMATCHED_BRACKET, t.InstructionPointer, memory, fmt.Errorf("OP_WHILE at tape index [%d] failed to advance to OP_WHILE_END instruction. Tape end reached.", t.InstructionPointer))
 				}
 
-				for i, op := range t.Instructions[t.InstructionPointer:len(t.Instructions)] {
-					if op == OP_WHILE_END {
-						// Move the instruction pointer to just before OP_WHILE_END
-						t.InstructionPointer = t.InstructionPointer + i - 1
-						return true, nil
+				// Scan for the matching OP_WHILE_END, stepping over nested loops
+				depth := 0
+				for i := t.InstructionPointer + 1; i < len(t.Instructions); i++ {
+					switch t.Instructions[i] {
+					case OP_WHILE:
+						depth++
+					case OP_WHILE_END:
+						if depth == 0 {
+							// Move the instruction pointer onto OP_WHILE_END so Advance steps past the loop
+							t.InstructionPointer = i
+							return true, nil
+						}
+						depth--
 					}
 				}
 
-				return false, fmt.Errorf("OP_WHILE at tape index [%d] failed to advance to OP_WHILE_END instruction. Tape end reached.", t.InstructionPointer)
+				return false, newMachineError(ERR_UNMATCHED_BRACKET, t.InstructionPointer, memory, fmt.Errorf("OP_WHILE at tape index [%d] failed to advance to OP_WHILE_END instruction. Tape end reached.", t.InstructionPointer))
 			}
 		} else {
-			return false, fmt.Errorf("OP_WHILE at tape index [%d] failed to get current memory cell at index [%d] during OP_WHILE evaluation. %v", t.InstructionPointer, memory.MemoryPointer, err)
+			return false, newMachineError(ERR_OTHER, t.InstructionPointer, memory, fmt.Errorf("OP_WHILE at tape index [%d] failed to get current memory cell at index [%d] during OP_WHILE evaluation. %v", t.InstructionPointer, memory.MemoryPointer, err))
 		}
 
 	case OP_WHILE_END:
@@ -100,14 +99,14 @@ func (t *Tape) Execute(memory *Memory) (bool, error) {
 				if len(t.WhileIndexStack) > 0 {
 					while_start := t.WhileIndexStack[len(t.WhileIndexStack)-1]
 					if while_start < 0 || while_start > len(t.Instructions)-1 {
-						return false, fmt.Errorf("OP_WHILE_END at tape index [%d] failed to fallback. InstructionPointer [%d] from while stack is out of bounds (Instruction length: [%d]", t.InstructionPointer, while_start, len(t.Instructions))
+						return false, newMachineError(ERR_UNMATCHED_BRACKET, t.InstructionPointer, memory, fmt.Errorf("OP_WHILE_END at tape index [%d] failed to fallback. InstructionPointer [%d] from while stack is out of bounds (Instruction length: [%d]", t.InstructionPointer, while_start, len(t.Instructions)))
 					}
 					t.WhileIndexStack = t.WhileIndexStack[:len(t.WhileIndexStack)-1]
 					// Move the instruction pointer to just before OP_WHILE
 					t.InstructionPointer = while_start - 1
 					return true, nil
 				} else {
-					return false, fmt.Errorf("OP_WHILE_END at tape index [%d] failed to fallback. Failed to pop while stack.", t.InstructionPointer)
+					return false, newMachineError(ERR_UNMATCHED_BRACKET, t.InstructionPointer, memory, fmt.Errorf("OP_WHILE_END at tape index [%d] failed to fallback. Failed to pop while stack.", t.InstructionPointer))
 				}
 			} else {
 				// Ensure we pop the while stack when current memory cell is zero and we advance past
@@ -116,22 +115,19 @@ func (t *Tape) Execute(memory *Memory) (bool, error) {
 				}
 			}
 		} else {
-			return false, fmt.Errorf("OP_WHILE at tape index [%d] failed to get current memory cell at index [%d] during OP_WHILE evaluation. %v", memory.MemoryPointer, t.InstructionPointer, err)
-		}
-	case OP_JUMP:
-		if ok, err := memory.BookmarkJump(); !ok {
-			return false, fmt.Errorf("OP_JUMP at tape index [%d] failed to jump. %v", t.InstructionPointer, err)
-		}
-	case OP_BOOKMARK:
-		if ok, err := memory.StoreBookmark(); !ok {
-			return false, fmt.Errorf("OP_BOOKMARK at tape index [%d] failed to store. %v", t.InstructionPointer, err)
+			return false, newMachineError(ERR_OTHER, t.InstructionPointer, memory, fmt.Errorf("OP_WHILE at tape index [%d] failed to get current memory cell at index [%d] during OP_WHILE evaluation. %v", memory.MemoryPointer, t.InstructionPointer, err))
 		}
 	case NO_OP:
 		if DEBUG {
 			fmt.Fprintf(os.Stderr, "\n---\nMACHINE STATE:\nMEMORY DUMP: %v\nMEMORY POINTER: %v\nINSTRUCTION DUMP: %v\nINSTRUCTION POINTER: %v\nWHILE STACK: %v\nBOOKMARK: %v\n", memory.Cells, memory.MemoryPointer, t.Instructions, t.InstructionPointer, t.WhileIndexStack, memory.BookmarkRegister)
 		}
 	default:
-		panic(fmt.Sprintf("Unknown OP [%v] encountered!", o))
+		def := OpBySymbol(o)
+		if def == nil || def.Exec == nil {
+			panic(fmt.Sprintf("Unknown OP [%v] encountered!", o))
+		}
+		ok, err := def.Exec(memory, t.InstructionPointer)
+		return ok, asMachineError(err, t.InstructionPointer, memory)
 	}
 
 	return true, nil
@@ -0,0 +1,396 @@
+package transpile
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+
+	bf "nickandperla.net/brainfuck"
+)
+
+// Transpiling turns a BF program into a standalone Go function
+//
+//	func Sort(cells []uint8) error
+//
+// that does exactly what a Machine configured the same way does: same final
+// cells, same exception, with the Machine's error message, on the same op.
+// The generated file only imports fmt, so it can be dropped into any package.
+//
+// The program is compiled first and the Go code follows the Bytecode: folded
+// runs become a single call, matched brackets become a Go for loop. The
+// execution limit and loop iterations are counted exactly like the Machine
+// counts them, so a transpiled sorter gives up where the evaluation did.
+
+// Config sets up the generated code. Machine is the config of the Machine
+// being reproduced; its memory cell count is ignored, the length of cells
+// takes its place. The function sorts cells in place with every cell
+// writable, so memory layouts with separate input and output cells or read
+// only input are not reproduced.
+type Config struct {
+	Package string // Defaults to "main"
+	Func    string // Exported name of the function. Defaults to "Sort"
+	Machine *bf.MachineConfig
+}
+
+// Go returns the gofmt'ed source of a Go file with program as a function.
+// Programs using ops without a Go translation, e.g. ones added with
+// bf.RegisterOp, and cells wider than 8 bits are rejected.
+func Go(program string, config *Config) ([]byte, error) {
+	c := *config
+	if c.Package == "" {
+		c.Package = "main"
+	}
+	if c.Func == "" {
+		c.Func = "Sort"
+	}
+	if !token.IsIdentifier(c.Package) {
+		return nil, fmt.Errorf("Failed to transpile. Package name [%s] is not an identifier", c.Package)
+	}
+	if !token.IsIdentifier(c.Func) || !token.IsExported(c.Func) {
+		return nil, fmt.Errorf("Failed to transpile. Function name [%s] is not an exported identifier", c.Func)
+	}
+	if c.Machine == nil {
+		return nil, fmt.Errorf("Failed to transpile. No MachineConfig")
+	}
+	if w := c.Machine.CellWidth; w != 0 && w != bf.CELL_WIDTH_8 {
+		return nil, fmt.Errorf("Failed to transpile. Cell width [%d] doesn't fit in uint8 cells", w)
+	}
+	for i := 0; i < len(program); i++ {
+		if _, ok := helpers[program[i]]; !ok {
+			return nil, fmt.Errorf("Failed to transpile. Op [%c] at tape index [%d] has no Go translation", program[i], i)
+		}
+	}
+
+	g := &generator{
+		config:   &c,
+		machine:  strings.ToLower(c.Func[:1]) + c.Func[1:] + "Machine",
+		used:     make(map[string]bool),
+		compiled: bf.Compile(program),
+	}
+	g.file(program)
+
+	src, err := format.Source([]byte(g.b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to transpile. Generated code doesn't parse. %v", err)
+	}
+	return src, nil
+}
+
+// helpers maps each op to the machine method that runs it. Brackets are
+// written out as Go control flow and use step.
+var helpers = map[byte]string{
+	bf.OP_POINTER_LEFT:   "left",
+	bf.OP_POINTER_RIGHT:  "right",
+	bf.OP_INC:            "inc",
+	bf.OP_DEC:            "dec",
+	bf.OP_WHILE:          "step",
+	bf.OP_WHILE_END:      "step",
+	bf.OP_JUMP:           "jump",
+	bf.OP_BOOKMARK:       "store",
+	bf.NO_OP:             "step",
+	bf.OP_NEXT_BOOKMARK:  "next",
+	bf.OP_FIRST_BOOKMARK: "first",
+	bf.OP_CMP_SWAP:       "cmpSwap",
+}
+
+type generator struct {
+	config   *Config
+	machine  string // Name of the generated state type
+	used     map[string]bool
+	compiled *bf.Program
+	b        strings.Builder
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.b, format, args...)
+}
+
+func (g *generator) file(program string) {
+	c := g.config
+	g.printf("// Code generated by transpile from a brainfuck program. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\nimport \"fmt\"\n\n", c.Package)
+
+	g.printf("// %s runs the brainfuck program\n//\n", c.Func)
+	for _, line := range wrap(program, 64) {
+		g.printf("//\t%s\n", line)
+	}
+	g.printf("//\n// on cells, in place, the way a Machine with %d bookmark register(s), overflow\n", g.registers())
+	g.printf("// mode %s and an execution limit of %d ops would. The returned error has\n", c.Machine.OverflowMode, c.Machine.MaxInstructionExecutionCount)
+	g.printf("// the Machine's message.\n")
+	if max := c.Machine.MaxLoopIterations; max > 0 {
+		g.printf("//\n// A loop may start at most %d iterations each time it's entered.\n", max)
+	}
+	g.printf("func %s(cells []uint8) error {\n", c.Func)
+	g.printf("if len(cells) == 0 {\nreturn fmt.Errorf(\"%s needs at least one cell\")\n}\n", c.Func)
+	g.printf("m := &%s{cells: cells}\n", g.machine)
+	if len(g.compiled.Code) == 0 {
+		// Mirrors Machine.runEmpty
+		g.printf("return m.count(1, fmt.Errorf(\"InstructionPointer [%%d] out of bounds (Instruction length: [%%d]\", 0, 0))\n}\n")
+	} else {
+		g.block(0, len(g.compiled.Code))
+		g.printf("return nil\n}\n")
+	}
+
+	g.printf("\n// %s is the state of %s.\n", g.machine, c.Func)
+	g.printf("type %s struct {\n", g.machine)
+	g.printf("cells []uint8\np int // Memory pointer\nbookmark int // The selected bookmark register\n")
+	g.printf("bookmarks [%d]int // Every register, the selected one's slot only updated when another gets selected\n", g.registers())
+	g.printf("selected int\nexecuted uint // Ops executed, counted like Machine.InstructionCount\n}\n")
+
+	g.used["count"] = true
+	for _, name := range helperOrder {
+		if g.used[name] {
+			g.printf("\n")
+			g.printf(strings.ReplaceAll(helperSource[name], "MACHINE", g.machine), g.helperArgs(name)...)
+		}
+	}
+}
+
+// block writes the Go code for the Bytecode in [from, to).
+func (g *generator) block(from, to int) {
+	code := g.compiled.Code
+	for pc := from; pc < to; pc++ {
+		bc := &code[pc]
+		source := g.compiled.Instructions[bc.Index : bc.Index+int(bc.Count)]
+		switch {
+		case bc.Op == bf.OP_WHILE && bc.Jump != bf.NO_MATCH:
+			end := &code[bc.Jump]
+			g.call("step()", bc.Index, "[")
+			if g.config.Machine.MaxLoopIterations > 0 {
+				g.used["loop"] = true
+				g.printf("if m.cells[m.p] != 0 {\niterations%d := uint(1)\nfor {\n", bc.Index)
+			} else {
+				g.printf("if m.cells[m.p] != 0 {\nfor {\n")
+			}
+			g.block(pc+1, bc.Jump)
+			g.call("step()", end.Index, "]")
+			g.printf("if m.cells[m.p] == 0 {\nbreak\n}\n")
+			if g.config.Machine.MaxLoopIterations > 0 {
+				g.printf("iterations%d++\n", bc.Index)
+				g.call(fmt.Sprintf("loop(%d, iterations%d)", bc.Index, bc.Index), bc.Index, "[")
+			} else {
+				g.call("step()", bc.Index, "[")
+			}
+			g.printf("}\n}\n")
+			pc = bc.Jump
+		case bc.Op == bf.OP_WHILE:
+			g.used["unmatchedOpen"] = true
+			g.call(fmt.Sprintf("unmatchedOpen(%d)", bc.Index), bc.Index, source)
+		case bc.Op == bf.OP_WHILE_END:
+			g.used["unmatchedClose"] = true
+			g.call(fmt.Sprintf("unmatchedClose(%d)", bc.Index), bc.Index, source)
+		default:
+			name := helpers[bc.Op]
+			g.used[name] = true
+			if bf.OpBySymbol(bc.Op).Foldable {
+				g.used["budget"] = true
+				g.call(fmt.Sprintf("%s(%d, %d)", name, bc.Count, bc.Index), bc.Index, source)
+			} else {
+				g.call(name+"()", bc.Index, source)
+			}
+		}
+	}
+}
+
+func (g *generator) call(method string, index int, source string) {
+	if method == "step()" {
+		g.used["step"] = true
+	}
+	g.printf("if err := m.%s; err != nil { // %d: %s\nreturn err\n}\n", method, index, source)
+}
+
+func (g *generator) registers() uint {
+	if g.config.Machine.BookmarkRegisters < 1 {
+		return 1
+	}
+	return g.config.Machine.BookmarkRegisters
+}
+
+// helperArgs returns the values for the verbs in a helper's source.
+func (g *generator) helperArgs(name string) []interface{} {
+	switch name {
+	case "budget", "count":
+		return []interface{}{g.config.Machine.MaxInstructionExecutionCount}
+	case "loop":
+		return []interface{}{g.config.Machine.MaxLoopIterations}
+	case "inc", "dec":
+		return []interface{}{overflowSource[name][g.config.Machine.OverflowMode]}
+	case "next":
+		return []interface{}{g.registers()}
+	}
+	return nil
+}
+
+func wrap(s string, width int) []string {
+	if s == "" {
+		return []string{"(empty)"}
+	}
+	var lines []string
+	for len(s) > width {
+		lines = append(lines, s[:width])
+		s = s[width:]
+	}
+	return append(lines, s)
+}
+
+var helperOrder = []string{"budget", "count", "step", "loop", "left", "right", "inc", "dec",
+	"jump", "store", "next", "first", "cmpSwap", "unmatchedOpen", "unmatchedClose"}
+
+// helperSource is the source of each machine method. MACHINE is replaced by
+// the state type's name and the verbs are filled in by helperArgs. Folded ops
+// take the run length and the tape index of its first op.
+var helperSource = map[string]string{
+	"budget": `// budget returns how many of n folded ops may run before the execution limit.
+func (m *MACHINE) budget(n uint) uint {
+	b := %d - m.executed
+	if b == 0 {
+		b = 1
+	}
+	if n > b {
+		return b
+	}
+	return n
+}
+`,
+	"count": `// count records ran executed ops, the failed one included. Reaching the
+// execution limit takes precedence over err.
+func (m *MACHINE) count(ran uint, err error) error {
+	m.executed += ran
+	if m.executed >= %d {
+		return fmt.Errorf("Instruction execution count limit reached")
+	}
+	return err
+}
+`,
+	"step": `func (m *MACHINE) step() error {
+	return m.count(1, nil)
+}
+`,
+	"loop": `// loop reenters a loop about to start its iterations'th iteration.
+func (m *MACHINE) loop(index int, iterations uint) error {
+	if iterations > %d {
+		return m.count(1, fmt.Errorf("OP_WHILE at tape index [%%d] failed to start iteration [%%d]. Loop iteration limit reached", index, iterations))
+	}
+	return m.count(1, nil)
+}
+`,
+	"left": `func (m *MACHINE) left(n uint, index int) error {
+	n = m.budget(n)
+	if uint(m.p) >= n {
+		m.p -= int(n)
+		return m.count(n, nil)
+	}
+	ran, failed := uint(m.p)+1, index+m.p
+	m.p = 0
+	return m.count(ran, fmt.Errorf("OP_POINTER_LEFT at tape index [%%d] failed to move memory pointer left. Failed to move memory pointer [%%d] left. Out of bounds (Memory length: [%%d])", failed, m.p, len(m.cells)))
+}
+`,
+	"right": `func (m *MACHINE) right(n uint, index int) error {
+	n = m.budget(n)
+	last := uint(len(m.cells) - 1)
+	if uint(m.p)+n <= last {
+		m.p += int(n)
+		return m.count(n, nil)
+	}
+	ran := last - uint(m.p) + 1
+	m.p = int(last)
+	return m.count(ran, fmt.Errorf("OP_POINTER_RIGHT at tape index [%%d] failed to move memory pointer right. Failed to move memory pointer [%%d] right. Out of bounds (Memory length: [%%d])", index+int(ran)-1, m.p, len(m.cells)))
+}
+`,
+	"inc": `func (m *MACHINE) inc(n uint, index int) error {
+	n = m.budget(n)
+	v := uint(m.cells[m.p])
+	if v+n <= 255 {
+		m.cells[m.p] = uint8(v + n)
+		return m.count(n, nil)
+	}
+%s}
+`,
+	"dec": `func (m *MACHINE) dec(n uint, index int) error {
+	n = m.budget(n)
+	v := uint(m.cells[m.p])
+	if n <= v {
+		m.cells[m.p] = uint8(v - n)
+		return m.count(n, nil)
+	}
+%s}
+`,
+	"jump": `func (m *MACHINE) jump() error {
+	m.p, m.bookmark = m.bookmark, m.p
+	return m.count(1, nil)
+}
+`,
+	"store": `func (m *MACHINE) store() error {
+	m.bookmark = m.p
+	return m.count(1, nil)
+}
+`,
+	"next": `func (m *MACHINE) next() error {
+	m.bookmarks[m.selected] = m.bookmark
+	m.selected = (m.selected + 1) %% %d
+	m.bookmark = m.bookmarks[m.selected]
+	return m.count(1, nil)
+}
+`,
+	"first": `func (m *MACHINE) first() error {
+	m.bookmarks[m.selected] = m.bookmark
+	m.selected = 0
+	m.bookmark = m.bookmarks[0]
+	return m.count(1, nil)
+}
+`,
+	"cmpSwap": `func (m *MACHINE) cmpSwap() error {
+	low, high := m.p, m.bookmark
+	if low > high {
+		low, high = high, low
+	}
+	if m.cells[low] > m.cells[high] {
+		m.cells[low], m.cells[high] = m.cells[high], m.cells[low]
+	}
+	return m.count(1, nil)
+}
+`,
+	"unmatchedOpen": `func (m *MACHINE) unmatchedOpen(index int) error {
+	if m.cells[m.p] == 0 {
+		return m.count(1, fmt.Errorf("OP_WHILE at tape index [%%d] failed to advance to OP_WHILE_END instruction. Tape end reached.", index))
+	}
+	return m.count(1, nil)
+}
+`,
+	"unmatchedClose": `func (m *MACHINE) unmatchedClose(index int) error {
+	if m.cells[m.p] != 0 {
+		return m.count(1, fmt.Errorf("OP_WHILE_END at tape index [%%d] failed to fallback. Failed to pop while stack.", index))
+	}
+	return m.count(1, nil)
+}
+`,
+}
+
+// overflowSource finishes inc and dec for runs that pass a cell's bound.
+var overflowSource = map[string]map[bf.OverflowMode]string{
+	"inc": {
+		bf.OVERFLOW_ERROR: `	ran := 255 - v + 1
+	m.cells[m.p] = 255
+	return m.count(ran, fmt.Errorf("OP_INC at tape index [%d] failed to increment memory cell index [%d]. Increment failed. Cell value [%d] at UpperBound [%d]", index+int(ran)-1, m.p, 255, 255))
+`,
+		bf.OVERFLOW_WRAP: `	m.cells[m.p] = uint8(v + n)
+	return m.count(n, nil)
+`,
+		bf.OVERFLOW_SATURATE: `	m.cells[m.p] = 255
+	return m.count(n, nil)
+`,
+	},
+	"dec": {
+		bf.OVERFLOW_ERROR: `	ran := v + 1
+	m.cells[m.p] = 0
+	return m.count(ran, fmt.Errorf("OP_DEC at tape index [%d] failed to decrement memory cell index [%d]. Decrement failed. Cell value [%d] at LowerBound [%d]", index+int(v), m.p, 0, 0))
+`,
+		bf.OVERFLOW_WRAP: `	m.cells[m.p] = uint8(v - n)
+	return m.count(n, nil)
+`,
+		bf.OVERFLOW_SATURATE: `	m.cells[m.p] = 0
+	return m.count(n, nil)
+`,
+	},
+}
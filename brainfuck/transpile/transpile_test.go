@@ -0,0 +1,193 @@
+package transpile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	bf "nickandperla.net/brainfuck"
+)
+
+func TestGoRejects(t *testing.T) {
+	mc := &bf.MachineConfig{MaxInstructionExecutionCount: 100}
+	tests := []struct {
+		program string
+		config  *Config
+	}{
+		{"+!", &Config{Machine: mc}},
+		{"+", &Config{Machine: &bf.MachineConfig{CellWidth: bf.CELL_WIDTH_16}}},
+		{"+", &Config{Func: "sort", Machine: mc}},
+		{"+", &Config{Package: "my-sorter", Machine: mc}},
+		{"+", &Config{}},
+	}
+	for _, tt := range tests {
+		if src, err := Go(tt.program, tt.config); err == nil {
+			t.Errorf("Transpiling [%s] with %+v returned no error\n%s", tt.program, tt.config, src)
+		}
+	}
+}
+
+func TestGoFile(t *testing.T) {
+	src, err := Go(bf.SWAP_RIGHT, &Config{Package: "sorter", Machine: &bf.MachineConfig{MaxInstructionExecutionCount: 1000}})
+	if err != nil {
+		t.Fatalf("Failed to transpile: %v", err)
+	}
+	for _, want := range []string{"package sorter", "func Sort(cells []uint8) error", "type sortMachine struct", "func (m *sortMachine) jump() error"} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("Generated code has no [%s]\n%s", want, src)
+		}
+	}
+	// SWAP_RIGHT has no OP_CMP_SWAP, so neither does its machine
+	if bytes.Contains(src, []byte("cmpSwap")) {
+		t.Errorf("Generated code has an unused helper\n%s", src)
+	}
+}
+
+type differentialCase struct {
+	program string
+	config  bf.MachineConfig
+	inputs  [][]uint8
+}
+
+type differentialResult struct {
+	Cells []int
+	Err   string
+}
+
+// TestGoMatchesMachine transpiles a mix of prefabs, edge cases and random
+// programs, builds them with the go tool and checks every one leaves the same
+// cells and error as the Machine on the same inputs.
+func TestGoMatchesMachine(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Builds the transpiled code with the go tool")
+	}
+	goTool := filepath.Join(runtime.GOROOT(), "bin", "go")
+	if _, err := exec.LookPath(goTool); err != nil {
+		t.Skipf("No go tool: %v", err)
+	}
+
+	programs := append([]string{"", "#", "<", ">>>>>>>>", "-", "+]", "[", "+[]", "+[>+]", "[-]+[", "*>>+++%<<^%",
+		"+&>*&>>*@^-^&^", "+++[>++<-]>[<<+>>-]"},
+		bf.PREFAB_OPSETS[:]...)
+	programs = append(programs, bf.BOOKMARK_PREFAB_OPSETS[:]...)
+	programs = append(programs, bf.CMP_SWAP_PREFAB_OPSETS[:]...)
+	r := rand.New(rand.NewSource(7))
+	ops := bf.OpSet(&bf.MachineConfig{BookmarkRegisters: 2, CmpSwap: true})
+	for i := 0; i < 40; i++ {
+		p := make([]byte, 1+r.Intn(60))
+		for j := range p {
+			p[j] = ops[r.Intn(len(ops))]
+		}
+		programs = append(programs, string(p))
+	}
+
+	var cases []differentialCase
+	for i, program := range programs {
+		for _, mode := range []bf.OverflowMode{bf.OVERFLOW_ERROR, bf.OVERFLOW_WRAP, bf.OVERFLOW_SATURATE} {
+			c := differentialCase{program: program, config: bf.MachineConfig{
+				MaxInstructionExecutionCount: []uint{0, 1, 7, 60, 2000}[i%5],
+				OverflowMode:                 mode,
+				BookmarkRegisters:            uint(i % 3),
+				MaxLoopIterations:            []uint{0, 2, 0, 5}[i%4],
+			}}
+			for k := 0; k < 4; k++ {
+				input := make([]uint8, 1+r.Intn(8))
+				for j := range input {
+					input[j] = []uint8{0, 1, 254, 255, uint8(r.Intn(256))}[r.Intn(5)]
+				}
+				c.inputs = append(c.inputs, input)
+			}
+			cases = append(cases, c)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "transpile")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, src []byte) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), src, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	write("go.mod", []byte("module transpiled\n\ngo 1.15\n"))
+
+	var harness strings.Builder
+	harness.WriteString("package main\n\nimport (\n\t\"encoding/json\"\n\t\"os\"\n)\n\n")
+	harness.WriteString("type result struct {\n\tCells []int\n\tErr   string\n}\n\n")
+	harness.WriteString("var cases = []struct {\n\tsort   func([]uint8) error\n\tinputs [][]uint8\n}{\n")
+	for i, c := range cases {
+		config := c.config
+		src, err := Go(c.program, &Config{Func: fmt.Sprintf("Sort%d", i), Machine: &config})
+		if err != nil {
+			t.Fatalf("Failed to transpile [%s]: %v", c.program, err)
+		}
+		write(fmt.Sprintf("sort%d.go", i), src)
+		fmt.Fprintf(&harness, "\t{Sort%d, %#v},\n", i, c.inputs)
+	}
+	harness.WriteString("}\n\nfunc main() {\n\tenc := json.NewEncoder(os.Stdout)\n")
+	harness.WriteString("\tfor _, c := range cases {\n\t\tfor _, input := range c.inputs {\n")
+	harness.WriteString("\t\t\tr := result{}\n\t\t\tif err := c.sort(input); err != nil {\n\t\t\t\tr.Err = err.Error()\n\t\t\t}\n")
+	harness.WriteString("\t\t\tfor _, v := range input {\n\t\t\t\tr.Cells = append(r.Cells, int(v))\n\t\t\t}\n")
+	harness.WriteString("\t\t\tenc.Encode(r)\n\t\t}\n\t}\n}\n")
+	write("main.go", []byte(harness.String()))
+
+	cmd := exec.Command(goTool, "run", ".")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Failed to run the transpiled code: %v\n%s", err, stderr.String())
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for _, c := range cases {
+		for _, input := range c.inputs {
+			want := runMachine(t, c, input)
+			var got differentialResult
+			if !scanner.Scan() {
+				t.Fatalf("Transpiled code stopped early")
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to read result: %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("Program [%s] with %+v on %v\nTranspiled: %v\nMachine:    %v", c.program, c.config, input, got, want)
+			}
+		}
+	}
+}
+
+func runMachine(t *testing.T, c differentialCase, input []uint8) differentialResult {
+	config := c.config
+	config.MemoryCellCount = uint(len(input))
+	m := bf.NewMachine(&config)
+	m.LoadProgram(c.program)
+	cells := make([]uint32, len(input))
+	for i, v := range input {
+		cells[i] = uint32(v)
+	}
+	if ok, err := m.LoadMemory(cells); !ok {
+		t.Fatalf("Failed to load memory: %v", err)
+	}
+
+	var r differentialResult
+	if _, err := m.Run(); err != nil {
+		r.Err = err.Error()
+	}
+	for _, v := range m.Memory.Cells {
+		r.Cells = append(r.Cells, int(v))
+	}
+	return r
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	bf "nickandperla.net/brainfuck"
+	"nickandperla.net/genetic_sort"
+
+	"github.com/BurntSushi/toml"
+)
+
+var toolConfigPath = flag.String("config", "./config.toml", "The config file for genetic_sort tools to use")
+var popId = flag.Uint("popid", 1, "The id of the population whose champion to print")
+var raw = flag.Bool("raw", false, "Also print the champion's program as evolved")
+var genes = flag.Bool("genes", false, "Profile the champion and print which Instruction genes are live code and which are introns")
+
+func main() {
+	flag.Parse()
+
+	conffile, err := os.Open(*toolConfigPath)
+	if err != nil {
+		log.Fatalf("Unable to load genetic_sort config: %v", err)
+	}
+
+	confDecoder := toml.NewDecoder(conffile)
+	var toolConfig genetic_sort.ToolConfig
+	if _, err = confDecoder.Decode(&toolConfig); err != nil {
+		log.Fatalf("Failed to unmarshal tool config: %v", err)
+	}
+	conffile.Close()
+
+	persist, err := genetic_sort.NewPersistence(toolConfig.Persistence)
+	if err != nil {
+		log.Fatalf("Failed to create or initialize Persistence: %v", err)
+	}
+	defer persist.Shutdown()
+
+	pop, err := persist.LoadShallow(*popId)
+	if err != nil {
+		log.Fatalf("Unable to load population from DB: %v", err)
+	}
+
+	unit, eval, err := pop.QueryBestUnit()
+	if err != nil {
+		log.Fatalf("Failed to find the champion: %v", err)
+	}
+	if unit == nil {
+		log.Fatalf("Population %d has no evaluated alive units", pop.ID)
+	}
+
+	program := genetic_sort.Instructions(unit.Instructions).ToProgram()
+	optimized := bf.Optimize(program, pop.PopulationConfig.EvaluatorConfig.MachineConfig.OverflowMode)
+
+	fmt.Printf("Population %d champion: unit %d (generation %d)\n", pop.ID, unit.ID, unit.Generation)
+	fmt.Printf("  Sortedness:            %d\n", eval.Sortedness)
+	fmt.Printf("  Set fidelity:          %d\n", eval.SetFidelity)
+	fmt.Printf("  Instructions executed: %d\n", eval.InstructionsExecuted)
+	fmt.Printf("  Length:                %d -> %d\n", len(program), len(optimized))
+	if *raw {
+		fmt.Printf("\n%s\n", program)
+	}
+	fmt.Printf("\n%s\n", optimized)
+
+	if *genes {
+		printGenes(pop.PopulationConfig.EvaluatorConfig, unit)
+	}
+}
+
+// printGenes runs the champion once more with profiling on and prints how
+// often each of its genes executed.
+func printGenes(config *genetic_sort.EvaluatorConfig, unit *genetic_sort.Unit) {
+	// Profiles only line up with the genes for the program as evolved
+	c := *config
+	c.Optimize = false
+	evaluator := genetic_sort.NewEvaluator(&c)
+	evaluator.EnableProfiling()
+	executions := evaluator.Evaluate(unit.Clone()).GeneExecutions(unit)
+
+	fmt.Printf("\nGene  Executed  Ops\n")
+	var introns int
+	for g, ins := range unit.Instructions {
+		marker := ""
+		if executions[g] == 0 {
+			marker = "  (intron)"
+			introns++
+		}
+		fmt.Printf("%4d  %8d  %s%s\n", g, executions[g], ins.ToProgram(), marker)
+	}
+	fmt.Printf("%d of %d genes are introns\n", introns, len(unit.Instructions))
+}
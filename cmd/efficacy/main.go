@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"nickandperla.net/genetic_sort"
+
+	"github.com/BurntSushi/toml"
+)
+
+var toolConfigPath = flag.String("config", "./config.toml", "The config file for genetic_sort tools to use")
+var popId = flag.Uint("popid", 1, "The id of the population whose mutation efficacy to print")
+
+func main() {
+	flag.Parse()
+
+	conffile, err := os.Open(*toolConfigPath)
+	if err != nil {
+		log.Fatalf("Unable to load genetic_sort config: %v", err)
+	}
+
+	confDecoder := toml.NewDecoder(conffile)
+	var toolConfig genetic_sort.ToolConfig
+	if _, err = confDecoder.Decode(&toolConfig); err != nil {
+		log.Fatalf("Failed to unmarshal tool config: %v", err)
+	}
+	conffile.Close()
+
+	persist, err := genetic_sort.NewPersistence(toolConfig.Persistence)
+	if err != nil {
+		log.Fatalf("Failed to create or initialize Persistence: %v", err)
+	}
+	defer persist.Shutdown()
+
+	pop, err := persist.LoadShallow(*popId)
+	if err != nil {
+		log.Fatalf("Unable to load population from DB: %v", err)
+	}
+
+	report, err := pop.MutationEfficacy()
+	if err != nil {
+		log.Fatalf("Failed to report mutation efficacy: %v", err)
+	}
+
+	fmt.Printf("Population %d mutation efficacy, children ranked against their parents\n", pop.ID)
+	printTable("Meta op", append([]*genetic_sort.Efficacy{&report.Unmutated}, report.MetaOps...))
+	printTable("Op", report.Ops)
+	printTable("Gene meta op", report.GeneMetaOps)
+}
+
+func printTable(title string, efficacies []*genetic_sort.Efficacy) {
+	if len(efficacies) == 0 {
+		return
+	}
+	fmt.Printf("\n%-12s  %8s  %10s  %8s  %11s  %10s\n", title, "Total", "Beneficial", "Neutral", "Deleterious", "Avg dSort")
+	for _, e := range efficacies {
+		if e.Total() == 0 {
+			continue
+		}
+		beneficial, neutral, deleterious := e.Rates()
+		fmt.Printf("%-12s  %8d  %9.2f%%  %7.2f%%  %10.2f%%  %+10.2f\n", e.Name, e.Total(),
+			beneficial*100, neutral*100, deleterious*100, float64(e.SortednessDelta)/float64(e.Total()))
+	}
+}
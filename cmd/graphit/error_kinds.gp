@@ -0,0 +1,44 @@
+# Plot machine error kinds by generation
+#
+# Usage:
+#   gnuplot -e "db='path/to/genetic_sort.db'; pop=1" error_kinds.gp
+#
+# Kind codes (brainfuck.ErrorKind):
+#   1 = other
+#   2 = pointer_underflow
+#   3 = pointer_overflow
+#   4 = increment_overflow
+#   5 = decrement_underflow
+#   6 = unmatched_bracket
+#   7 = bookmark_out_of_range
+#   8 = execution_limit
+#   9 = empty_program
+#  10 = read_only_violation
+#  11 = loop_limit
+#
+# Failed evaluations from before kinds were recorded count as other.
+#
+# Defaults:
+if (!exists("db")) db = "genetic_sort.db"
+if (!exists("pop")) pop = 1
+
+set terminal pngcairo size 900,500 enhanced
+
+set title sprintf("Machine Errors Over Generations — Population %d", pop)
+set xlabel "Generation"
+set ylabel "Evaluations"
+set grid
+set key top left
+set datafile separator "|"
+
+plot '< sqlite3 '.db.' "SELECT u.generation, SUM(e.machine_error_kind = 2), SUM(e.machine_error_kind = 3), SUM(e.machine_error_kind = 4), SUM(e.machine_error_kind = 5), SUM(e.machine_error_kind = 6), SUM(e.machine_error_kind = 7), SUM(e.machine_error_kind = 8), SUM(e.machine_error_kind = 11), SUM(e.machine_error_kind = 10), SUM(e.machine_error_kind IN (1, 9)) FROM units u JOIN evaluations e ON e.unit_id = u.id WHERE u.population_id = '.pop.' GROUP BY u.generation ORDER BY u.generation"' \
+    using 1:2 with lines title "Pointer Underflow", \
+    '' using 1:3 with lines title "Pointer Overflow", \
+    '' using 1:4 with lines title "Increment Overflow", \
+    '' using 1:5 with lines title "Decrement Underflow", \
+    '' using 1:6 with lines title "Unmatched Bracket", \
+    '' using 1:7 with lines title "Bookmark Out Of Range", \
+    '' using 1:8 with lines title "Execution Limit", \
+    '' using 1:9 with lines title "Loop Limit", \
+    '' using 1:10 with lines title "Read Only Violation", \
+    '' using 1:11 with lines title "Other"
@@ -221,10 +221,10 @@ func runTrial(toolConfig *genetic_sort.ToolConfig, popConfig *genetic_sort.Popul
 			}
 
 			effectiveInput := popConfig.EvaluatorConfig.ComputeEffectiveInputCellCount(pop.CurrentGeneration)
-			log.Printf("  Gen %d: alive=%d effective_input=%d best_sort=%d best_fid=%d avg_sort=%.1f avg_fid=%.1f",
+			log.Printf("  Gen %d: alive=%d effective_input=%d best_sort=%d best_fid=%d avg_sort=%.1f avg_fid=%.1f avg_mut=%.4f",
 				gen, metrics.AliveCount, effectiveInput,
 				metrics.BestSortedness, metrics.BestSetFidelity,
-				metrics.AvgSortedness, metrics.AvgSetFidelity)
+				metrics.AvgSortedness, metrics.AvgSetFidelity, metrics.AvgMutationChance)
 
 			result.BestSortedness = metrics.BestSortedness
 			result.BestFidelity = metrics.BestSetFidelity
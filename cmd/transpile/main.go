@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+
+	bf "nickandperla.net/brainfuck"
+	"nickandperla.net/brainfuck/transpile"
+	"nickandperla.net/genetic_sort"
+
+	"github.com/BurntSushi/toml"
+)
+
+var toolConfigPath = flag.String("config", "./config.toml", "The config file for genetic_sort tools to use")
+var popId = flag.Uint("popid", 1, "The id of the population whose machine config to use and whose champion to transpile")
+var program = flag.String("program", "", "Transpile this program instead of the champion")
+var optimize = flag.Bool("optimize", false, "Transpile the peephole optimized program. Always on if the population evaluates optimized programs")
+var output = flag.String("o", "sort.go", "The Go file to write")
+var pkg = flag.String("package", "main", "The package of the Go file")
+var funcName = flag.String("func", "Sort", "The name of the generated function")
+
+func main() {
+	flag.Parse()
+
+	conffile, err := os.Open(*toolConfigPath)
+	if err != nil {
+		log.Fatalf("Unable to load genetic_sort config: %v", err)
+	}
+
+	confDecoder := toml.NewDecoder(conffile)
+	var toolConfig genetic_sort.ToolConfig
+	if _, err = confDecoder.Decode(&toolConfig); err != nil {
+		log.Fatalf("Failed to unmarshal tool config: %v", err)
+	}
+	conffile.Close()
+
+	persist, err := genetic_sort.NewPersistence(toolConfig.Persistence)
+	if err != nil {
+		log.Fatalf("Failed to create or initialize Persistence: %v", err)
+	}
+	defer persist.Shutdown()
+
+	pop, err := persist.LoadShallow(*popId)
+	if err != nil {
+		log.Fatalf("Unable to load population from DB: %v", err)
+	}
+	ec := pop.PopulationConfig.EvaluatorConfig
+	// The generated function sorts its cells in place, all of them writable
+	if ec.InputOffset != 0 || ec.OutputOffset != 0 || ec.ReadOnlyInput {
+		log.Fatalf("Population %d evaluates with input at [%d], output at [%d] and read only input [%v]. Only sorting in place transpiles", pop.ID, ec.InputOffset, ec.OutputOffset, ec.ReadOnlyInput)
+	}
+
+	source := *program
+	if source == "" {
+		unit, _, err := pop.QueryBestUnit()
+		if err != nil {
+			log.Fatalf("Failed to find the champion: %v", err)
+		}
+		if unit == nil {
+			log.Fatalf("Population %d has no evaluated alive units", pop.ID)
+		}
+		source = genetic_sort.Instructions(unit.Instructions).ToProgram()
+		log.Printf("Transpiling population %d champion: unit %d (generation %d)", pop.ID, unit.ID, unit.Generation)
+	}
+	if *optimize || ec.Optimize {
+		source = bf.Optimize(source, ec.MachineConfig.OverflowMode)
+	}
+
+	src, err := transpile.Go(source, &transpile.Config{
+		Package: *pkg,
+		Func:    *funcName,
+		Machine: ec.MachineConfig,
+	})
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *output, err)
+	}
+	log.Printf("Wrote %s (%d ops)", *output, len(source))
+}
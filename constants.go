@@ -32,6 +32,13 @@ func (pr *pooledRand) Intn(n int) int {
 	return v
 }
 
+func (pr *pooledRand) Int63n(n int64) int64 {
+	r := pr.pool.Get().(*rand.Rand)
+	v := r.Int63n(n)
+	pr.pool.Put(r)
+	return v
+}
+
 func (pr *pooledRand) Float32() float32 {
 	r := pr.pool.Get().(*rand.Rand)
 	v := r.Float32()
@@ -39,6 +46,13 @@ func (pr *pooledRand) Float32() float32 {
 	return v
 }
 
+func (pr *pooledRand) NormFloat64() float64 {
+	r := pr.pool.Get().(*rand.Rand)
+	v := r.NormFloat64()
+	pr.pool.Put(r)
+	return v
+}
+
 // rng is the package-level random source. Uses sync.Pool internally
 // so concurrent goroutines each get their own *rand.Rand — no contention.
 var rng *pooledRand = newPooledRand(time.Now().UnixNano())
@@ -74,3 +88,16 @@ const (
 	REPLACE_OP
 	META_NO_OP
 )
+
+// FailedStaticAnalysis follows FailedCompetition. It's kept out of the iota
+// block above so the persisted meta op values don't shift
+const (
+	FailedStaticAnalysis SelectFailReason = 8
+)
+
+// Later meta ops, numbered on from META_NO_OP
+const (
+	WRAP_OP          = META_NO_OP + 1 // wrap a balanced span of a gene in a new loop
+	UNWRAP_OP        = META_NO_OP + 2 // drop the brackets of a loop in a gene
+	INSERT_MODULE_OP = META_NO_OP + 3 // insert a module mined from the elite into a gene
+)
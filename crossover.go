@@ -0,0 +1,129 @@
+package genetic_sort
+
+import (
+	"fmt"
+)
+
+// CrossoverMode picks how a Crossover child takes its genes from its two
+// parents. Genes line up by index.
+type CrossoverMode uint8
+
+const (
+	CROSSOVER_SINGLE_POINT CrossoverMode = iota // the first parent's genes up to a cut, the mate's after it
+	CROSSOVER_TWO_POINT                         // the mate's genes between two cuts, the first parent's elsewhere
+	CROSSOVER_UNIFORM                           // each gene from either parent with even odds
+)
+
+func (c CrossoverMode) String() string {
+	switch c {
+	case CROSSOVER_SINGLE_POINT:
+		return "single_point"
+	case CROSSOVER_TWO_POINT:
+		return "two_point"
+	case CROSSOVER_UNIFORM:
+		return "uniform"
+	}
+	return fmt.Sprintf("CrossoverMode(%d)", uint8(c))
+}
+
+func (c CrossoverMode) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *CrossoverMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "single_point":
+		*c = CROSSOVER_SINGLE_POINT
+	case "two_point":
+		*c = CROSSOVER_TWO_POINT
+	case "uniform":
+		*c = CROSSOVER_UNIFORM
+	default:
+		return fmt.Errorf("Unknown crossover mode [%s]. Expected one of single_point, two_point, uniform", text)
+	}
+	return nil
+}
+
+// Sexual reproduction. The child takes copies of genes from u and mate as
+// mode says, then grows like a Mitosis child: new IDs, and every gene ages
+// and may mutate at the mean of its parents' MutationChance. A single point
+// child ends with the mate's genes and so has the mate's length; other
+// children have u's.
+func (u *Unit) Crossover(mate *Unit, mode CrossoverMode, mutator *Mutator, unitIDs, insIDs *IDGenerator) *Unit {
+	genes := crossGenes(u.Instructions, mate.Instructions, mode)
+	child := &Unit{
+		PopulationID:   u.PopulationID,
+		ParentID:       &u.ID,
+		SecondParentID: &mate.ID,
+		Instructions:   make([]*Instruction, len(genes)),
+		Generation:     u.Generation + 1,
+		Lifespan:       u.Lifespan,
+		MutationChance: (u.MutationChance + mate.MutationChance) / 2,
+	}
+	if mate.Generation > u.Generation {
+		child.Generation = mate.Generation + 1
+	}
+	for i, gene := range genes {
+		child.Instructions[i] = gene.Clone()
+	}
+	return child.grow(mutator, unitIDs, insIDs)
+}
+
+// crossGenes picks the genes of a child of a and b. The genes are shared
+// with the parents, not copied.
+func crossGenes(a, b []*Instruction, mode CrossoverMode) []*Instruction {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+
+	switch mode {
+	case CROSSOVER_SINGLE_POINT:
+		cut := rng.Intn(n + 1)
+		genes := make([]*Instruction, 0, len(b))
+		genes = append(genes, a[:cut]...)
+		return append(genes, b[cut:]...)
+	case CROSSOVER_TWO_POINT:
+		from, to := rng.Intn(n+1), rng.Intn(n+1)
+		if from > to {
+			from, to = to, from
+		}
+		genes := make([]*Instruction, 0, len(a))
+		genes = append(genes, a[:from]...)
+		genes = append(genes, b[from:to]...)
+		return append(genes, a[to:]...)
+	case CROSSOVER_UNIFORM:
+		genes := make([]*Instruction, len(a))
+		copy(genes, a)
+		for i := 0; i < n; i++ {
+			if rng.Intn(2) == 1 {
+				genes[i] = b[i]
+			}
+		}
+		return genes
+	}
+	panic(fmt.Sprintf("Unknown crossover mode [%v]", mode))
+}
+
+// mates hands out crossover mates to parents ranked best-first, by binary
+// tournament: the better of two parents drawn at random.
+type mates []*Unit
+
+func (m mates) pick() *Unit {
+	i, j := rng.Intn(len(m)), rng.Intn(len(m))
+	if j < i {
+		i = j
+	}
+	return m[i]
+}
+
+// breed produces one offspring of u: with chance rate a Crossover with a
+// mate picked from pool, otherwise, or when u draws itself, by Mitosis.
+func breed(u *Unit, pool mates, rate float32, mode CrossoverMode, mutator *Mutator, unitIDs, insIDs *IDGenerator) *Unit {
+	if rate > 0 && len(pool) > 1 && rng.Float32() < rate {
+		if mate := pool.pick(); mate != u {
+			return u.Crossover(mate, mode, mutator, unitIDs, insIDs)
+		}
+	}
+	return u.Mitosis(mutator, unitIDs, insIDs)
+}
@@ -0,0 +1,380 @@
+package genetic_sort
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mutation efficacy: when a Reproducer persists a Mitosis child it also
+// persists a mutation_effects row per Mutation and GeneMutation the child
+// took, or a single row without a meta op for a child that took none, along
+// with the latest evaluation of its parent. Once the child has its first
+// evaluation, RecordMutationEffects ranks it against its parent and stores
+// the outcome and fitness deltas on those rows. Children the Selector's
+// prescreen kills never get an evaluation, their mutations are deleterious
+// without fitness deltas. The rows outlive the mutations and evaluations PruneDeadUnitData deletes, and
+// the units Prune deletes, so MutationEfficacy can report on a whole run.
+// Crossover children are left out, their parents changed them as much as
+// their mutations did.
+
+// Outcomes of the mutations of a child, ranked against its parent.
+const (
+	EFFECT_DELETERIOUS = -1
+	EFFECT_NEUTRAL     = 0
+	EFFECT_BENEFICIAL  = 1
+)
+
+// effectRow is one mutation of a child, or none, with the parent's fitness.
+type effectRow struct {
+	popID            uint
+	unitID, parentID uint
+	gene             int  // 1 for a GeneMutation
+	metaOp, op       *int // nil meta op for a child without mutations
+	parent           *Evaluation
+}
+
+// mutationEffectRows lists the rows of u, a child of parent.
+func mutationEffectRows(u *Unit, parent *Evaluation) []effectRow {
+	var rows []effectRow
+	for _, ins := range u.Instructions {
+		for _, m := range ins.Mutations {
+			metaOp, op := int(m.MetaOP), int(m.Op)
+			rows = append(rows, effectRow{popID: u.PopulationID, unitID: u.ID, parentID: *u.ParentID, metaOp: &metaOp, op: &op, parent: parent})
+		}
+	}
+	for _, m := range u.GeneMutations {
+		metaOp := int(m.MetaOP)
+		rows = append(rows, effectRow{popID: u.PopulationID, unitID: u.ID, parentID: *u.ParentID, gene: 1, metaOp: &metaOp, parent: parent})
+	}
+	if len(rows) == 0 {
+		rows = append(rows, effectRow{popID: u.PopulationID, unitID: u.ID, parentID: *u.ParentID, parent: parent})
+	}
+	return rows
+}
+
+// bulkInsertMutationEffects inserts the pending mutation effects of the
+// Mitosis children among units. parents holds the latest evaluation of
+// each parent; children of parents without one are left out.
+func bulkInsertMutationEffects(tx *sql.Tx, units []*Unit, parents map[uint]*Evaluation) error {
+	var all []effectRow
+	for _, u := range units {
+		if u.ParentID == nil || u.SecondParentID != nil {
+			continue
+		}
+		if parent := parents[*u.ParentID]; parent != nil {
+			all = append(all, mutationEffectRows(u, parent)...)
+		}
+	}
+
+	const effectCols = 10        // population_id, unit_id, parent_id, gene, meta_op, op, parent_machine_run, parent_sortedness, parent_set_fidelity, parent_instructions_executed
+	const maxEffectsPerStmt = 49 // 49 * 10 = 490 variables
+
+	for start := 0; start < len(all); start += maxEffectsPerStmt {
+		end := start + maxEffectsPerStmt
+		if end > len(all) {
+			end = len(all)
+		}
+		chunk := all[start:end]
+
+		var sb strings.Builder
+		sb.WriteString(`INSERT INTO mutation_effects (population_id, unit_id, parent_id, gene, meta_op, op,
+			parent_machine_run, parent_sortedness, parent_set_fidelity, parent_instructions_executed) VALUES `)
+		args := make([]interface{}, 0, len(chunk)*effectCols)
+		for i, r := range chunk {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString("(?,?,?,?,?,?,?,?,?,?)")
+			machineRun := 0
+			if r.parent.MachineRun {
+				machineRun = 1
+			}
+			var metaOp, op interface{}
+			if r.metaOp != nil {
+				metaOp = *r.metaOp
+			}
+			if r.op != nil {
+				op = *r.op
+			}
+			args = append(args, r.popID, r.unitID, r.parentID, r.gene, metaOp, op,
+				machineRun, r.parent.Sortedness, r.parent.SetFidelity, r.parent.InstructionsExecuted)
+		}
+		if _, err := tx.Exec(sb.String(), args...); err != nil {
+			return fmt.Errorf("bulk insert mutation effects failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// RecordMutationEffects ranks every child with pending mutation effects and
+// an evaluation, by its first evaluation, against its parent and records
+// the outcome. Children the prescreen killed are deleterious. Returns how
+// many children it ranked.
+func (p *Population) RecordMutationEffects(ranker *FitnessRanker) (uint, error) {
+	counts := make([]uint, p.persist.NumShards)
+	errs := make([]error, p.persist.NumShards)
+	var wg sync.WaitGroup
+
+	for i := uint(0); i < p.persist.NumShards; i++ {
+		wg.Add(1)
+		go func(shard uint) {
+			defer wg.Done()
+			n, err := recordShardMutationEffects(p.persist.Shards[shard], p.ID, ranker)
+			if err != nil {
+				errs[shard] = fmt.Errorf("shard %d: %w", shard, err)
+				return
+			}
+			counts[shard] = n
+		}(i)
+	}
+	wg.Wait()
+
+	if err := firstError(errs); err != nil {
+		return 0, err
+	}
+	var total uint
+	for _, n := range counts {
+		total += n
+	}
+	return total, nil
+}
+
+func recordShardMutationEffects(db *sql.DB, popID uint, ranker *FitnessRanker) (uint, error) {
+	type pending struct {
+		unitID        uint
+		parent, child Evaluation
+	}
+
+	rows, err := db.Query(`SELECT DISTINCT m.unit_id,
+		m.parent_machine_run, m.parent_sortedness, m.parent_set_fidelity, m.parent_instructions_executed,
+		e.machine_run, e.sortedness, e.set_fidelity, e.instructions_executed
+		FROM mutation_effects m
+		JOIN evaluations e ON e.id = (SELECT MIN(id) FROM evaluations WHERE unit_id = m.unit_id)
+		WHERE m.population_id = ? AND m.outcome IS NULL`, popID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query pending mutation effects: %w", err)
+	}
+	var children []pending
+	for rows.Next() {
+		var c pending
+		var parentRun, childRun int
+		if err := rows.Scan(&c.unitID,
+			&parentRun, &c.parent.Sortedness, &c.parent.SetFidelity, &c.parent.InstructionsExecuted,
+			&childRun, &c.child.Sortedness, &c.child.SetFidelity, &c.child.InstructionsExecuted); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		c.parent.MachineRun = parentRun != 0
+		c.child.MachineRun = childRun != 0
+		children = append(children, c)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	// The prescreen kills without evaluating, so only the tombstone tells
+	rows, err = db.Query(`SELECT DISTINCT m.unit_id
+		FROM mutation_effects m
+		JOIN tombstones t ON t.unit_id = m.unit_id
+		WHERE m.population_id = ? AND m.outcome IS NULL AND t.reason = ?
+		AND NOT EXISTS (SELECT 1 FROM evaluations e WHERE e.unit_id = m.unit_id)`, popID, FailedStaticAnalysis)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query prescreened mutation effects: %w", err)
+	}
+	var prescreened []uint
+	for rows.Next() {
+		var unitID uint
+		if err := rows.Scan(&unitID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		prescreened = append(prescreened, unitID)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	if len(children) == 0 && len(prescreened) == 0 {
+		return 0, nil
+	}
+
+	err = withTx(db, func(tx *sql.Tx) error {
+		for _, c := range children {
+			// CompareEvaluations is -1 when its first argument is better
+			outcome := ranker.CompareEvaluations(&c.parent, &c.child)
+			if _, err := tx.Exec(`UPDATE mutation_effects SET outcome = ?,
+				sortedness_delta = ?, set_fidelity_delta = ?, instructions_executed_delta = ?
+				WHERE unit_id = ? AND outcome IS NULL`,
+				outcome,
+				int(c.child.Sortedness)-int(c.parent.Sortedness),
+				int(c.child.SetFidelity)-int(c.parent.SetFidelity),
+				int64(c.child.InstructionsExecuted)-int64(c.parent.InstructionsExecuted),
+				c.unitID); err != nil {
+				return fmt.Errorf("failed to record mutation effect: %w", err)
+			}
+		}
+		for _, unitID := range prescreened {
+			if _, err := tx.Exec(`UPDATE mutation_effects SET outcome = ? WHERE unit_id = ? AND outcome IS NULL`,
+				EFFECT_DELETERIOUS, unitID); err != nil {
+				return fmt.Errorf("failed to record mutation effect: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uint(len(children) + len(prescreened)), nil
+}
+
+// Efficacy tallies the outcomes of one kind of mutation.
+type Efficacy struct {
+	Name        string
+	Beneficial  uint
+	Neutral     uint
+	Deleterious uint
+	// Sum of the changes in sortedness, to average over Total
+	SortednessDelta int64
+}
+
+func (e *Efficacy) Total() uint {
+	return e.Beneficial + e.Neutral + e.Deleterious
+}
+
+// Rates returns the fractions of beneficial, neutral and deleterious outcomes.
+func (e *Efficacy) Rates() (beneficial, neutral, deleterious float64) {
+	total := float64(e.Total())
+	if total == 0 {
+		return 0, 0, 0
+	}
+	return float64(e.Beneficial) / total, float64(e.Neutral) / total, float64(e.Deleterious) / total
+}
+
+func (e *Efficacy) add(outcome int, count uint, sortednessDelta int64) {
+	switch {
+	case outcome > 0:
+		e.Beneficial += count
+	case outcome < 0:
+		e.Deleterious += count
+	default:
+		e.Neutral += count
+	}
+	e.SortednessDelta += sortednessDelta
+}
+
+// MutationEfficacyReport tallies recorded mutation effects by meta op, by
+// the op a meta op added, and by gene meta op. Unmutated holds children
+// that took no mutation, the baseline the others compare to. Every tally is
+// sorted by name.
+type MutationEfficacyReport struct {
+	Unmutated   Efficacy
+	MetaOps     []*Efficacy
+	Ops         []*Efficacy
+	GeneMetaOps []*Efficacy
+}
+
+// MutationEfficacy reports on every recorded mutation effect of the
+// population.
+func (p *Population) MutationEfficacy() (*MutationEfficacyReport, error) {
+	type tally struct {
+		gene               int
+		metaOp, op         sql.NullInt64
+		outcome            int
+		count              uint
+		sumSortednessDelta int64
+	}
+	results := make([][]tally, p.persist.NumShards)
+	errs := make([]error, p.persist.NumShards)
+	var wg sync.WaitGroup
+
+	for i := uint(0); i < p.persist.NumShards; i++ {
+		wg.Add(1)
+		go func(shard uint) {
+			defer wg.Done()
+			rows, err := p.persist.Shards[shard].Query(`SELECT m.gene, m.meta_op, m.op, m.outcome,
+				COUNT(*), COALESCE(SUM(m.sortedness_delta), 0)
+				FROM mutation_effects m
+				WHERE m.population_id = ? AND m.outcome IS NOT NULL
+				GROUP BY m.gene, m.meta_op, m.op, m.outcome`, p.ID)
+			if err != nil {
+				errs[shard] = fmt.Errorf("shard %d: %w", shard, err)
+				return
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var t tally
+				if err := rows.Scan(&t.gene, &t.metaOp, &t.op, &t.outcome, &t.count, &t.sumSortednessDelta); err != nil {
+					errs[shard] = fmt.Errorf("shard %d: %w", shard, err)
+					return
+				}
+				results[shard] = append(results[shard], t)
+			}
+			errs[shard] = rows.Err()
+		}(i)
+	}
+	wg.Wait()
+
+	if err := firstError(errs); err != nil {
+		return nil, err
+	}
+
+	report := &MutationEfficacyReport{Unmutated: Efficacy{Name: "none"}}
+	metaOps := map[string]*Efficacy{}
+	ops := map[string]*Efficacy{}
+	geneMetaOps := map[string]*Efficacy{}
+	tallyOf := func(m map[string]*Efficacy, name string) *Efficacy {
+		if m[name] == nil {
+			m[name] = &Efficacy{Name: name}
+		}
+		return m[name]
+	}
+
+	for _, shard := range results {
+		for _, t := range shard {
+			switch {
+			case !t.metaOp.Valid:
+				report.Unmutated.add(t.outcome, t.count, t.sumSortednessDelta)
+			case t.gene != 0:
+				tallyOf(geneMetaOps, GeneMetaOpName(byte(t.metaOp.Int64))).add(t.outcome, t.count, t.sumSortednessDelta)
+			default:
+				metaOp := byte(t.metaOp.Int64)
+				tallyOf(metaOps, MetaOpName(metaOp)).add(t.outcome, t.count, t.sumSortednessDelta)
+				if addsOp(metaOp) && t.op.Valid {
+					tallyOf(ops, string(rune(t.op.Int64))).add(t.outcome, t.count, t.sumSortednessDelta)
+				}
+			}
+		}
+	}
+
+	report.MetaOps = sortedEfficacies(metaOps)
+	report.Ops = sortedEfficacies(ops)
+	report.GeneMetaOps = sortedEfficacies(geneMetaOps)
+	return report, nil
+}
+
+// addsOp reports whether a meta op puts its Mutation's Op into the gene.
+func addsOp(metaOp byte) bool {
+	switch metaOp {
+	case PUSH_OP, UNSHIFT_OP, INSERT_OP, REPLACE_OP:
+		return true
+	}
+	return false
+}
+
+func sortedEfficacies(m map[string]*Efficacy) []*Efficacy {
+	efficacies := make([]*Efficacy, 0, len(m))
+	for _, e := range m {
+		efficacies = append(efficacies, e)
+	}
+	sort.Slice(efficacies, func(i, j int) bool {
+		return efficacies[i].Name < efficacies[j].Name
+	})
+	return efficacies
+}
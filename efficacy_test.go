@@ -0,0 +1,210 @@
+package genetic_sort
+
+import (
+	"database/sql"
+	test "testing"
+)
+
+func TestMutationEffects(t *test.T) {
+	db, persist := setupReproducerTestDB(t)
+	defer db.Close()
+	rng = newPooledRand(42)
+
+	// Parents score sortedness 0, 10, 20, 30 and 40
+	pop := insertReproducerTestPopulation(t, db, 5)
+	pop.persist = persist
+	seedUnitsForReproduction(t, db, pop.ID, 5, persist.UnitIDs, persist.InstructionIDs)
+
+	reproducer := NewReproducer(persist, pop.ID, 2, 100, NewFitnessRanker(nil), persist.UnitIDs, persist.InstructionIDs)
+	reproducer.Mutator = NewUnitMutator(&UnitConfig{InstructionConfig: &InstructionConfig{OpSetCount: 2}}, &EvaluatorConfig{})
+	if _, err := reproducer.Reproduce(); err != nil {
+		t.Fatalf("Reproduce returned error: %v", err)
+	}
+
+	// Nothing to rank before the children are evaluated
+	ranker := NewFitnessRanker(nil)
+	if ranked, err := pop.RecordMutationEffects(ranker); err != nil || ranked != 0 {
+		t.Fatalf("RecordMutationEffects ranked [%d] unevaluated children, err [%v]", ranked, err)
+	}
+
+	// Every child scores sortedness 20, a second evaluation must not count
+	rows, err := db.Query(`SELECT id, parent_id FROM units WHERE population_id = ? AND parent_id IS NOT NULL`, pop.ID)
+	if err != nil {
+		t.Fatalf("Failed to query offspring: %v", err)
+	}
+	parents := map[uint]uint{}
+	for rows.Next() {
+		var id, parentID uint
+		if err := rows.Scan(&id, &parentID); err != nil {
+			t.Fatalf("Failed to scan offspring: %v", err)
+		}
+		parents[id] = parentID
+	}
+	rows.Close()
+	for id := range parents {
+		for _, sortedness := range []byte{20, 90} {
+			if _, err := db.Exec(`INSERT INTO evaluations (unit_id, machine_run, sortedness, set_fidelity, instructions_executed, instruction_count)
+				VALUES (?, 1, ?, 50, 100, 0)`, id, sortedness); err != nil {
+				t.Fatalf("Failed to evaluate offspring: %v", err)
+			}
+		}
+	}
+
+	ranked, err := pop.RecordMutationEffects(ranker)
+	if err != nil {
+		t.Fatalf("RecordMutationEffects returned error: %v", err)
+	}
+	if int(ranked) != len(parents) {
+		t.Errorf("Ranked [%d] children, expected [%d]", ranked, len(parents))
+	}
+	if ranked, _ := pop.RecordMutationEffects(ranker); ranked != 0 {
+		t.Errorf("Ranked [%d] children a second time", ranked)
+	}
+
+	// The seeded parents' sortedness is 10 * (their ID - 1)
+	rows, err = db.Query(`SELECT unit_id, parent_id, outcome, sortedness_delta FROM mutation_effects`)
+	if err != nil {
+		t.Fatalf("Failed to query mutation effects: %v", err)
+	}
+	var effects uint
+	for rows.Next() {
+		var unitID, parentID uint
+		var outcome, delta int
+		if err := rows.Scan(&unitID, &parentID, &outcome, &delta); err != nil {
+			t.Fatalf("Failed to scan mutation effect: %v", err)
+		}
+		effects++
+		if parents[unitID] != parentID {
+			t.Errorf("Effect of unit [%d] names parent [%d], expected [%d]", unitID, parentID, parents[unitID])
+		}
+		expectedDelta := 20 - 10*(int(parentID)-1)
+		expectedOutcome := EFFECT_NEUTRAL
+		if expectedDelta > 0 {
+			expectedOutcome = EFFECT_BENEFICIAL
+		} else if expectedDelta < 0 {
+			expectedOutcome = EFFECT_DELETERIOUS
+		}
+		if outcome != expectedOutcome || delta != expectedDelta {
+			t.Errorf("Effect of unit [%d] is [%d] by [%d], expected [%d] by [%d]", unitID, outcome, delta, expectedOutcome, expectedDelta)
+		}
+	}
+	rows.Close()
+	// At least one row per child, without a meta op if it took no mutation
+	if effects < uint(len(parents)) {
+		t.Errorf("Found [%d] mutation effects for [%d] children", effects, len(parents))
+	}
+
+	report, err := pop.MutationEfficacy()
+	if err != nil {
+		t.Fatalf("MutationEfficacy returned error: %v", err)
+	}
+	total := report.Unmutated.Total()
+	for _, e := range report.MetaOps {
+		total += e.Total()
+	}
+	if total != effects {
+		t.Errorf("Report tallies [%d] effects, expected [%d]", total, effects)
+	}
+	for _, e := range report.Ops {
+		if e.Total() == 0 || len(e.Name) != 1 {
+			t.Errorf("Report has op tally [%+v]", e)
+		}
+	}
+	if len(report.GeneMetaOps) != 0 {
+		t.Errorf("Report has gene meta op tallies %v without gene mutations", report.GeneMetaOps)
+	}
+}
+
+func TestPrescreenedMutationEffects(t *test.T) {
+	db, persist := setupReproducerTestDB(t)
+	defer db.Close()
+	rng = newPooledRand(42)
+
+	pop := insertReproducerTestPopulation(t, db, 5)
+	pop.persist = persist
+	seedUnitsForReproduction(t, db, pop.ID, 5, persist.UnitIDs, persist.InstructionIDs)
+
+	reproducer := NewReproducer(persist, pop.ID, 2, 100, NewFitnessRanker(nil), persist.UnitIDs, persist.InstructionIDs)
+	reproducer.Mutator = NewUnitMutator(&UnitConfig{InstructionConfig: &InstructionConfig{OpSetCount: 2}}, &EvaluatorConfig{})
+	if _, err := reproducer.Reproduce(); err != nil {
+		t.Fatalf("Reproduce returned error: %v", err)
+	}
+
+	var children []uint
+	rows, err := db.Query(`SELECT id FROM units WHERE population_id = ? AND parent_id IS NOT NULL ORDER BY id`, pop.ID)
+	if err != nil {
+		t.Fatalf("Failed to query offspring: %v", err)
+	}
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("Failed to scan offspring: %v", err)
+		}
+		children = append(children, id)
+	}
+	rows.Close()
+	if len(children) < 2 {
+		t.Fatalf("Reproduce made [%d] children, expected at least 2", len(children))
+	}
+
+	// The prescreen kills the first child, the second dies unevaluated for
+	// another reason and can't be ranked
+	prescreened, other := children[0], children[1]
+	for unitID, reason := range map[uint]SelectFailReason{prescreened: FailedStaticAnalysis, other: FailedLifespan} {
+		if _, err := db.Exec(`INSERT INTO tombstones (unit_id, reason) VALUES (?, ?)`, unitID, reason); err != nil {
+			t.Fatalf("Failed to bury unit [%d]: %v", unitID, err)
+		}
+	}
+
+	ranked, err := pop.RecordMutationEffects(NewFitnessRanker(nil))
+	if err != nil || ranked != 1 {
+		t.Fatalf("RecordMutationEffects ranked [%d] children, expected [1], err [%v]", ranked, err)
+	}
+	for unitID, expected := range map[uint]sql.NullInt64{
+		prescreened: {Int64: EFFECT_DELETERIOUS, Valid: true},
+		other:       {},
+	} {
+		rows, err := db.Query(`SELECT outcome FROM mutation_effects WHERE unit_id = ?`, unitID)
+		if err != nil {
+			t.Fatalf("Failed to query mutation effects: %v", err)
+		}
+		for rows.Next() {
+			var outcome sql.NullInt64
+			if err := rows.Scan(&outcome); err != nil {
+				t.Fatalf("Failed to scan mutation effect: %v", err)
+			}
+			if outcome != expected {
+				t.Errorf("Effect of unit [%d] is %+v, expected %+v", unitID, outcome, expected)
+			}
+		}
+		rows.Close()
+	}
+
+	report, err := pop.MutationEfficacy()
+	if err != nil {
+		t.Fatalf("MutationEfficacy returned error: %v", err)
+	}
+	deleterious := report.Unmutated.Deleterious
+	for _, e := range report.MetaOps {
+		deleterious += e.Deleterious
+	}
+	if deleterious == 0 {
+		t.Errorf("Report counts no deleterious effects for the prescreened child")
+	}
+}
+
+func TestEfficacyRates(t *test.T) {
+	e := &Efficacy{}
+	if b, n, d := e.Rates(); b != 0 || n != 0 || d != 0 {
+		t.Errorf("Empty efficacy rates are [%v %v %v]", b, n, d)
+	}
+	e.add(EFFECT_BENEFICIAL, 1, 5)
+	e.add(EFFECT_NEUTRAL, 2, 0)
+	e.add(EFFECT_DELETERIOUS, 1, -3)
+	if b, n, d := e.Rates(); b != 0.25 || n != 0.5 || d != 0.25 {
+		t.Errorf("Efficacy rates are [%v %v %v], expected [0.25 0.5 0.25]", b, n, d)
+	}
+	if e.SortednessDelta != 2 {
+		t.Errorf("Efficacy sortedness delta is [%d], expected [2]", e.SortednessDelta)
+	}
+}
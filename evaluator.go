@@ -1,6 +1,7 @@
 package genetic_sort
 
 import (
+	"fmt"
 	"log"
 	"math"
 
@@ -25,6 +26,20 @@ type Evaluation struct {
 	InstructionCount     uint
 	InstructionsExecuted uint
 	MachineError         *string
+	MachineErrorKind     bf.ErrorKind // ERR_NONE unless the machine raised an exception
+	MachineErrorLoop     *uint        // Tape index of the loop running when a limit stopped the machine
+	Profile              *bf.Profile  // Only set while the Evaluator is profiling, never persisted
+}
+
+// setMachineError records the exception that halted the machine.
+func (e *Evaluation) setMachineError(err error) {
+	msg := err.Error()
+	e.MachineError = &msg
+	e.MachineErrorKind = bf.ErrorKindOf(err)
+	if loop := bf.LoopOf(err); loop != bf.NO_MATCH {
+		index := uint(loop)
+		e.MachineErrorLoop = &index
+	}
 }
 
 type EvaluatorConfig struct {
@@ -35,6 +50,64 @@ type EvaluatorConfig struct {
 	InputCellStart          uint              `toml:"input_cell_start"`
 	InputCellStep           uint              `toml:"input_cell_step"`
 	EvalRounds              uint              `toml:"eval_rounds"`
+
+	// Run the peephole optimized program instead of the raw one. The Unit's
+	// genes and InstructionCount are untouched, but InstructionsExecuted
+	// counts the optimized program. Not allowed with ReadOnlyInput, since
+	// optimizing drops writes like the +- pairs that would violate it.
+	Optimize bool `toml:"optimize"`
+
+	// How programs run. BACKEND_CLOSURE compiles each program into closures
+	// before running it, which pays off over many rounds. Profiling always
+	// runs on the interpreter.
+	Backend bf.Backend `toml:"backend"`
+
+	// Memory layout. Input loads at InputOffset and output is read from
+	// OutputOffset; both 0 means sorting in place. The scratch region is the
+	// working space a layout sets aside and must not overlap either of them.
+	// It is only a reservation: CheckLayout keeps input and output out of it,
+	// but programs may use any cell. With ReadOnlyInput any write to an input cell halts the Machine with
+	// bf.ERR_READ_ONLY_VIOLATION, and output may not overlap input.
+	InputOffset      uint `toml:"input_offset"`
+	OutputOffset     uint `toml:"output_offset"`
+	ScratchOffset    uint `toml:"scratch_offset"`
+	ScratchCellCount uint `toml:"scratch_cell_count"`
+	ReadOnlyInput    bool `toml:"read_only_input"`
+}
+
+// CheckLayout returns an error if the input, output and scratch regions
+// don't fit in memory or overlap where they may not, or if read only input
+// can't be enforced.
+func (ec *EvaluatorConfig) CheckLayout() error {
+	cellCount := ec.MachineConfig.MemoryCellCount
+	input := [2]uint{ec.InputOffset, ec.InputOffset + ec.InputCellCount}
+	output := [2]uint{ec.OutputOffset, ec.OutputOffset + ec.OutputCellCount}
+	scratch := [2]uint{ec.ScratchOffset, ec.ScratchOffset + ec.ScratchCellCount}
+
+	if input[1] > cellCount {
+		return fmt.Errorf("Input cells [%d, %d) don't fit in [%d] memory cells", input[0], input[1], cellCount)
+	}
+	if output[1] > cellCount {
+		return fmt.Errorf("Output cells [%d, %d) don't fit in [%d] memory cells", output[0], output[1], cellCount)
+	}
+	if scratch[1] > cellCount {
+		return fmt.Errorf("Scratch cells [%d, %d) don't fit in [%d] memory cells", scratch[0], scratch[1], cellCount)
+	}
+	if overlaps(scratch, input) || overlaps(scratch, output) {
+		return fmt.Errorf("Scratch cells [%d, %d) overlap the input [%d, %d) or output [%d, %d) cells", scratch[0], scratch[1], input[0], input[1], output[0], output[1])
+	}
+	if ec.ReadOnlyInput && overlaps(input, output) {
+		return fmt.Errorf("Output cells [%d, %d) overlap the read only input cells [%d, %d)", output[0], output[1], input[0], input[1])
+	}
+	if ec.ReadOnlyInput && ec.Optimize {
+		return fmt.Errorf("Read only input can't be optimized, optimizing drops writes to input cells")
+	}
+	return nil
+}
+
+// overlaps reports whether the half open cell ranges a and b share a cell.
+func overlaps(a, b [2]uint) bool {
+	return a[0] < a[1] && b[0] < b[1] && a[0] < b[1] && b[0] < a[1]
 }
 
 // ComputeEffectiveInputCellCount returns the input cell count to use for a
@@ -53,48 +126,122 @@ func (ec *EvaluatorConfig) ComputeEffectiveInputCellCount(generation uint) uint
 }
 
 type Evaluator struct {
-	Machine *bf.Machine
-	Config  *EvaluatorConfig
+	Machine  *bf.Machine
+	Config   *EvaluatorConfig
+	batch    *bf.Batch          // Runs multi-round evaluations on Machine
+	inputs   [][]uint32         // Round inputs, reused across evaluations
+	closures *bf.ClosureProgram // The last program single-round evaluations closure compiled
 }
 
 func NewEvaluator(ec *EvaluatorConfig) *Evaluator {
+	if err := ec.CheckLayout(); err != nil {
+		log.Fatalf("Bad evaluator memory layout. %v", err)
+	}
+	if err := ec.MachineConfig.Check(); err != nil {
+		log.Fatalf("Bad evaluator machine. %v", err)
+	}
+	machine := bf.NewMachine(ec.MachineConfig)
 	return &Evaluator{
-		Machine: bf.NewMachine(ec.MachineConfig),
+		Machine: machine,
 		Config:  ec,
+		batch:   bf.NewBatch(machine),
 	}
 }
 
+// EnableProfiling makes every following evaluation carry a bf.Profile of its
+// Machine run. Profiling slows the Machine down, so it's meant for tools
+// looking at a few Units, not for generations.
+func (e *Evaluator) EnableProfiling() {
+	e.Machine.Profile = bf.NewProfile()
+}
+
+func (e *Evaluator) attachProfile(eval *Evaluation) {
+	if e.Machine.Profile != nil {
+		eval.Profile = e.Machine.Profile.Clone()
+	}
+}
+
+// GeneExecutions returns how many ops of each of u's Instruction genes were
+// executed during this evaluation. Genes that never executed are introns.
+// Returns nil without a profile, or when the profiled program isn't u's
+// program as evolved, e.g. because the Evaluator optimized it.
+func (e *Evaluation) GeneExecutions(u *Unit) []uint {
+	if e.Profile == nil || len(e.Profile.Executions) != len(Instructions(u.Instructions).ToProgram()) {
+		return nil
+	}
+
+	genes := make([]uint, len(u.Instructions))
+	offset := 0
+	for g, ins := range u.Instructions {
+		for _, count := range e.Profile.Executions[offset : offset+len(ins.ToProgram())] {
+			genes[g] += count
+		}
+		offset += len(ins.ToProgram())
+	}
+	return genes
+}
+
+// run runs the program loaded on the Machine with the configured backend.
+func (e *Evaluator) run() (bool, error) {
+	if e.Config.Backend != bf.BACKEND_CLOSURE || e.Machine.Profile != nil {
+		return e.Machine.Run()
+	}
+	if e.closures == nil || e.closures.Program != e.Machine.Program {
+		e.closures = bf.CompileClosures(e.Machine.Program)
+	}
+	return e.closures.Run(e.Machine)
+}
+
+// load loads u's program and input into the configured input region.
+func (e *Evaluator) load(u *Unit, input []uint32) {
+	e.Machine.LoadProgram(e.runnable(Instructions(u.Instructions).ToProgram()))
+	if ok, err := e.Machine.LoadMemoryAt(e.Config.InputOffset, input); !ok {
+		log.Fatalf("Failed to load memory into machine. %v", err)
+	}
+	readOnly := uint(0)
+	if e.Config.ReadOnlyInput {
+		readOnly = uint(len(input))
+	}
+	if ok, err := e.Machine.SetReadOnly(e.Config.InputOffset, readOnly); !ok {
+		log.Fatalf("Failed to make input read only. %v", err)
+	}
+}
+
+// runnable returns the program the Machine actually runs for a Unit's program.
+func (e *Evaluator) runnable(program string) string {
+	if e.Config.Optimize {
+		return bf.Optimize(program, e.Config.MachineConfig.OverflowMode)
+	}
+	return program
+}
+
 func (e *Evaluator) Evaluate(u *Unit) *Evaluation {
 	eval := &Evaluation{
 		UnitID: u.ID,
 	}
 
-	input := makeRandomInput(e.Config.InputCellCount)
-	e.Machine.LoadProgram(Instructions(u.Instructions).ToProgram())
-	if ok, err := e.Machine.LoadMemory(input); !ok {
-		log.Fatalf("Failed to load memory into machine. %v", err)
-	}
+	input := makeRandomInput(e.Config.InputCellCount, e.Machine.Memory.MaxValue)
+	e.load(u, input)
 
-	if ok, err := e.Machine.Run(); !ok {
+	if ok, err := e.run(); !ok {
 		if err != nil {
-			var msg string = err.Error()
-			eval.MachineError = &msg
+			eval.setMachineError(err)
 		}
 	} else {
 		eval.MachineRun = true
 	}
 
-	ok, output, err := e.Machine.ReadMemory(e.Config.OutputCellCount)
+	ok, output, err := e.Machine.ReadMemoryAt(e.Config.OutputOffset, e.Config.OutputCellCount)
 
 	if !ok {
-		log.Fatalf("Failed to read memory. Check MachineConfig.MemoryConfig.CellCount and EvaluatorConfig.OutputCellCount. %v", err)
+		log.Fatalf("Failed to read memory. Check MachineConfig.MemoryConfig.CellCount and EvaluatorConfig.OutputOffset and OutputCellCount. %v", err)
 	}
 
-	copyOutput := make([]uint8, len(output))
+	copyOutput := make([]uint32, len(output))
 	copy(copyOutput, output)
 
-	inMap := make(map[uint8]bool)
-	outMap := make(map[uint8]bool)
+	inMap := make(map[uint32]bool)
+	outMap := make(map[uint32]bool)
 
 	for g := 0; g < len(input); g++ {
 		inMap[input[g]] = true
@@ -119,6 +266,7 @@ func (e *Evaluator) Evaluate(u *Unit) *Evaluation {
 
 	eval.Sortedness = byte(-(int((float32(inversions)/float32(maxInversions))*100) - 100))
 	eval.InstructionsExecuted = e.Machine.InstructionCount
+	e.attachProfile(eval)
 	eval.InstructionCount = uint(len(Instructions(u.Instructions).ToProgram()))
 
 	u.Evaluations = append(u.Evaluations, eval)
@@ -141,32 +289,28 @@ func (e *Evaluator) EvaluateWithCellCounts(u *Unit, inputCells, outputCells uint
 		UnitID: u.ID,
 	}
 
-	input := makeRandomInput(inputCells)
-	e.Machine.LoadProgram(Instructions(u.Instructions).ToProgram())
-	if ok, err := e.Machine.LoadMemory(input); !ok {
-		log.Fatalf("Failed to load memory into machine. %v", err)
-	}
+	input := makeRandomInput(inputCells, e.Machine.Memory.MaxValue)
+	e.load(u, input)
 
-	if ok, err := e.Machine.Run(); !ok {
+	if ok, err := e.run(); !ok {
 		if err != nil {
-			var msg string = err.Error()
-			eval.MachineError = &msg
+			eval.setMachineError(err)
 		}
 	} else {
 		eval.MachineRun = true
 	}
 
-	ok, output, err := e.Machine.ReadMemory(outputCells)
+	ok, output, err := e.Machine.ReadMemoryAt(e.Config.OutputOffset, outputCells)
 
 	if !ok {
-		log.Fatalf("Failed to read memory. Check MachineConfig.MemoryConfig.CellCount and EvaluatorConfig.OutputCellCount. %v", err)
+		log.Fatalf("Failed to read memory. Check MachineConfig.MemoryConfig.CellCount and EvaluatorConfig.OutputOffset and OutputCellCount. %v", err)
 	}
 
-	copyOutput := make([]uint8, len(output))
+	copyOutput := make([]uint32, len(output))
 	copy(copyOutput, output)
 
-	inMap := make(map[uint8]bool)
-	outMap := make(map[uint8]bool)
+	inMap := make(map[uint32]bool)
+	outMap := make(map[uint32]bool)
 
 	for g := 0; g < len(input); g++ {
 		inMap[input[g]] = true
@@ -196,6 +340,7 @@ func (e *Evaluator) EvaluateWithCellCounts(u *Unit, inputCells, outputCells uint
 	eval.SetFidelity = byte(uint(rawFidelity * scale))
 	eval.Sortedness = byte(uint(rawSortedness * scale))
 	eval.InstructionsExecuted = e.Machine.InstructionCount
+	e.attachProfile(eval)
 	eval.InstructionCount = uint(len(Instructions(u.Instructions).ToProgram()))
 
 	u.Evaluations = append(u.Evaluations, eval)
@@ -222,34 +367,34 @@ func (e *Evaluator) evaluateMultiRound(u *Unit, rounds, inputCells, outputCells
 	program := Instructions(u.Instructions).ToProgram()
 	instrCount := uint(len(program))
 
-	for r := uint(0); r < rounds; r++ {
-		eval := &Evaluation{UnitID: u.ID}
+	compiled := e.Machine.Program
+	if runnable := e.runnable(program); compiled == nil || compiled.Instructions != runnable {
+		compiled = bf.Compile(runnable)
+	}
 
-		input := makeRandomInput(inputCells)
-		e.Machine.LoadProgram(program)
-		if ok, err := e.Machine.LoadMemory(input); !ok {
-			log.Fatalf("Failed to load memory into machine. %v", err)
-		}
+	inputs := e.roundInputs(rounds, inputCells)
+	e.batch.Backend = e.Config.Backend
+	e.batch.InputOffset, e.batch.OutputOffset, e.batch.ReadOnlyInput = e.Config.InputOffset, e.Config.OutputOffset, e.Config.ReadOnlyInput
+	results, err := e.batch.Run(compiled, inputs, outputCells)
+	if err != nil {
+		log.Fatalf("Failed to run rounds on machine. %v", err)
+	}
 
-		if ok, err := e.Machine.Run(); !ok {
-			if err != nil {
-				var msg string = err.Error()
-				eval.MachineError = &msg
-			}
-		} else {
-			eval.MachineRun = true
-		}
+	for r, result := range results {
+		eval := &Evaluation{UnitID: u.ID}
+		input, output := inputs[r], result.Output
 
-		ok, output, err := e.Machine.ReadMemory(outputCells)
-		if !ok {
-			log.Fatalf("Failed to read memory. %v", err)
+		if result.Ok {
+			eval.MachineRun = true
+		} else if result.Err != nil {
+			eval.setMachineError(result.Err)
 		}
 
-		copyOutput := make([]uint8, len(output))
+		copyOutput := make([]uint32, len(output))
 		copy(copyOutput, output)
 
-		inMap := make(map[uint8]bool)
-		outMap := make(map[uint8]bool)
+		inMap := make(map[uint32]bool)
+		outMap := make(map[uint32]bool)
 		for g := 0; g < len(input); g++ {
 			inMap[input[g]] = true
 			outMap[output[g]] = true
@@ -272,7 +417,8 @@ func (e *Evaluator) evaluateMultiRound(u *Unit, rounds, inputCells, outputCells
 		scale := float32(inputCells) / float32(e.Config.InputCellCount)
 		eval.SetFidelity = byte(uint(rawFidelity * scale))
 		eval.Sortedness = byte(uint(rawSortedness * scale))
-		eval.InstructionsExecuted = e.Machine.InstructionCount
+		eval.InstructionsExecuted = result.InstructionCount
+		eval.Profile = result.Profile
 		eval.InstructionCount = instrCount
 
 		fitness := eval.Fitness()
@@ -286,15 +432,44 @@ func (e *Evaluator) evaluateMultiRound(u *Unit, rounds, inputCells, outputCells
 	return worst
 }
 
-func makeRandomInput(count uint) []uint8 {
-	ret := make([]uint8, count)
+// roundInputs fills the Evaluator's reused input buffers with a random input
+// of inputCells cells for each of rounds rounds.
+func (e *Evaluator) roundInputs(rounds, inputCells uint) [][]uint32 {
+	if uint(cap(e.inputs)) < rounds {
+		e.inputs = make([][]uint32, rounds)
+	}
+	e.inputs = e.inputs[:rounds]
+	for r := range e.inputs {
+		if uint(cap(e.inputs[r])) < inputCells {
+			e.inputs[r] = make([]uint32, inputCells)
+		}
+		e.inputs[r] = e.inputs[r][:inputCells]
+		for i := range e.inputs[r] {
+			e.inputs[r][i] = randomCell(e.Machine.Memory.MaxValue)
+		}
+	}
+	return e.inputs
+}
+
+// makeRandomInput returns count random cell values in [0, max).
+func makeRandomInput(count uint, max uint32) []uint32 {
+	ret := make([]uint32, count)
 	for i := uint(0); i < count; i++ {
-		ret[i] = uint8(rng.Intn(int(math.MaxUint8)))
+		ret[i] = randomCell(max)
 	}
 	return ret
 }
 
-func merge(a []uint8, inversion0 uint) uint {
+// randomCell draws a cell value in [0, max). An int can't hold every uint32
+// on 32 bit platforms, so bounds past math.MaxInt32 draw with Int63n.
+func randomCell(max uint32) uint32 {
+	if max <= math.MaxInt32 {
+		return uint32(rng.Intn(int(max)))
+	}
+	return uint32(rng.Int63n(int64(max)))
+}
+
+func merge(a []uint32, inversion0 uint) uint {
 
 	inversion1 := uint(0)
 
@@ -313,7 +488,7 @@ func merge(a []uint8, inversion0 uint) uint {
 	return inversion0 + inversion1
 }
 
-func merge_sort(a []uint8) uint {
+func merge_sort(a []uint32) uint {
 	inversions := uint(0)
 	if len(a) > 1 {
 		mid := len(a) / 2
@@ -2,6 +2,9 @@ package genetic_sort
 
 import (
 	"log"
+	"math"
+	"reflect"
+	"strings"
 	test "testing"
 
 	bf "nickandperla.net/brainfuck"
@@ -19,7 +22,7 @@ func makeEvaluatorAndUnit() (*Evaluator, *Unit) {
 		},
 	)
 
-	unit := NewUnitFromConfig(&UnitConfig{
+	unit := defaultMutator.NewUnit(&UnitConfig{
 		MutationChance:    0.25,
 		InstructionCount:  1,
 		InstructionConfig: &InstructionConfig{OpSetCount: 2},
@@ -74,7 +77,7 @@ func TestEvaluate(t *test.T) {
 		t.Errorf("Unexpected Sortedness: [%v], expected: 32", result.Sortedness)
 	}
 
-	if result.InstructionsExecuted != 157 {
+	if result.InstructionsExecuted != 156 {
 		t.Errorf("Unexpected InstructionsExecuted: [%v], expected: 156", result.InstructionsExecuted)
 	}
 
@@ -126,7 +129,7 @@ func TestComputeEffectiveInputCellCount(t *test.T) {
 }
 
 func TestSortedness(t *test.T) {
-	unsorted := []uint8{5, 4, 3, 2, 1}
+	unsorted := []uint32{5, 4, 3, 2, 1}
 
 	inversions := merge_sort(unsorted)
 
@@ -134,7 +137,7 @@ func TestSortedness(t *test.T) {
 		t.Errorf("merge_sort implementation returns unexpected number of inversions on reversed list. Expected 10, got: %v", inversions)
 	}
 
-	unsorted = []uint8{1, 2, 3, 5, 4}
+	unsorted = []uint32{1, 2, 3, 5, 4}
 
 	inversions = merge_sort(unsorted)
 
@@ -143,3 +146,277 @@ func TestSortedness(t *test.T) {
 	}
 
 }
+
+func TestEvaluateWideCells(t *test.T) {
+	rng = newPooledRand(42)
+	evaluator := NewEvaluator(
+		&EvaluatorConfig{
+			MachineConfig: &bf.MachineConfig{
+				MaxInstructionExecutionCount: 10000,
+				MemoryCellCount:              20,
+				CellWidth:                    bf.CELL_WIDTH_16,
+			},
+			InputCellCount:  5,
+			OutputCellCount: 5,
+		},
+	)
+
+	unit := &Unit{Instructions: []*Instruction{NewInstruction("#")}}
+	result := evaluator.Evaluate(unit)
+
+	// A program that leaves the input alone keeps every value
+	if result.SetFidelity != 100 {
+		t.Errorf("Unexpected SetFidelity: [%v], expected: 100", result.SetFidelity)
+	}
+
+	var wide bool
+	for _, val := range evaluator.Machine.Memory.Cells[:5] {
+		if val > 255 {
+			wide = true
+		}
+	}
+	if !wide {
+		t.Errorf("No input value wider than 8 bits in %v", evaluator.Machine.Memory.Cells[:5])
+	}
+}
+
+func TestEvaluateOptimized(t *test.T) {
+	config := &EvaluatorConfig{
+		MachineConfig: &bf.MachineConfig{
+			MaxInstructionExecutionCount: 10000,
+			MemoryCellCount:              20,
+		},
+		InputCellCount:  5,
+		OutputCellCount: 5,
+	}
+	unit := &Unit{Instructions: []*Instruction{NewInstruction("#><##"), NewInstruction("[-][-]")}}
+
+	// Same seed, same input
+	rng = newPooledRand(42)
+	raw := NewEvaluator(config).Evaluate(unit)
+	config.Optimize = true
+	rng = newPooledRand(42)
+	optimized := NewEvaluator(config).Evaluate(unit)
+
+	if optimized.InstructionCount != raw.InstructionCount {
+		t.Errorf("Optimizing changed InstructionCount from [%d] to [%d]", raw.InstructionCount, optimized.InstructionCount)
+	}
+	if optimized.InstructionsExecuted >= raw.InstructionsExecuted {
+		t.Errorf("Optimized InstructionsExecuted [%d] not lower than raw [%d]", optimized.InstructionsExecuted, raw.InstructionsExecuted)
+	}
+	if optimized.MachineRun != raw.MachineRun {
+		t.Errorf("Optimizing changed MachineRun from [%v] to [%v]", raw.MachineRun, optimized.MachineRun)
+	}
+	if unit.Instructions[0].ToProgram()[0] != bf.NO_OP {
+		t.Errorf("Optimizing changed the Unit's genes")
+	}
+}
+
+// evaluateRounds evaluates u the way a Population with EvalRounds rounds does.
+func evaluateRounds(e *Evaluator, u *Unit, rounds uint) *Evaluation {
+	if rounds > 1 {
+		return e.EvaluateMultiRound(u, rounds)
+	}
+	return e.Evaluate(u)
+}
+
+func TestEvaluateMachineErrorKind(t *test.T) {
+	config := &EvaluatorConfig{
+		MachineConfig: &bf.MachineConfig{
+			MaxInstructionExecutionCount: 100,
+			MemoryCellCount:              5,
+		},
+		InputCellCount:  5,
+		OutputCellCount: 5,
+	}
+	tests := []struct {
+		program string
+		kind    bf.ErrorKind
+	}{
+		{"><", bf.ERR_NONE},
+		{"<", bf.ERR_POINTER_UNDERFLOW},
+		{"+[]", bf.ERR_EXECUTION_LIMIT},
+	}
+
+	for _, rounds := range []uint{1, 3} {
+		for _, tt := range tests {
+			rng = newPooledRand(42)
+			unit := &Unit{Instructions: []*Instruction{NewInstruction(tt.program)}}
+			eval := evaluateRounds(NewEvaluator(config), unit, rounds)
+			if eval.MachineErrorKind != tt.kind {
+				t.Errorf("Program [%s] over [%d] rounds has MachineErrorKind [%v], expected [%v]", tt.program, rounds, eval.MachineErrorKind, tt.kind)
+			}
+			if (eval.MachineError == nil) != (tt.kind == bf.ERR_NONE) {
+				t.Errorf("Program [%s] over [%d] rounds has MachineError [%v] for kind [%v]", tt.program, rounds, eval.MachineError, tt.kind)
+			}
+		}
+	}
+}
+
+func TestEvaluateMemoryRegions(t *test.T) {
+	config := &EvaluatorConfig{
+		MachineConfig: &bf.MachineConfig{
+			MaxInstructionExecutionCount: 10000,
+			MemoryCellCount:              8,
+		},
+		InputCellCount:  3,
+		OutputCellCount: 3,
+		OutputOffset:    4,
+	}
+	// Moves the three input cells into the output region
+	move := "[->>>>+<<<<]>[->>>>+<<<<]>[->>>>+<<<<]"
+	unit := &Unit{Instructions: []*Instruction{NewInstruction(move)}}
+
+	for _, rounds := range []uint{1, 3} {
+		config.ReadOnlyInput = false
+		rng = newPooledRand(42)
+		eval := evaluateRounds(NewEvaluator(config), unit, rounds)
+		if !eval.MachineRun || eval.SetFidelity != 100 {
+			t.Errorf("Moving input to the output region over [%d] rounds has MachineRun [%v] SetFidelity [%d]", rounds, eval.MachineRun, eval.SetFidelity)
+		}
+
+		config.ReadOnlyInput = true
+		rng = newPooledRand(42)
+		eval = evaluateRounds(NewEvaluator(config), unit, rounds)
+		if eval.MachineRun || eval.MachineErrorKind != bf.ERR_READ_ONLY_VIOLATION {
+			t.Errorf("Moving read only input over [%d] rounds has MachineRun [%v] MachineErrorKind [%v]", rounds, eval.MachineRun, eval.MachineErrorKind)
+		}
+	}
+}
+
+func TestCheckLayout(t *test.T) {
+	tests := []struct {
+		config EvaluatorConfig
+		ok     bool
+	}{
+		{EvaluatorConfig{InputCellCount: 4, OutputCellCount: 4}, true},
+		{EvaluatorConfig{InputCellCount: 4, OutputCellCount: 4, OutputOffset: 4, ScratchOffset: 8, ScratchCellCount: 2, ReadOnlyInput: true}, true},
+		{EvaluatorConfig{InputCellCount: 4, OutputCellCount: 4, InputOffset: 7}, false},
+		{EvaluatorConfig{InputCellCount: 4, OutputCellCount: 4, OutputOffset: 7}, false},
+		{EvaluatorConfig{InputCellCount: 4, OutputCellCount: 4, ScratchOffset: 9, ScratchCellCount: 2}, false},
+		{EvaluatorConfig{InputCellCount: 4, OutputCellCount: 4, OutputOffset: 4, ScratchOffset: 6, ScratchCellCount: 2}, false},
+		{EvaluatorConfig{InputCellCount: 4, OutputCellCount: 4, ReadOnlyInput: true}, false},
+		{EvaluatorConfig{InputCellCount: 4, OutputCellCount: 4, OutputOffset: 3, ReadOnlyInput: true}, false},
+		// Optimizing would fold away writes to input cells, like >+-<
+		{EvaluatorConfig{InputCellCount: 4, OutputCellCount: 4, OutputOffset: 4, ReadOnlyInput: true, Optimize: true}, false},
+	}
+
+	for _, tt := range tests {
+		config := tt.config
+		config.MachineConfig = &bf.MachineConfig{MemoryCellCount: 10}
+		if err := config.CheckLayout(); (err == nil) != tt.ok {
+			t.Errorf("CheckLayout of %+v returned [%v]", tt.config, err)
+		}
+	}
+}
+
+func TestEvaluateLoopLimit(t *test.T) {
+	config := &EvaluatorConfig{
+		MachineConfig: &bf.MachineConfig{
+			MaxInstructionExecutionCount: 3000,
+			MemoryCellCount:              5,
+			MaxLoopIterations:            300,
+		},
+		InputCellCount:  5,
+		OutputCellCount: 5,
+	}
+	tests := []struct {
+		program string
+		kind    bf.ErrorKind
+		loop    int
+	}{
+		// Slow but finite, up to 255 iterations per loop
+		{"[-]>[-]>[-]", bf.ERR_NONE, bf.NO_MATCH},
+		{"[-]+[]", bf.ERR_LOOP_LIMIT, 4},
+		{"[-]+[>" + strings.Repeat("+-", 9) + "<]", bf.ERR_EXECUTION_LIMIT, 4},
+		{"[-]++++++++[-]" + strings.Repeat("+-", 1500), bf.ERR_EXECUTION_LIMIT, bf.NO_MATCH},
+	}
+
+	for _, rounds := range []uint{1, 3} {
+		for _, tt := range tests {
+			rng = newPooledRand(42)
+			unit := &Unit{Instructions: []*Instruction{NewInstruction(tt.program)}}
+			eval := evaluateRounds(NewEvaluator(config), unit, rounds)
+			if eval.MachineErrorKind != tt.kind {
+				t.Errorf("Program [%s] over [%d] rounds has MachineErrorKind [%v], expected [%v]", tt.program, rounds, eval.MachineErrorKind, tt.kind)
+			}
+			if loop := eval.MachineErrorLoop; (loop == nil) != (tt.loop == bf.NO_MATCH) || (loop != nil && int(*loop) != tt.loop) {
+				t.Errorf("Program [%s] over [%d] rounds has MachineErrorLoop [%v], expected [%d]", tt.program, rounds, loop, tt.loop)
+			}
+		}
+	}
+}
+
+func TestEvaluateClosureBackend(t *test.T) {
+	config := &EvaluatorConfig{
+		MachineConfig: &bf.MachineConfig{
+			MaxInstructionExecutionCount: 500,
+			MemoryCellCount:              10,
+		},
+		InputCellCount:  5,
+		OutputCellCount: 5,
+	}
+	units := []*Unit{
+		{Instructions: []*Instruction{NewInstruction(bf.SWAP_RIGHT), NewInstruction(">" + bf.SWAP_RIGHT)}},
+		{Instructions: []*Instruction{NewInstruction("+[>+]")}},
+		{Instructions: []*Instruction{NewInstruction("-")}},
+	}
+
+	for _, rounds := range []uint{1, 4} {
+		for _, u := range units {
+			config.Backend = bf.BACKEND_INTERPRETER
+			rng = newPooledRand(42)
+			interpreted := evaluateRounds(NewEvaluator(config), u, rounds)
+			config.Backend = bf.BACKEND_CLOSURE
+			rng = newPooledRand(42)
+			closures := evaluateRounds(NewEvaluator(config), u, rounds)
+
+			if !reflect.DeepEqual(interpreted, closures) {
+				t.Errorf("[%s] over [%d] rounds evaluated to %+v with closures, %+v interpreted",
+					Instructions(u.Instructions).ToProgram(), rounds, closures, interpreted)
+			}
+		}
+	}
+}
+
+func TestEvaluateProfiled(t *test.T) {
+	evaluator, _ := makeEvaluatorAndUnit()
+	unit := &Unit{Instructions: []*Instruction{NewInstruction("[-]["), NewInstruction("+>"), NewInstruction("]#")}}
+
+	if eval := evaluator.Evaluate(unit); eval.Profile != nil || eval.GeneExecutions(unit) != nil {
+		t.Errorf("Evaluation profiled without profiling enabled")
+	}
+
+	evaluator.EnableProfiling()
+	eval := evaluator.Evaluate(unit)
+	if eval.Profile == nil {
+		t.Fatalf("Evaluation missing its profile")
+	}
+
+	genes := eval.GeneExecutions(unit)
+	if len(genes) != 3 {
+		t.Fatalf("GeneExecutions returned [%d] genes, expected [3]", len(genes))
+	}
+	// The middle gene sits in a loop that can never run
+	if genes[0] == 0 || genes[1] != 0 || genes[2] != 1 {
+		t.Errorf("Unexpected gene executions %v", genes)
+	}
+}
+
+func TestRandomCell(t *test.T) {
+	rng = newPooledRand(42)
+	wide := false
+	for n := 0; n < 100; n++ {
+		if cell := randomCell(255); cell >= 255 {
+			t.Fatalf("Drew cell [%d] for maximum [255]", cell)
+		}
+		cell := randomCell(math.MaxUint32)
+		if cell == math.MaxUint32 {
+			t.Fatalf("Drew cell [%d] for maximum [%d]", cell, uint32(math.MaxUint32))
+		}
+		wide = wide || cell > math.MaxInt32
+	}
+	if !wide {
+		t.Errorf("32 bit cells never drew past math.MaxInt32")
+	}
+}
@@ -0,0 +1,106 @@
+package genetic_sort
+
+import (
+	"fmt"
+)
+
+// Gene meta ops change which genes a Unit has, where a Mutation's meta op
+// changes the ops of one gene. A child takes at most one GeneMutation, with
+// its Mutator's GeneMutationChance, as it grows.
+const (
+	DUPLICATE_GENE_OP byte = iota + 1 // copy a gene to a random position
+	DELETE_GENE_OP                    // drop a gene, unless it's the only one
+	INSERT_GENE_OP                    // add a fresh gene of random prefabs
+	MOVE_GENE_OP                      // move a gene to a random position
+)
+
+var GENE_META_OP_SET = []byte{
+	DUPLICATE_GENE_OP,
+	DELETE_GENE_OP,
+	INSERT_GENE_OP,
+	MOVE_GENE_OP,
+}
+
+// geneMetaOpNames names the gene meta ops in configs and reports.
+var geneMetaOpNames = map[byte]string{
+	DUPLICATE_GENE_OP: "duplicate",
+	DELETE_GENE_OP:    "delete",
+	INSERT_GENE_OP:    "insert",
+	MOVE_GENE_OP:      "move",
+}
+
+// GeneMetaOpName returns the config name of a gene meta op.
+func GeneMetaOpName(metaOp byte) string {
+	if name, ok := geneMetaOpNames[metaOp]; ok {
+		return name
+	}
+	return fmt.Sprintf("GeneMetaOp(%d)", metaOp)
+}
+
+type GeneMutation struct {
+	UnitID    uint
+	MetaOP    byte
+	Position1 *uint // the gene duplicated, deleted or moved
+	Position2 *uint // where the duplicate, fresh or moved gene ended up
+	Chance    float32
+}
+
+func (m *GeneMutation) String() string {
+	return fmt.Sprintf("{MetaOP: %v, Position1: %v, Position2: %v, Chance: %v}", GeneMetaOpName(m.MetaOP), m.Position1, m.Position2, m.Chance)
+}
+
+func (mu *Mutator) NewGeneMutation(chance float32) *GeneMutation {
+	return &GeneMutation{
+		MetaOP: GENE_META_OP_SET[mu.geneMetaOpWeights.draw(len(GENE_META_OP_SET))],
+		Chance: chance,
+	}
+}
+
+// Apply changes which genes u has and records the mutation on u. Fresh genes
+// are drawn from mu. A mutation that can't apply, like deleting a unit's only
+// gene, leaves the genes alone and is recorded without positions.
+func (m *GeneMutation) Apply(u *Unit, mu *Mutator) {
+	genes := u.Instructions
+	length := len(genes)
+
+	switch m.MetaOP {
+	case DUPLICATE_GENE_OP:
+		if length == 0 {
+			break
+		}
+		from, to := uint(rng.Intn(length)), uint(rng.Intn(length+1))
+		genes = insertGene(genes, to, genes[from].Clone())
+		m.Position1, m.Position2 = &from, &to
+	case DELETE_GENE_OP:
+		if length < 2 {
+			break
+		}
+		at := uint(rng.Intn(length))
+		genes = append(genes[:at], genes[at+1:]...)
+		m.Position1 = &at
+	case INSERT_GENE_OP:
+		to := uint(rng.Intn(length + 1))
+		genes = insertGene(genes, to, mu.NewInstruction(mu.GeneOpSetCount))
+		m.Position2 = &to
+	case MOVE_GENE_OP:
+		if length < 2 {
+			break
+		}
+		from, to := uint(rng.Intn(length)), uint(rng.Intn(length))
+		gene := genes[from]
+		genes = append(genes[:from], genes[from+1:]...)
+		genes = insertGene(genes, to, gene)
+		m.Position1, m.Position2 = &from, &to
+	}
+
+	u.Instructions = genes
+	u.GeneMutations = append(u.GeneMutations, m)
+}
+
+// insertGene inserts gene into genes before index at.
+func insertGene(genes []*Instruction, at uint, gene *Instruction) []*Instruction {
+	genes = append(genes, nil)
+	copy(genes[at+1:], genes[at:])
+	genes[at] = gene
+	return genes
+}
@@ -8,35 +8,20 @@ import (
 	bf "nickandperla.net/brainfuck"
 )
 
-// Lookup tables for 4-bit nibble <-> BF op conversion.
-// Avoids encoding/binary + strings.Builder overhead.
-var nibbleToOp [16]byte
-var opToNibble [256]byte
-
-func init() {
-	nibbleToOp[1] = bf.OP_POINTER_LEFT
-	nibbleToOp[2] = bf.OP_POINTER_RIGHT
-	nibbleToOp[3] = bf.OP_INC
-	nibbleToOp[4] = bf.OP_DEC
-	nibbleToOp[5] = bf.OP_WHILE
-	nibbleToOp[6] = bf.OP_WHILE_END
-	nibbleToOp[7] = bf.OP_JUMP
-	nibbleToOp[8] = bf.OP_BOOKMARK
-	nibbleToOp[9] = bf.NO_OP
-
-	opToNibble[bf.OP_POINTER_LEFT] = 1
-	opToNibble[bf.OP_POINTER_RIGHT] = 2
-	opToNibble[bf.OP_INC] = 3
-	opToNibble[bf.OP_DEC] = 4
-	opToNibble[bf.OP_WHILE] = 5
-	opToNibble[bf.OP_WHILE_END] = 6
-	opToNibble[bf.OP_JUMP] = 7
-	opToNibble[bf.OP_BOOKMARK] = 8
-	opToNibble[bf.NO_OP] = 9
-}
+// Ops are packed as 4-bit nibbles holding their code from the bf op
+// registry, 8 to a 32-bit word. Nibble 0 is padding. Codes of
+// bf.EXTENDED_CODE and up don't fit a nibble and take three: an
+// bf.EXTENDED_CODE escape followed by the code's high and low nibble. The
+// built in ops all have single nibble codes, so their packing is unchanged
+// by the escape.
 
 type InstructionConfig struct {
 	OpSetCount int `toml:"op_set_count"`
+	// Prefabs is a library of named prefabs that replaces the ones of the
+	// machine's dialect, weighed by name with PrefabWeights. nil keeps the
+	// dialect's.
+	Prefabs       map[string]string  `toml:"prefabs"`
+	PrefabWeights map[string]float32 `toml:"prefab_weights"`
 }
 
 type Instruction struct {
@@ -51,17 +36,34 @@ type Instruction struct {
 
 type Instructions []*Instruction
 
-func NewInstructionFromConfig(config *InstructionConfig) *Instruction {
-	return NewRandomInstruction(config.OpSetCount)
+// NewInstructionFromConfig draws a gene of config on the default machine. A
+// config with a prefab library draws from it, and fails if it's bad. Draw
+// many genes, or genes for another machine, with config's Mutator.
+func NewInstructionFromConfig(config *InstructionConfig) (*Instruction, error) {
+	mu, err := configMutator(config)
+	if err != nil {
+		return nil, err
+	}
+	return mu.NewInstruction(config.OpSetCount), nil
 }
 
 func NewRandomInstruction(opSetCount int) *Instruction {
+	return defaultMutator.NewInstruction(opSetCount)
+}
+
+// NewInstruction strings together opSetCount prefabs of the Mutator's
+// dialect or library, drawn with its prefab weights, or its modules.
+func (mu *Mutator) NewInstruction(opSetCount int) *Instruction {
 	instruction := &Instruction{Age: 0}
 
 	var sb str.Builder
 
 	for i := 0; i < opSetCount; i++ {
-		sb.WriteString(bf.PREFAB_OPSETS[rng.Intn(len(bf.PREFAB_OPSETS))])
+		if len(mu.Modules) > 0 && rng.Float32() < mu.ModuleChance {
+			sb.WriteString(mu.Modules[rng.Intn(len(mu.Modules))])
+			continue
+		}
+		sb.WriteString(mu.Prefabs[mu.prefabWeights.draw(len(mu.Prefabs))])
 	}
 
 	raw := []byte(sb.String())
@@ -129,7 +131,6 @@ func (i *Instruction) EnsureCompressed() {
 }
 
 // makeOpsBig decompresses 4-bit packed ops into raw BF op bytes.
-// Uses lookup table instead of encoding/binary + strings.Builder.
 func makeOpsBig(stuff []byte) []byte {
 	result := make([]byte, 0, len(stuff)*2)
 
@@ -137,18 +138,32 @@ func makeOpsBig(stuff []byte) []byte {
 		log.Printf("Making things big. Count: %v, Original: %v", len(stuff), stuff)
 	}
 
+	// Nibbles of an extended code still to come, and the code so far
+	var extended int
+	var code byte
 	for i := 0; i+4 <= len(stuff); i += 4 {
 		packed := uint32(stuff[i])<<24 | uint32(stuff[i+1])<<16 | uint32(stuff[i+2])<<8 | uint32(stuff[i+3])
 		for j := uint(0); j < 8; j++ {
-			symbol := (packed >> (28 - 4*j)) & 0xF
-			if symbol == 0 {
+			symbol := byte((packed >> (28 - 4*j)) & 0xF)
+			if extended > 0 {
+				code = code<<4 | symbol
+				extended--
+				if extended > 0 {
+					continue
+				}
+			} else if symbol == 0 {
+				continue
+			} else if symbol == bf.EXTENDED_CODE {
+				extended, code = 2, 0
 				continue
+			} else {
+				code = symbol
 			}
-			op := nibbleToOp[symbol]
-			if op == 0 {
-				panic(fmt.Sprintf("Unknown symbol [%v] encountered!", symbol))
+			def := bf.OpByCode(code)
+			if def == nil {
+				panic(fmt.Sprintf("Unknown symbol [%v] encountered!", code))
 			}
-			result = append(result, op)
+			result = append(result, def.Symbol)
 		}
 	}
 
@@ -159,36 +174,49 @@ func makeOpsBig(stuff []byte) []byte {
 }
 
 // makeOpsSmallBytes compresses raw BF op bytes into 4-bit packed format.
-// Uses lookup table instead of encoding/binary + switch statements.
 func makeOpsSmallBytes(raw []byte) []byte {
 	if len(raw) == 0 {
 		return nil
 	}
-	outLen := ((len(raw) + 7) / 8) * 4
-	result := make([]byte, outLen)
 
 	if DEBUG {
 		log.Printf("Making things small. Count: %v, Original: %v", len(raw), raw)
 	}
 
-	for i := 0; i < len(raw); i += 8 {
-		var packed uint32
-		end := i + 8
-		if end > len(raw) {
-			end = len(raw)
+	codes := make([]byte, len(raw))
+	nibbles := 0
+	for i, o := range raw {
+		if o == 0 {
+			// Packs as padding
+			nibbles++
+			continue
 		}
-		for j := 0; j < end-i; j++ {
-			symbol := opToNibble[raw[i+j]]
-			if symbol == 0 && raw[i+j] != 0 {
-				panic(fmt.Sprintf("Unknown OP [%v] encountered!", raw[i+j]))
-			}
-			packed |= uint32(symbol) << (28 - uint(4*j))
+		def := bf.OpBySymbol(o)
+		if def == nil {
+			panic(fmt.Sprintf("Unknown OP [%v] encountered!", o))
+		}
+		codes[i] = def.Code
+		if def.Code >= bf.EXTENDED_CODE {
+			nibbles += 3
+		} else {
+			nibbles++
+		}
+	}
+
+	result := make([]byte, ((nibbles+7)/8)*4)
+	n := 0
+	put := func(nibble byte) {
+		result[n/2] |= nibble << (4 * uint(1-n%2))
+		n++
+	}
+	for _, code := range codes {
+		if code >= bf.EXTENDED_CODE {
+			put(bf.EXTENDED_CODE)
+			put(code >> 4)
+			put(code & 0xF)
+		} else {
+			put(code)
 		}
-		outIdx := (i / 8) * 4
-		result[outIdx] = byte(packed >> 24)
-		result[outIdx+1] = byte(packed >> 16)
-		result[outIdx+2] = byte(packed >> 8)
-		result[outIdx+3] = byte(packed)
 	}
 
 	if DEBUG {
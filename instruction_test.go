@@ -9,7 +9,10 @@ import (
 )
 
 func TestNewInstruction(t *testing.T) {
-	instruct1 := NewInstructionFromConfig(&InstructionConfig{OpSetCount: 10})
+	instruct1, err := NewInstructionFromConfig(&InstructionConfig{OpSetCount: 10})
+	if err != nil {
+		t.Fatalf("NewInstructionFromConfig() failed. %v", err)
+	}
 
 	if instruct1 == nil {
 		t.Errorf("Unexpected failure from NewInstructionFromConfig(). Returned nil")
@@ -31,9 +34,44 @@ func TestNewInstruction(t *testing.T) {
 	}
 }
 
+func TestNewInstructionFromPrefabs(t *testing.T) {
+	config := &InstructionConfig{OpSetCount: 3, Prefabs: map[string]string{"clear": "[-]"}}
+	instruction, err := NewInstructionFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewInstructionFromConfig() failed. %v", err)
+	}
+	if program := string(instruction.ToProgram()); program != "[-][-][-]" {
+		t.Errorf("Gene [%s] isn't drawn from the prefab library", program)
+	}
+
+	unit, err := NewUnitFromConfig(&UnitConfig{InstructionCount: 2, InstructionConfig: config})
+	if err != nil {
+		t.Fatalf("NewUnitFromConfig() failed. %v", err)
+	}
+	if program := Instructions(unit.Instructions).ToProgram(); program != "[-][-][-][-][-][-]" {
+		t.Errorf("Unit [%s] isn't drawn from the prefab library", program)
+	}
+
+	// % isn't an op of the default machine, but is of one with cmp_swap
+	config = &InstructionConfig{OpSetCount: 2, Prefabs: map[string]string{"sort": "^>%"}}
+	if _, err := NewInstructionFromConfig(config); err == nil {
+		t.Errorf("Prefab [^>%%] was accepted on the default machine")
+	}
+	mu, err := config.Mutator(&bf.MachineConfig{CmpSwap: true})
+	if err != nil {
+		t.Fatalf("Prefab [^>%%] was rejected on a machine with cmp_swap. %v", err)
+	}
+	if program := string(mu.NewInstruction(mu.GeneOpSetCount).ToProgram()); program != "^>%^>%" {
+		t.Errorf("Gene [%s] isn't drawn from the prefab library", program)
+	}
+}
+
 func TestInstructionClone(t *testing.T) {
 
-	instruct1 := NewInstructionFromConfig(&InstructionConfig{OpSetCount: 10})
+	instruct1, err := NewInstructionFromConfig(&InstructionConfig{OpSetCount: 10})
+	if err != nil {
+		t.Fatalf("NewInstructionFromConfig() failed. %v", err)
+	}
 
 	if instruct1 == nil {
 		t.Errorf("Unexpected failure from NewInstructionFromConfig(). Returned nil")
@@ -51,7 +89,10 @@ func TestInstructionClone(t *testing.T) {
 
 func TestInstructionStringer(t *testing.T) {
 
-	instruct1 := NewInstructionFromConfig(&InstructionConfig{OpSetCount: 10})
+	instruct1, err := NewInstructionFromConfig(&InstructionConfig{OpSetCount: 10})
+	if err != nil {
+		t.Fatalf("NewInstructionFromConfig() failed. %v", err)
+	}
 
 	if instruct1 == nil {
 		t.Errorf("Unexpected failure from NewInstructionFromConfig(). Returned nil")
@@ -80,7 +121,10 @@ func TestInstructionStringer(t *testing.T) {
 
 func TestInstructionAge(t *testing.T) {
 
-	instruct1 := NewInstructionFromConfig(&InstructionConfig{OpSetCount: 10})
+	instruct1, err := NewInstructionFromConfig(&InstructionConfig{OpSetCount: 10})
+	if err != nil {
+		t.Fatalf("NewInstructionFromConfig() failed. %v", err)
+	}
 	if instruct1 == nil {
 		t.Errorf("Unexpected failure from NewInstructionFromConfig(). Returned nil")
 	}
@@ -107,6 +151,40 @@ func TestMakeOpsSmallAndBig(t *testing.T) {
 	}
 }
 
+func TestMakeOpsSmallAndBigBookmarks(t *testing.T) {
+	ops := bf.SWAP_RIGHT_MARKED
+	uncompressed := makeOpsBig(makeOpsSmall(ops))
+
+	if ops != string(uncompressed) {
+		t.Errorf("Failed to roundtrip bookmark register Ops.\nOrig: %v\nUncompressed: %v\n", ops, string(uncompressed))
+	}
+}
+
+func TestMakeOpsSmallAndBigExtendedCodes(t *testing.T) {
+	err := bf.RegisterOp(&bf.OpDef{
+		Symbol:  '!',
+		Name:    "OP_TEST_EXTENDED",
+		Code:    0x30,
+		Exec:    func(memory *bf.Memory, index int) (bool, error) { return true, nil },
+		Enabled: func(mc *bf.MachineConfig) bool { return false },
+	})
+	if err != nil {
+		t.Fatalf("Failed to register test op: %v", err)
+	}
+
+	// Escapes straddle the 32-bit word boundaries
+	ops := "!+!-!!><*^!#!"
+	compressed := makeOpsSmall(ops)
+	uncompressed := makeOpsBig(compressed)
+
+	if ops != string(uncompressed) {
+		t.Errorf("Failed to roundtrip extended Ops.\nOrig: %v\nCompressed: %v\nUncompressed: %v\n", ops, compressed, string(uncompressed))
+	}
+	if !bytes.Equal(makeOpsSmall(bf.SWAP_RIGHT), []byte{0x85, 0x26, 0x75, 0x47, 0x37, 0x62, 0x54, 0x13, 0x26, 0x75, 0x47, 0x37, 0x60, 0, 0, 0}) {
+		t.Errorf("Packing of built in ops changed: %x", makeOpsSmall(bf.SWAP_RIGHT))
+	}
+}
+
 func TestInstructionsToProgram(t *testing.T) {
 
 	var ins Instructions = make(Instructions, 3)
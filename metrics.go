@@ -13,6 +13,8 @@ type PopulationMetrics struct {
 	BestSetFidelity byte
 	AvgSortedness  float64
 	AvgSetFidelity float64
+	// AvgMutationChance tracks the mutation chance adapting units evolve
+	AvgMutationChance float64
 }
 
 // shardMetrics holds per-shard aggregates that get merged into PopulationMetrics.
@@ -22,6 +24,7 @@ type shardMetrics struct {
 	sumSetFidelity uint64
 	maxSortedness  byte
 	maxSetFidelity byte
+	sumMutationChance float64
 }
 
 // QueryMetrics queries aggregate fitness metrics for all alive units across
@@ -54,12 +57,14 @@ func (p *Population) QueryMetrics() (*PopulationMetrics, error) {
 	m := &PopulationMetrics{}
 	var totalCount uint64
 	var totalSortedness, totalFidelity uint64
+	var totalMutationChance float64
 
 	for _, sm := range results {
 		m.AliveCount += sm.count
 		totalCount += uint64(sm.count)
 		totalSortedness += sm.sumSortedness
 		totalFidelity += sm.sumSetFidelity
+		totalMutationChance += sm.sumMutationChance
 		if sm.maxSortedness > m.BestSortedness {
 			m.BestSortedness = sm.maxSortedness
 		}
@@ -71,6 +76,7 @@ func (p *Population) QueryMetrics() (*PopulationMetrics, error) {
 	if totalCount > 0 {
 		m.AvgSortedness = float64(totalSortedness) / float64(totalCount)
 		m.AvgSetFidelity = float64(totalFidelity) / float64(totalCount)
+		m.AvgMutationChance = totalMutationChance / float64(totalCount)
 	}
 
 	return m, nil
@@ -80,7 +86,7 @@ func queryShardMetrics(db *sql.DB, popID uint) (shardMetrics, error) {
 	var sm shardMetrics
 	row := db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(e.sortedness), 0),
 		COALESCE(SUM(e.set_fidelity), 0), COALESCE(MAX(e.sortedness), 0),
-		COALESCE(MAX(e.set_fidelity), 0)
+		COALESCE(MAX(e.set_fidelity), 0), COALESCE(SUM(u.mutation_chance), 0)
 		FROM evaluations e
 		JOIN (
 			SELECT MAX(evaluations.id) as id
@@ -88,12 +94,14 @@ func queryShardMetrics(db *sql.DB, popID uint) (shardMetrics, error) {
 			JOIN units ON units.id = evaluations.unit_id
 			WHERE units.population_id = ? AND units.alive = ?
 			GROUP BY evaluations.unit_id
-		) latest ON e.id = latest.id`, popID, Alive)
+		) latest ON e.id = latest.id
+		JOIN units u ON u.id = e.unit_id`, popID, Alive)
 
 	var count int64
 	var sumSort, sumFid int64
 	var maxSort, maxFid int
-	if err := row.Scan(&count, &sumSort, &sumFid, &maxSort, &maxFid); err != nil {
+	var sumChance float64
+	if err := row.Scan(&count, &sumSort, &sumFid, &maxSort, &maxFid, &sumChance); err != nil {
 		return sm, err
 	}
 	sm.count = uint(count)
@@ -101,6 +109,7 @@ func queryShardMetrics(db *sql.DB, popID uint) (shardMetrics, error) {
 	sm.sumSetFidelity = uint64(sumFid)
 	sm.maxSortedness = byte(maxSort)
 	sm.maxSetFidelity = byte(maxFid)
+	sm.sumMutationChance = sumChance
 	return sm, nil
 }
 
@@ -229,10 +238,10 @@ func queryShardBestUnit(db *sql.DB, popID uint, shard uint) (struct {
 
 func loadSingleUnit(db *sql.DB, unitID uint) (*Unit, error) {
 	u := &Unit{}
-	var parentID sql.NullInt64
-	err := db.QueryRow(`SELECT id, population_id, parent_id, age, generation, lifespan, mutation_chance, alive
+	var parentID, secondParentID sql.NullInt64
+	err := db.QueryRow(`SELECT id, population_id, parent_id, second_parent_id, age, generation, lifespan, mutation_chance, alive
 		FROM units WHERE id = ?`, unitID).
-		Scan(&u.ID, &u.PopulationID, &parentID, &u.Age, &u.Generation, &u.Lifespan, &u.MutationChance, &u.Alive)
+		Scan(&u.ID, &u.PopulationID, &parentID, &secondParentID, &u.Age, &u.Generation, &u.Lifespan, &u.MutationChance, &u.Alive)
 	if err != nil {
 		return nil, err
 	}
@@ -240,6 +249,10 @@ func loadSingleUnit(db *sql.DB, unitID uint) (*Unit, error) {
 		pid := uint(parentID.Int64)
 		u.ParentID = &pid
 	}
+	if secondParentID.Valid {
+		pid := uint(secondParentID.Int64)
+		u.SecondParentID = &pid
+	}
 	return u, nil
 }
 
@@ -247,10 +260,10 @@ func loadSingleEvaluation(db *sql.DB, evalID uint) (*Evaluation, error) {
 	e := &Evaluation{}
 	var machineRun int
 	err := db.QueryRow(`SELECT id, unit_id, machine_run, set_fidelity, sortedness,
-		instruction_count, instructions_executed, machine_error
+		instruction_count, instructions_executed, machine_error, machine_error_kind, machine_error_loop
 		FROM evaluations WHERE id = ?`, evalID).
 		Scan(&e.ID, &e.UnitID, &machineRun, &e.SetFidelity, &e.Sortedness,
-			&e.InstructionCount, &e.InstructionsExecuted, &e.MachineError)
+			&e.InstructionCount, &e.InstructionsExecuted, &e.MachineError, &e.MachineErrorKind, &e.MachineErrorLoop)
 	if err != nil {
 		return nil, err
 	}
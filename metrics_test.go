@@ -66,7 +66,7 @@ func TestQueryMetricsWithUnits(t *test.T) {
 	// Create 3 alive units with evaluations
 	for i := 0; i < 3; i++ {
 		res, err := db.Exec(`INSERT INTO units (population_id, alive, mutation_chance, lifespan) VALUES (?, ?, ?, ?)`,
-			pop.ID, Alive, 0.1, 100)
+			pop.ID, Alive, 0.1*float64(i+1), 100)
 		if err != nil {
 			t.Fatalf("Failed to create unit %d: %v", i, err)
 		}
@@ -104,6 +104,10 @@ func TestQueryMetricsWithUnits(t *test.T) {
 	if m.AvgSetFidelity < 84.9 || m.AvgSetFidelity > 85.1 {
 		t.Errorf("Expected avg set fidelity ~85.0, got %.2f", m.AvgSetFidelity)
 	}
+	// avg mutation chance = (0.1+0.2+0.3)/3 = 0.2
+	if m.AvgMutationChance < 0.199 || m.AvgMutationChance > 0.201 {
+		t.Errorf("Expected avg mutation chance ~0.2, got %.4f", m.AvgMutationChance)
+	}
 }
 
 func TestQueryMetricsUsesLatestEval(t *test.T) {
@@ -219,3 +223,28 @@ func TestQueryBestUnit(t *test.T) {
 		t.Error("Expected instructions to be loaded for best unit")
 	}
 }
+
+func TestLoadSingleEvaluationMachineError(t *test.T) {
+	db, persist := setupMetricsTestDB(t)
+	defer db.Close()
+
+	msg, loop := "OP_WHILE at tape index [4] failed to start iteration [9]. Loop iteration limit reached", uint(4)
+	u := &Unit{ID: 1, Alive: Alive, Evaluations: []*Evaluation{
+		{UnitID: 1, MachineError: &msg, MachineErrorKind: bf.ERR_LOOP_LIMIT, MachineErrorLoop: &loop},
+		{UnitID: 1, MachineRun: true},
+	}}
+	if err := persist.PersistEvaluatedBatch([]*Unit{u}); err != nil {
+		t.Fatalf("Failed to persist evaluations: %v", err)
+	}
+
+	for _, want := range u.Evaluations {
+		got, err := loadSingleEvaluation(db, want.ID)
+		if err != nil {
+			t.Fatalf("Failed to load evaluation %d: %v", want.ID, err)
+		}
+		if got.MachineErrorKind != want.MachineErrorKind || (got.MachineErrorLoop == nil) != (want.MachineErrorLoop == nil) ||
+			(got.MachineErrorLoop != nil && *got.MachineErrorLoop != *want.MachineErrorLoop) {
+			t.Errorf("Evaluation %d loaded with kind [%v] loop [%v], expected [%v] [%v]", want.ID, got.MachineErrorKind, got.MachineErrorLoop, want.MachineErrorKind, want.MachineErrorLoop)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package genetic_sort
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	str "strings"
+)
+
+// Module mining, automatically defined functions after our prefabs: every
+// few generations the programs of the best units are scanned for snippets
+// that recur across many of them, and the most useful snippets become the
+// population's modules. Fresh genes and INSERT_MODULE_OP then draw from the
+// modules the way they draw from prefabs, so building blocks selection
+// already found spread to the rest of the population whole.
+
+// ModuleConfig configures module mining. nil mines nothing.
+type ModuleConfig struct {
+	// Mine every MineEvery generations from the EliteCount best units
+	MineEvery  uint `toml:"mine_every" json:"mine_every"`
+	EliteCount uint `toml:"elite_count" json:"elite_count"`
+	// Modules are snippets of MinLength to MaxLength ops, with balanced
+	// loops, found in at least MinSupport of the elite
+	MinLength  uint `toml:"min_length" json:"min_length"`
+	MaxLength  uint `toml:"max_length" json:"max_length"`
+	MinSupport uint `toml:"min_support" json:"min_support"`
+	// The library keeps the MaxModules best
+	MaxModules uint `toml:"max_modules" json:"max_modules"`
+	// Chance that each prefab of a fresh gene is a module instead
+	Chance float32 `toml:"chance" json:"chance"`
+}
+
+// Check reports what's wrong with mc, if anything.
+func (mc *ModuleConfig) Check() error {
+	if mc.MineEvery == 0 || mc.EliteCount == 0 || mc.MaxModules == 0 {
+		return fmt.Errorf("Module mining needs mine_every, elite_count and max_modules above 0")
+	}
+	if !(2 <= mc.MinLength && mc.MinLength <= mc.MaxLength) {
+		return fmt.Errorf("Module lengths [%d, %d] are not a range of at least 2 ops", mc.MinLength, mc.MaxLength)
+	}
+	if mc.MinSupport < 2 {
+		return fmt.Errorf("Module min_support [%d] is below 2, so nothing needs to recur", mc.MinSupport)
+	}
+	if !(0 <= mc.Chance && mc.Chance <= 1) {
+		return fmt.Errorf("Module chance [%v] is not inside [0, 1]", mc.Chance)
+	}
+	return nil
+}
+
+// Module is a snippet mined from the elite. Support is how many of the
+// elite programs it was found in.
+type Module struct {
+	Ops     string
+	Support uint
+}
+
+// MineModules finds the snippets recurring across programs that mc allows,
+// best first. A snippet is as good as the ops it covers across programs,
+// its length times its support, and snippets inside better ones are left
+// out.
+func MineModules(programs [][]byte, mc *ModuleConfig) []Module {
+	support := make(map[string]uint)
+	for _, program := range programs {
+		seen := make(map[string]bool)
+		for length := int(mc.MinLength); length <= int(mc.MaxLength); length++ {
+			for start := 0; start+length <= len(program); start++ {
+				snippet := string(program[start : start+length])
+				if seen[snippet] {
+					continue
+				}
+				seen[snippet] = true
+				if balanced(snippet) {
+					support[snippet]++
+				}
+			}
+		}
+	}
+
+	var candidates []Module
+	for ops, n := range support {
+		if n >= mc.MinSupport {
+			candidates = append(candidates, Module{Ops: ops, Support: n})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if gainA, gainB := uint(len(a.Ops))*a.Support, uint(len(b.Ops))*b.Support; gainA != gainB {
+			return gainA > gainB
+		}
+		return a.Ops < b.Ops
+	})
+
+	var modules []Module
+	for _, candidate := range candidates {
+		if uint(len(modules)) == mc.MaxModules {
+			break
+		}
+		redundant := false
+		for _, module := range modules {
+			if str.Contains(module.Ops, candidate.Ops) {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			modules = append(modules, candidate)
+		}
+	}
+	return modules
+}
+
+// UseModules makes mu draw from modules, each prefab of a fresh gene with
+// chance. Mutations draw INSERT_MODULE_OP, if it's one of mu's MetaOps, only
+// while there are modules.
+func (mu *Mutator) UseModules(modules []Module, chance float32) {
+	mu.Modules = make([]string, len(modules))
+	for i, module := range modules {
+		mu.Modules[i] = module.Ops
+	}
+	mu.ModuleChance = chance
+}
+
+// insertModule inserts module into raw before index at.
+func insertModule(raw []byte, at uint, module []byte) []byte {
+	inserted := make([]byte, 0, len(raw)+len(module))
+	inserted = append(inserted, raw[:at]...)
+	inserted = append(inserted, module...)
+	return append(inserted, raw[at:]...)
+}
+
+// miningDue reports whether the population mines modules this generation.
+func (p *Population) miningDue() bool {
+	mc := p.PopulationConfig.UnitConfig.ModuleConfig
+	return mc != nil && p.CurrentGeneration%mc.MineEvery == 0
+}
+
+// unitMutator returns the Mutator of the population's units, with its
+// modules.
+func (p *Population) unitMutator() *Mutator {
+	config := p.PopulationConfig
+	mu := NewUnitMutator(config.UnitConfig, config.EvaluatorConfig)
+	if mc := config.UnitConfig.ModuleConfig; mc != nil {
+		mu.UseModules(p.Modules, mc.Chance)
+	}
+	return mu
+}
+
+// PromoteModules mines the elite programs for modules and makes them the
+// population's modules, replacing the ones it had. Mining nothing, as when
+// too few units are left, keeps them.
+func (p *Population) PromoteModules(programs [][]byte) error {
+	modules := MineModules(programs, p.PopulationConfig.UnitConfig.ModuleConfig)
+	if len(modules) == 0 {
+		return nil
+	}
+	err := withTx(p.persist.shard0(), func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM modules WHERE population_id = ?", p.ID); err != nil {
+			return err
+		}
+		for _, module := range modules {
+			if _, err := tx.Exec(`INSERT INTO modules (population_id, generation, ops, support) VALUES (?, ?, ?, ?)`,
+				p.ID, p.CurrentGeneration, module.Ops, module.Support); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save modules: %w", err)
+	}
+	p.Modules = modules
+	return nil
+}
+
+// queryModules loads the modules of a population, best first.
+func queryModules(db *sql.DB, popID uint) ([]Module, error) {
+	rows, err := db.Query("SELECT ops, support FROM modules WHERE population_id = ? ORDER BY id", popID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query modules: %w", err)
+	}
+	defer rows.Close()
+
+	var modules []Module
+	for rows.Next() {
+		var m Module
+		if err := rows.Scan(&m.Ops, &m.Support); err != nil {
+			return nil, err
+		}
+		modules = append(modules, m)
+	}
+	return modules, rows.Err()
+}
+
+// elitePrograms returns the programs of the n best alive units, best first.
+func elitePrograms(units []*Unit, ranker *FitnessRanker, n uint) [][]byte {
+	var ranked []*Unit
+	for _, u := range units {
+		if u.Alive == Alive && len(u.Evaluations) > 0 {
+			ranked = append(ranked, u)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranker.CompareEvaluations(ranked[i].Evaluations[len(ranked[i].Evaluations)-1],
+			ranked[j].Evaluations[len(ranked[j].Evaluations)-1]) < 0
+	})
+	if uint(len(ranked)) > n {
+		ranked = ranked[:n]
+	}
+	programs := make([][]byte, len(ranked))
+	for i, u := range ranked {
+		programs[i] = []byte(Instructions(u.Instructions).ToProgram())
+	}
+	return programs
+}
+
+// queryElitePrograms returns the programs of the n best alive units across
+// all shards, best first, by their latest evaluations.
+func (p *Population) queryElitePrograms(ranker *FitnessRanker, n uint) ([][]byte, error) {
+	var evals []Evaluation
+	for _, db := range p.persist.Shards {
+		shardEvals, err := queryReproduceEvals(db, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		evals = append(evals, shardEvals...)
+	}
+	sort.Slice(evals, func(i, j int) bool {
+		return ranker.CompareEvaluations(&evals[i], &evals[j]) < 0
+	})
+	if uint(len(evals)) > n {
+		evals = evals[:n]
+	}
+
+	programs := make([][]byte, len(evals))
+	for i, eval := range evals {
+		instructions, err := queryInstructionsForUnits(p.persist.shardFor(eval.UnitID), []uint{eval.UnitID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load elite unit %d: %w", eval.UnitID, err)
+		}
+		programs[i] = []byte(Instructions(instructions).ToProgram())
+	}
+	return programs, nil
+}
@@ -0,0 +1,158 @@
+package genetic_sort
+
+import (
+	"reflect"
+	"strings"
+	test "testing"
+)
+
+func TestMineModules(t *test.T) {
+	mc := &ModuleConfig{MineEvery: 1, EliteCount: 3, MinLength: 2, MaxLength: 6, MinSupport: 2, MaxModules: 2}
+	programs := [][]byte{
+		[]byte("+>[-]<>[-]<"),
+		[]byte("<<>[-]<+"),
+		[]byte("-->[-]<"),
+		[]byte("+[>+"),
+	}
+
+	// ">[-]<" covers the most ops. Its snippets are left out, and snippets
+	// with unbalanced loops, like "+[>", never count
+	modules := MineModules(programs, mc)
+	if len(modules) == 0 || modules[0] != (Module{Ops: ">[-]<", Support: 3}) {
+		t.Fatalf("Mined %v, expected >[-]< first", modules)
+	}
+	for _, module := range modules {
+		if !balanced(module.Ops) {
+			t.Errorf("Mined module [%s] has unbalanced loops", module.Ops)
+		}
+		if module.Support < mc.MinSupport {
+			t.Errorf("Mined module [%s] recurs in only %d programs", module.Ops, module.Support)
+		}
+		if module != modules[0] && strings.Contains(modules[0].Ops, module.Ops) {
+			t.Errorf("Mined module [%s] is a snippet of [%s]", module.Ops, modules[0].Ops)
+		}
+	}
+	if uint(len(modules)) > mc.MaxModules {
+		t.Errorf("Mined %d modules, expected at most %d", len(modules), mc.MaxModules)
+	}
+
+	// Nothing recurs in a single program, however often it repeats
+	if modules := MineModules(programs[:1], mc); len(modules) != 0 {
+		t.Errorf("Mined %v from one program", modules)
+	}
+
+	bad := []ModuleConfig{
+		{MineEvery: 0, EliteCount: 3, MinLength: 2, MaxLength: 6, MinSupport: 2, MaxModules: 2},
+		{MineEvery: 1, EliteCount: 3, MinLength: 1, MaxLength: 6, MinSupport: 2, MaxModules: 2},
+		{MineEvery: 1, EliteCount: 3, MinLength: 4, MaxLength: 3, MinSupport: 2, MaxModules: 2},
+		{MineEvery: 1, EliteCount: 3, MinLength: 2, MaxLength: 6, MinSupport: 1, MaxModules: 2},
+		{MineEvery: 1, EliteCount: 3, MinLength: 2, MaxLength: 6, MinSupport: 2, MaxModules: 2, Chance: 2},
+	}
+	if err := mc.Check(); err != nil {
+		t.Errorf("Check rejected %+v: %v", mc, err)
+	}
+	for _, config := range bad {
+		if err := config.Check(); err == nil {
+			t.Errorf("Check accepted %+v", config)
+		}
+	}
+}
+
+func TestModuleMutations(t *test.T) {
+	rng = newPooledRand(42)
+
+	uc := &UnitConfig{
+		InstructionConfig: &InstructionConfig{OpSetCount: 1},
+		MutationConfig:    &MutationConfig{MetaOpWeights: map[string]float32{"insert_module": 1e6}},
+		ModuleConfig:      &ModuleConfig{MineEvery: 1, EliteCount: 1, MinLength: 2, MaxLength: 2, MinSupport: 2, MaxModules: 1, Chance: 1},
+	}
+	for _, mode := range []MutationMode{MUTATION_FREE, MUTATION_SYNTACTIC} {
+		uc.MutationMode = mode
+		mu := NewUnitMutator(uc, &EvaluatorConfig{})
+		if strings.IndexByte(string(mu.MetaOps), INSERT_MODULE_OP) < 0 {
+			t.Fatalf("%s Mutator mining modules lacks insert_module", mode)
+		}
+
+		// Without modules insert_module isn't drawn, whatever it weighs
+		for n := 0; n < 100; n++ {
+			if m := mu.NewMutation(1); m.MetaOP == INSERT_MODULE_OP {
+				t.Fatalf("%s Mutator drew %v without modules", mode, m)
+			}
+		}
+
+		mu.UseModules([]Module{{Ops: "[>]", Support: 2}}, uc.ModuleConfig.Chance)
+		inserted := 0
+		for n := 0; n < 100; n++ {
+			gene := NewInstruction("+-+-")
+			m := mu.NewMutation(1)
+			if m.MetaOP != INSERT_MODULE_OP {
+				continue
+			}
+			inserted++
+			m.Apply(gene)
+			if m.Position1 == nil {
+				t.Fatalf("%s mutation %v did not insert its module", mode, m)
+			}
+			at := *m.Position1
+			if program := string(gene.ToProgram()); program != "+-+-"[:at]+"[>]"+"+-+-"[at:] {
+				t.Fatalf("%s mutation %v made [%s] from [+-+-]", mode, m, program)
+			}
+		}
+
+		if inserted == 0 {
+			t.Errorf("%s Mutator with modules never drew insert_module weighing 1e6", mode)
+		}
+
+		// Fresh genes are modules with the module chance
+		if program := string(mu.NewInstruction(3).ToProgram()); program != "[>][>][>]" {
+			t.Errorf("%s Mutator drew fresh gene [%s] with module chance 1", mode, program)
+		}
+	}
+}
+
+func TestPromoteModules(t *test.T) {
+	db, persist := setupReproducerTestDB(t)
+	defer db.Close()
+	rng = newPooledRand(42)
+
+	pop := insertReproducerTestPopulation(t, db, 5)
+	pop.persist = persist
+	pop.PopulationConfig.UnitConfig.ModuleConfig = &ModuleConfig{
+		MineEvery: 1, EliteCount: 2, MinLength: 2, MaxLength: 4, MinSupport: 2, MaxModules: 3,
+	}
+	units := seedUnitsForReproduction(t, db, pop.ID, 5, persist.UnitIDs, persist.InstructionIDs)
+
+	// The seeded units are better the later they were seeded
+	programs, err := pop.queryElitePrograms(NewFitnessRanker(nil), 2)
+	if err != nil {
+		t.Fatalf("queryElitePrograms returned error: %v", err)
+	}
+	expected := [][]byte{
+		[]byte(Instructions(units[4].Instructions).ToProgram()),
+		[]byte(Instructions(units[3].Instructions).ToProgram()),
+	}
+	if !reflect.DeepEqual(programs, expected) {
+		t.Errorf("Elite programs are %q, expected %q", programs, expected)
+	}
+
+	if err := pop.PromoteModules([][]byte{[]byte("+[-]+"), []byte("-[-]-")}); err != nil {
+		t.Fatalf("PromoteModules returned error: %v", err)
+	}
+	if err := pop.PromoteModules([][]byte{[]byte(">[<]>"), []byte("<[<]<")}); err != nil {
+		t.Fatalf("PromoteModules returned error: %v", err)
+	}
+
+	// Mining nothing keeps the library
+	if err := pop.PromoteModules([][]byte{[]byte("+-+-")}); err != nil {
+		t.Fatalf("PromoteModules returned error: %v", err)
+	}
+
+	// The latest library replaces the one before it, and loads back
+	loaded, err := persist.LoadShallow(pop.ID)
+	if err != nil {
+		t.Fatalf("LoadShallow returned error: %v", err)
+	}
+	if want := []Module{{Ops: "[<]", Support: 2}}; !reflect.DeepEqual(loaded.Modules, want) || !reflect.DeepEqual(pop.Modules, want) {
+		t.Errorf("Population modules are %v, loaded %v, expected %v", pop.Modules, loaded.Modules, want)
+	}
+}
@@ -1,7 +1,11 @@
 package genetic_sort
 
 import (
+	"bytes"
 	"fmt"
+	"log"
+	"math"
+	str "strings"
 
 	bf "nickandperla.net/brainfuck"
 )
@@ -18,6 +22,41 @@ var META_OP_SET = []byte{
 	META_NO_OP,
 }
 
+// metaOpNames names the meta ops in configs and reports.
+var metaOpNames = map[byte]string{
+	PUSH_OP:    "push",
+	POP_OP:     "pop",
+	SHIFT_OP:   "shift",
+	UNSHIFT_OP: "unshift",
+	INSERT_OP:  "insert",
+	DELETE_OP:  "delete",
+	SWAP_OP:    "swap",
+	REPLACE_OP: "replace",
+	META_NO_OP: "no_op",
+	WRAP_OP:    "wrap",
+	UNWRAP_OP:  "unwrap",
+
+	INSERT_MODULE_OP: "insert_module",
+}
+
+// MetaOpName returns the config name of a meta op.
+func MetaOpName(metaOp byte) string {
+	if name, ok := metaOpNames[metaOp]; ok {
+		return name
+	}
+	return fmt.Sprintf("MetaOp(%d)", metaOp)
+}
+
+// MutationConfig weighs how likely a Mutation is to draw each meta op, by
+// name, and each op it adds, by symbol, and how likely a GeneMutation is to
+// draw each gene meta op. Anything left out weighs 1, so an empty config
+// draws uniformly like no config at all.
+type MutationConfig struct {
+	MetaOpWeights     map[string]float32 `toml:"meta_ops" json:"meta_ops,omitempty"`
+	OpWeights         map[string]float32 `toml:"ops" json:"ops,omitempty"`
+	GeneMetaOpWeights map[string]float32 `toml:"gene_meta_ops" json:"gene_meta_ops,omitempty"`
+}
+
 type Mutation struct {
 	ID            uint
 	InstructionID uint
@@ -26,17 +65,239 @@ type Mutation struct {
 	MetaOP        byte
 	Op            byte
 	Chance        float32
+	Module        []byte // what an INSERT_MODULE_OP inserts. Not persisted
+	syntactic     bool   // applied in MUTATION_SYNTACTIC mode
 }
 
 func (m *Mutation) String() string {
 	return fmt.Sprintf("{Position1: %v, Position2: %v, MetaOP: %v, Op: %v, Chance: %v}", m.Position1, m.Position2, m.MetaOP, m.Op, m.Chance)
 }
 
+// A Mutator draws new genetic material, single ops for Mutations and prefabs
+// for new Instructions, from the dialect of the Machine a population runs on.
+type Mutator struct {
+	Ops     []byte
+	Prefabs []string
+	MetaOps []byte
+	Mode    MutationMode
+	// GeneMutationChance is the chance a growing child takes a GeneMutation.
+	// Fresh genes string together GeneOpSetCount prefabs.
+	GeneMutationChance float32
+	GeneOpSetCount     int
+	// Adaptation drifts children's MutationChance, nil keeps their parents'
+	Adaptation *ChanceAdaptation
+	// Modules mined from the population's elite. Each prefab of a fresh
+	// gene is one of them with ModuleChance, and INSERT_MODULE_OP inserts
+	// them.
+	Modules      []string
+	ModuleChance float32
+
+	metaOpWeights     weights // of MetaOps
+	opWeights         weights // of Ops
+	geneMetaOpWeights weights // of GENE_META_OP_SET
+	prefabWeights     weights // of Prefabs
+}
+
+// weights are the odds of drawing each of a set of choices. nil weights
+// draw uniformly.
+type weights []float32
+
+// draw picks the index of a choice out of n.
+func (w weights) draw(n int) int {
+	return w.drawExcept(n, -1)
+}
+
+// drawExcept picks the index of a choice out of n as if choice skip weighed
+// 0. It picks skip only when every other choice weighs 0.
+func (w weights) drawExcept(n, skip int) int {
+	if w == nil {
+		if skip < 0 || n == 1 {
+			return rng.Intn(n)
+		}
+		i := rng.Intn(n - 1)
+		if i >= skip {
+			i++
+		}
+		return i
+	}
+	var total float32
+	for i, weight := range w {
+		if i != skip {
+			total += weight
+		}
+	}
+	if total == 0 {
+		return skip
+	}
+	x := rng.Float32() * total
+	last := 0
+	for i, weight := range w {
+		if weight == 0 || i == skip {
+			continue
+		}
+		if x < weight {
+			return i
+		}
+		x -= weight
+		last = i
+	}
+	// Rounding left x just past the last choice
+	return last
+}
+
+// newWeights looks up the weight of each choice in named, by its name, with
+// 1 for choices it leaves out. what names the choices in errors.
+func newWeights(what string, named map[string]float32, names []string) (weights, error) {
+	if named == nil {
+		return nil, nil
+	}
+	w := make(weights, len(names))
+	known := make(map[string]bool, len(names))
+	var total float32
+	for i, name := range names {
+		known[name] = true
+		w[i] = 1
+		if weight, ok := named[name]; ok {
+			w[i] = weight
+		}
+		if !(w[i] >= 0 && w[i] <= math.MaxFloat32) {
+			return nil, fmt.Errorf("The %s [%s] weighs [%v]. Weights must be finite and not negative", what, name, w[i])
+		}
+		total += w[i]
+	}
+	for name := range named {
+		if !known[name] {
+			return nil, fmt.Errorf("Unknown %s [%s]. Expected one of %s", what, name, str.Join(names, ", "))
+		}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("Every %s weighs 0", what)
+	}
+	if total > math.MaxFloat32 {
+		// draw would scale by +Inf
+		return nil, fmt.Errorf("The %s weights add up to [%v]. Their total must be finite", what, total)
+	}
+	return w, nil
+}
+
+// The dialect of the default MachineConfig. Used wherever no Mutator is
+// given.
+var defaultMutator = NewMutator(nil)
+
+// NewMutator draws from the ops the bf op registry enables for mc. nil means
+// the default MachineConfig.
+func NewMutator(mc *bf.MachineConfig) *Mutator {
+	return &Mutator{
+		Ops:            bf.OpSet(mc),
+		Prefabs:        bf.PrefabOpSets(mc),
+		MetaOps:        META_OP_SET,
+		GeneOpSetCount: 1,
+	}
+}
+
+// Weigh makes mu draw meta ops and ops with the weights of config. nil draws
+// uniformly. It fails on names config has that mu doesn't draw from.
+func (mu *Mutator) Weigh(config *MutationConfig) error {
+	if config == nil {
+		mu.metaOpWeights, mu.opWeights, mu.geneMetaOpWeights = nil, nil, nil
+		return nil
+	}
+	metaOps := make([]string, len(mu.MetaOps))
+	for i, metaOp := range mu.MetaOps {
+		metaOps[i] = MetaOpName(metaOp)
+	}
+	metaOpWeights, err := newWeights("meta op", config.MetaOpWeights, metaOps)
+	if err != nil {
+		return err
+	}
+	ops := make([]string, len(mu.Ops))
+	for i, op := range mu.Ops {
+		ops[i] = string(op)
+	}
+	opWeights, err := newWeights("op", config.OpWeights, ops)
+	if err != nil {
+		return err
+	}
+	geneMetaOps := make([]string, len(GENE_META_OP_SET))
+	for i, metaOp := range GENE_META_OP_SET {
+		geneMetaOps[i] = GeneMetaOpName(metaOp)
+	}
+	geneMetaOpWeights, err := newWeights("gene meta op", config.GeneMetaOpWeights, geneMetaOps)
+	if err != nil {
+		return err
+	}
+	mu.metaOpWeights, mu.opWeights, mu.geneMetaOpWeights = metaOpWeights, opWeights, geneMetaOpWeights
+	return nil
+}
+
+// NewUnitMutator returns the Mutator of a population's units: it draws from
+// the dialect of ec's machine, or uc's prefab library, in the mode and with
+// the weights, gene mutation chance and chance adaptation of uc. When uc
+// mines modules its Mutations may insert them, once UseModules stocks it.
+func NewUnitMutator(uc *UnitConfig, ec *EvaluatorConfig) *Mutator {
+	mu, err := uc.InstructionConfig.Mutator(ec.MachineConfig)
+	if err != nil {
+		log.Fatalf("Bad prefab library. %v", err)
+	}
+	mu.SetMode(uc.MutationMode)
+	if uc.ModuleConfig != nil {
+		if err := uc.ModuleConfig.Check(); err != nil {
+			log.Fatalf("Bad module mining config. %v", err)
+		}
+		mu.MetaOps = append(append([]byte{}, mu.MetaOps...), INSERT_MODULE_OP)
+		mu.UseModules(nil, uc.ModuleConfig.Chance)
+	}
+	mu.GeneMutationChance = uc.GeneMutationChance
+	adaptation, err := uc.ChanceAdaptation()
+	if err != nil {
+		log.Fatalf("Bad mutation chance adaptation. %v", err)
+	}
+	mu.Adaptation = adaptation
+	if err := mu.Weigh(uc.MutationConfig); err != nil {
+		log.Fatalf("Bad mutation weights. %v", err)
+	}
+	return mu
+}
+
+// ChanceAdaptation makes a unit's MutationChance a trait its offspring
+// inherit with variation, so selection can tune it: each child's chance is
+// its parents' times e^(Step*N(0,1)), kept inside [Min, Max].
+type ChanceAdaptation struct {
+	Step float32
+	Min  float32
+	Max  float32
+}
+
+// Adapt returns a child's mutation chance given its parents'.
+func (a *ChanceAdaptation) Adapt(chance float32) float32 {
+	chance = float32(float64(chance) * math.Exp(float64(a.Step)*rng.NormFloat64()))
+	if chance < a.Min {
+		return a.Min
+	}
+	if chance > a.Max {
+		return a.Max
+	}
+	return chance
+}
+
 func NewMutation(chance float32) *Mutation {
+	return defaultMutator.NewMutation(chance)
+}
+
+func (mu *Mutator) NewMutation(chance float32) *Mutation {
+	skip := -1
+	if len(mu.Modules) == 0 {
+		// Nothing to insert yet
+		skip = bytes.IndexByte(mu.MetaOps, INSERT_MODULE_OP)
+	}
 	m := &Mutation{
-		MetaOP: META_OP_SET[rng.Intn(len(META_OP_SET))],
-		Op:     bf.OP_SET[rng.Intn(len(bf.OP_SET))],
-		Chance: chance,
+		MetaOP:    mu.MetaOps[mu.metaOpWeights.drawExcept(len(mu.MetaOps), skip)],
+		Op:        mu.Ops[mu.opWeights.draw(len(mu.Ops))],
+		Chance:    chance,
+		syntactic: mu.Mode == MUTATION_SYNTACTIC,
+	}
+	if m.MetaOP == INSERT_MODULE_OP && len(mu.Modules) > 0 {
+		m.Module = []byte(mu.Modules[rng.Intn(len(mu.Modules))])
 	}
 	return m
 }
@@ -52,6 +313,10 @@ func (m *Mutation) Apply(i *Instruction) {
 		i.Mutations = append(i.Mutations, m)
 		return
 	}
+	if m.syntactic {
+		m.applySyntactic(i)
+		return
+	}
 	pos1, pos2 := uint(rng.Intn(length)), uint(rng.Intn(length))
 
 	switch m.MetaOP {
@@ -85,6 +350,11 @@ func (m *Mutation) Apply(i *Instruction) {
 	case REPLACE_OP:
 		raw[pos1] = m.Op
 		m.Position1 = &pos1
+	case INSERT_MODULE_OP:
+		if m.Module != nil {
+			raw = insertModule(raw, pos1, m.Module)
+			m.Position1 = &pos1
+		}
 	}
 
 	i.cachedOps = raw
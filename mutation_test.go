@@ -1 +1,302 @@
 package genetic_sort
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	bf "nickandperla.net/brainfuck"
+)
+
+func TestMutatorDialect(t *testing.T) {
+	single := NewMutator(&bf.MachineConfig{BookmarkRegisters: 1})
+	multi := NewMutator(&bf.MachineConfig{BookmarkRegisters: 2})
+
+	for n := 0; n < 500; n++ {
+		if op := single.NewMutation(1).Op; op == bf.OP_NEXT_BOOKMARK || op == bf.OP_FIRST_BOOKMARK {
+			t.Fatalf("Single register Mutator drew register selecting op [%c]", op)
+		}
+		if ins := single.NewInstruction(2).ToProgram(); bytes.ContainsAny(ins, "&@") {
+			t.Fatalf("Single register Mutator drew a multi register prefab: %s", ins)
+		}
+	}
+
+	var selects bool
+	for n := 0; n < 500 && !selects; n++ {
+		selects = strings.ContainsAny(string(multi.NewInstruction(2).ToProgram()), "&@")
+	}
+	if !selects {
+		t.Errorf("Multi register Mutator never drew a multi register prefab")
+	}
+
+	if !bytes.Equal(NewMutator(nil).Ops, single.Ops) {
+		t.Errorf("NewMutator(nil) drew from %s, expected %s", NewMutator(nil).Ops, single.Ops)
+	}
+}
+
+func TestMutatorWeights(t *testing.T) {
+	rng = newPooledRand(42)
+
+	mu := NewMutator(nil)
+	zero := map[string]float32{}
+	for _, metaOp := range META_OP_SET {
+		zero[MetaOpName(metaOp)] = 0
+	}
+	zero["replace"] = 1
+	ops := map[string]float32{}
+	for _, op := range mu.Ops {
+		ops[string(op)] = 0
+	}
+	ops["+"], ops["-"] = 3, 1
+	if err := mu.Weigh(&MutationConfig{MetaOpWeights: zero, OpWeights: ops}); err != nil {
+		t.Fatalf("Weigh failed: %v", err)
+	}
+
+	counts := map[byte]int{}
+	for n := 0; n < 4000; n++ {
+		m := mu.NewMutation(1)
+		if m.MetaOP != REPLACE_OP {
+			t.Fatalf("Drew meta op [%s] weighing 0", MetaOpName(m.MetaOP))
+		}
+		counts[m.Op]++
+	}
+	if len(counts) != 2 || counts['+'] < 2700 || counts['+'] > 3300 {
+		t.Errorf("Drew ops %v, expected about 3000 + and 1000 -", counts)
+	}
+
+	// Weights reach Mitosis through its Mutator
+	u := &Unit{MutationChance: 1, Instructions: []*Instruction{NewInstruction("><><"), NewInstruction("<><>")}}
+	for _, gene := range u.Mitosis(mu, nil, nil).Instructions {
+		for _, m := range gene.Mutations {
+			if m.MetaOP != REPLACE_OP || (m.Op != '+' && m.Op != '-') {
+				t.Errorf("Mitosis applied mutation %v, outside of the weights", m)
+			}
+		}
+		if len(gene.Mutations) != 1 || !bytes.ContainsAny(gene.ToProgram(), "+-") {
+			t.Errorf("Mitosis gene [%s] did not get its replace mutation", gene.ToProgram())
+		}
+	}
+
+	// Unweighted meta ops and ops still weigh 1
+	if err := mu.Weigh(&MutationConfig{MetaOpWeights: map[string]float32{"push": 0}}); err != nil {
+		t.Fatalf("Weigh failed: %v", err)
+	}
+	for n := 0; n < 500; n++ {
+		if m := mu.NewMutation(1); m.MetaOP == PUSH_OP {
+			t.Fatalf("Drew meta op [push] weighing 0")
+		}
+	}
+
+	bad := []*MutationConfig{
+		{MetaOpWeights: map[string]float32{"explode": 1}},
+		{OpWeights: map[string]float32{"%": 1}}, // not in the default dialect
+		{OpWeights: map[string]float32{"+": -1}},
+		{MetaOpWeights: zero, OpWeights: map[string]float32{"+": float32(math.Inf(1))}},
+		{OpWeights: map[string]float32{"+": math.MaxFloat32, "-": math.MaxFloat32}},
+		{MetaOpWeights: map[string]float32{"push": 0, "pop": 0, "shift": 0, "unshift": 0, "insert": 0,
+			"delete": 0, "swap": 0, "replace": 0, "no_op": 0}},
+	}
+	for _, config := range bad {
+		if err := mu.Weigh(config); err == nil {
+			t.Errorf("Weigh accepted %+v", config)
+		}
+	}
+}
+
+func TestPrefabLibrary(t *testing.T) {
+	rng = newPooledRand(42)
+
+	mu := NewMutator(nil)
+	prefabs := map[string]string{"clear": "[-]", "bump": "+>", "never": "<"}
+	if err := mu.UsePrefabs(prefabs, map[string]float32{"clear": 3, "never": 0}); err != nil {
+		t.Fatalf("UsePrefabs failed: %v", err)
+	}
+
+	counts := map[string]int{}
+	for n := 0; n < 4000; n++ {
+		counts[string(mu.NewInstruction(1).ToProgram())]++
+	}
+	if len(counts) != 2 || counts["[-]"] < 2700 || counts["[-]"] > 3300 {
+		t.Errorf("Drew prefabs %v, expected about 3000 [-] and 1000 +>", counts)
+	}
+
+	// A population's units and fresh genes draw from its library
+	uc := &UnitConfig{
+		InstructionCount:  3,
+		InstructionConfig: &InstructionConfig{OpSetCount: 2, Prefabs: map[string]string{"clear": "[-]"}},
+	}
+	unit := NewUnitMutator(uc, &EvaluatorConfig{}).NewUnit(uc)
+	if program := Instructions(unit.Instructions).ToProgram(); program != strings.Repeat("[-]", 6) {
+		t.Errorf("Unit drew [%s] from a library of [-]", program)
+	}
+
+	// Without a library the dialect's prefabs stay
+	if err := mu.UsePrefabs(nil, nil); err != nil {
+		t.Fatalf("UsePrefabs(nil, nil) failed: %v", err)
+	}
+
+	bad := []struct {
+		prefabs map[string]string
+		weights map[string]float32
+	}{
+		{map[string]string{}, nil},
+		{map[string]string{"empty": ""}, nil},
+		{map[string]string{"open": "[-"}, nil},
+		{map[string]string{"backwards": "]+["}, nil},
+		{map[string]string{"bookmark": "&"}, nil}, // not in the default dialect
+		{map[string]string{"clear": "[-]"}, map[string]float32{"missing": 1}},
+		{map[string]string{"clear": "[-]"}, map[string]float32{"clear": 0}},
+		{nil, map[string]float32{"clear": 1}},
+	}
+	for _, library := range bad {
+		if err := NewMutator(nil).UsePrefabs(library.prefabs, library.weights); err == nil {
+			t.Errorf("UsePrefabs accepted %v weighed %v", library.prefabs, library.weights)
+		}
+	}
+}
+
+func TestGeneMutations(t *testing.T) {
+	rng = newPooledRand(42)
+
+	programs := func(genes []*Instruction) []string {
+		ops := make([]string, len(genes))
+		for i, gene := range genes {
+			ops[i] = string(gene.ToProgram())
+		}
+		return ops
+	}
+	parent := []string{">", ">>", ">>>", ">>>>"}
+
+	for _, metaOp := range GENE_META_OP_SET {
+		for trial := 0; trial < 50; trial++ {
+			u := &Unit{ID: 9}
+			for _, ops := range parent {
+				u.Instructions = append(u.Instructions, NewInstruction(ops))
+			}
+			m := &GeneMutation{MetaOP: metaOp}
+			m.Apply(u, defaultMutator)
+			got := programs(u.Instructions)
+
+			if len(u.GeneMutations) != 1 || u.GeneMutations[0] != m {
+				t.Fatalf("%s was not recorded on the unit: %v", GeneMetaOpName(metaOp), u.GeneMutations)
+			}
+
+			// Undo the mutation by its recorded positions and expect the parent
+			var undone []string
+			switch metaOp {
+			case DUPLICATE_GENE_OP:
+				if got[*m.Position2] != parent[*m.Position1] {
+					t.Fatalf("Duplicate put [%s] at [%d], expected a copy of gene [%d] %v", got[*m.Position2], *m.Position2, *m.Position1, got)
+				}
+				undone = append(append(undone, got[:*m.Position2]...), got[*m.Position2+1:]...)
+			case DELETE_GENE_OP:
+				undone = append(append(append(undone, got[:*m.Position1]...), parent[*m.Position1]), got[*m.Position1:]...)
+			case INSERT_GENE_OP:
+				if m.Position1 != nil {
+					t.Fatalf("Insert recorded a source gene [%d]", *m.Position1)
+				}
+				undone = append(append(undone, got[:*m.Position2]...), got[*m.Position2+1:]...)
+			case MOVE_GENE_OP:
+				if got[*m.Position2] != parent[*m.Position1] {
+					t.Fatalf("Move put [%s] at [%d], expected gene [%d] %v", got[*m.Position2], *m.Position2, *m.Position1, got)
+				}
+				rest := append(append([]string{}, got[:*m.Position2]...), got[*m.Position2+1:]...)
+				undone = append(append(append(undone, rest[:*m.Position1]...), got[*m.Position2]), rest[*m.Position1:]...)
+			}
+			if strings.Join(undone, " ") != strings.Join(parent, " ") {
+				t.Fatalf("%s %v turned %v into %v", GeneMetaOpName(metaOp), m, parent, got)
+			}
+		}
+	}
+
+	// A unit's only gene is never deleted or moved
+	for _, metaOp := range []byte{DELETE_GENE_OP, MOVE_GENE_OP} {
+		u := &Unit{Instructions: []*Instruction{NewInstruction("+")}}
+		m := &GeneMutation{MetaOP: metaOp}
+		m.Apply(u, defaultMutator)
+		if len(u.Instructions) != 1 || m.Position1 != nil || m.Position2 != nil || len(u.GeneMutations) != 1 {
+			t.Errorf("%s of an only gene left %d genes, positions [%v %v]", GeneMetaOpName(metaOp), len(u.Instructions), m.Position1, m.Position2)
+		}
+	}
+
+	// Mitosis applies them with the Mutator's chance, weighed by its config
+	mu := NewUnitMutator(&UnitConfig{
+		GeneMutationChance: 1,
+		InstructionConfig:  &InstructionConfig{OpSetCount: 3},
+		MutationConfig:     &MutationConfig{GeneMetaOpWeights: map[string]float32{"duplicate": 0, "delete": 0, "move": 0}},
+	}, &EvaluatorConfig{})
+	u := &Unit{ID: 1, Instructions: []*Instruction{NewInstruction("+")}}
+	unitIDs := NewIDGenerator(0)
+	child := u.Mitosis(mu, unitIDs, nil)
+	if len(child.Instructions) != 2 || len(child.GeneMutations) != 1 || child.GeneMutations[0].MetaOP != INSERT_GENE_OP {
+		t.Fatalf("Mitosis child has %d genes and gene mutations %v, expected an inserted gene", len(child.Instructions), child.GeneMutations)
+	}
+	if child.GeneMutations[0].UnitID != child.ID {
+		t.Errorf("Gene mutation unit [%d] is not the child [%d]", child.GeneMutations[0].UnitID, child.ID)
+	}
+	if len(u.Instructions) != 1 || u.GeneMutations != nil {
+		t.Errorf("Mitosis changed the parent's genes")
+	}
+	if grandchild := child.Mitosis(nil, nil, nil); grandchild.GeneMutations != nil {
+		t.Errorf("Grandchild inherited gene mutations %v", grandchild.GeneMutations)
+	}
+}
+
+func TestChanceAdaptation(t *testing.T) {
+	rng = newPooledRand(42)
+
+	uc := &UnitConfig{
+		InstructionConfig:   &InstructionConfig{OpSetCount: 1},
+		AdaptMutationChance: true,
+		MutationChanceStep:  0.3,
+		MutationChanceMin:   0.05,
+		MutationChanceMax:   0.4,
+	}
+	mu := NewUnitMutator(uc, &EvaluatorConfig{})
+	if mu.Adaptation == nil {
+		t.Fatalf("Adapting unit config gave no adaptation")
+	}
+
+	// Log-normal steps are even on a log scale around the parent's chance
+	parent := &Unit{MutationChance: 0.2, Instructions: []*Instruction{NewInstruction("+-")}}
+	var up, down, clamped int
+	for n := 0; n < 2000; n++ {
+		child := parent.Mitosis(mu, nil, nil)
+		switch c := child.MutationChance; {
+		case c < uc.MutationChanceMin || c > uc.MutationChanceMax:
+			t.Fatalf("Child mutation chance [%v] left [%v, %v]", c, uc.MutationChanceMin, uc.MutationChanceMax)
+		case c == uc.MutationChanceMin || c == uc.MutationChanceMax:
+			clamped++
+		case c > parent.MutationChance:
+			up++
+		case c < parent.MutationChance:
+			down++
+		}
+	}
+	if parent.MutationChance != 0.2 {
+		t.Errorf("Mitosis changed the parent's mutation chance to [%v]", parent.MutationChance)
+	}
+	if up < 800 || down < 800 || clamped == 0 {
+		t.Errorf("Children's mutation chances went up %d, down %d and hit a bound %d times", up, down, clamped)
+	}
+
+	// Without adaptation children keep their parent's chance
+	if child := parent.Mitosis(nil, nil, nil); child.MutationChance != parent.MutationChance {
+		t.Errorf("Child mutation chance [%v] is not its parent's [%v]", child.MutationChance, parent.MutationChance)
+	}
+
+	bad := []UnitConfig{
+		{AdaptMutationChance: true, MutationChanceStep: -1, MutationChanceMax: 1},
+		{AdaptMutationChance: true, MutationChanceMin: 0.5, MutationChanceMax: 0.1},
+		{AdaptMutationChance: true, MutationChanceMax: 2},
+	}
+	for _, config := range bad {
+		if _, err := config.ChanceAdaptation(); err == nil {
+			t.Errorf("ChanceAdaptation accepted %+v", config)
+		}
+	}
+	if a, err := (&UnitConfig{MutationChanceStep: 0.3}).ChanceAdaptation(); a != nil || err != nil {
+		t.Errorf("Unit config without adaptation gave adaptation %v, error %v", a, err)
+	}
+}
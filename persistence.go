@@ -2,6 +2,7 @@ package genetic_sort
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"path/filepath"
@@ -164,14 +165,38 @@ func (p *Persistence) createSchema() error {
 			unit_instruction_count INTEGER,
 			unit_ins_op_set_count INTEGER,
 			unit_lifespan INTEGER,
+			unit_crossover_rate REAL DEFAULT 0,
+			unit_crossover INTEGER DEFAULT 0,
+			unit_mutation TEXT,
+			unit_gene_mutation_chance REAL DEFAULT 0,
+			unit_adapt_mutation_chance INTEGER DEFAULT 0,
+			unit_mutation_chance_step REAL DEFAULT 0,
+			unit_mutation_chance_min REAL DEFAULT 0,
+			unit_mutation_chance_max REAL DEFAULT 0,
+			unit_mutation_mode INTEGER DEFAULT 0,
+			unit_ins_prefabs TEXT,
+			unit_ins_prefab_weights TEXT,
+			unit_modules TEXT,
 			eval_machine_max_instruction_execution_count INTEGER,
 			eval_machine_memory_cell_count INTEGER,
+			eval_machine_overflow_mode INTEGER DEFAULT 0,
+			eval_machine_cell_width INTEGER DEFAULT 0,
+			eval_machine_bookmark_registers INTEGER DEFAULT 0,
+			eval_machine_cmp_swap INTEGER DEFAULT 0,
+			eval_machine_max_loop_iterations INTEGER DEFAULT 0,
 			eval_input_cell_count INTEGER,
 			eval_output_cell_count INTEGER,
 			eval_synthesis_input_cell_count INTEGER,
 			eval_input_cell_start INTEGER,
 			eval_input_cell_step INTEGER,
 			eval_eval_rounds INTEGER,
+			eval_optimize INTEGER DEFAULT 0,
+			eval_backend INTEGER DEFAULT 0,
+			eval_input_offset INTEGER DEFAULT 0,
+			eval_output_offset INTEGER DEFAULT 0,
+			eval_scratch_offset INTEGER DEFAULT 0,
+			eval_scratch_cell_count INTEGER DEFAULT 0,
+			eval_read_only_input INTEGER DEFAULT 0,
 			sel_machine_run INTEGER,
 			sel_set_fidelity INTEGER,
 			sel_sortedness INTEGER,
@@ -181,6 +206,7 @@ func (p *Persistence) createSchema() error {
 			sel_sortedness_step INTEGER DEFAULT 0,
 			sel_instruction_count INTEGER,
 			sel_instructions_executed INTEGER,
+			sel_static_analysis INTEGER DEFAULT 0,
 			fit_sortedness_priority INTEGER,
 			fit_set_fidelity_priority INTEGER,
 			fit_efficiency_priority INTEGER
@@ -189,6 +215,7 @@ func (p *Persistence) createSchema() error {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			population_id INTEGER,
 			parent_id INTEGER,
+			second_parent_id INTEGER,
 			age INTEGER DEFAULT 0,
 			generation INTEGER DEFAULT 0,
 			lifespan INTEGER,
@@ -211,6 +238,38 @@ func (p *Persistence) createSchema() error {
 			op INTEGER,
 			chance REAL
 		)`,
+		`CREATE TABLE IF NOT EXISTS gene_mutations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			unit_id INTEGER,
+			meta_op INTEGER,
+			position1 INTEGER,
+			position2 INTEGER,
+			chance REAL
+		)`,
+		`CREATE TABLE IF NOT EXISTS mutation_effects (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			population_id INTEGER,
+			unit_id INTEGER,
+			parent_id INTEGER,
+			gene INTEGER DEFAULT 0,
+			meta_op INTEGER,
+			op INTEGER,
+			parent_machine_run INTEGER,
+			parent_sortedness INTEGER,
+			parent_set_fidelity INTEGER,
+			parent_instructions_executed INTEGER,
+			outcome INTEGER,
+			sortedness_delta INTEGER,
+			set_fidelity_delta INTEGER,
+			instructions_executed_delta INTEGER
+		)`,
+		`CREATE TABLE IF NOT EXISTS modules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			population_id INTEGER,
+			generation INTEGER,
+			ops TEXT,
+			support INTEGER
+		)`,
 		`CREATE TABLE IF NOT EXISTS evaluations (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			unit_id INTEGER,
@@ -220,6 +279,8 @@ func (p *Persistence) createSchema() error {
 			instruction_count INTEGER,
 			instructions_executed INTEGER,
 			machine_error TEXT,
+			machine_error_kind INTEGER DEFAULT 0,
+			machine_error_loop INTEGER,
 			input BLOB,
 			output BLOB
 		)`,
@@ -233,6 +294,10 @@ func (p *Persistence) createSchema() error {
 		`CREATE INDEX IF NOT EXISTS idx_evaluations_unit_id ON evaluations(unit_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_tombstones_unit_id ON tombstones(unit_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_mutations_instruction_id ON mutations(instruction_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_gene_mutations_unit_id ON gene_mutations(unit_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_mutation_effects_unit_id ON mutation_effects(unit_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_mutation_effects_pop_outcome ON mutation_effects(population_id, outcome)`,
+		`CREATE INDEX IF NOT EXISTS idx_modules_population_id ON modules(population_id)`,
 	}
 
 	for _, db := range p.Shards {
@@ -241,6 +306,101 @@ func (p *Persistence) createSchema() error {
 				return fmt.Errorf("schema creation failed: %w\nStatement: %s", err, stmt)
 			}
 		}
+		if err := migrateSchema(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schemaColumns lists columns added after their table was first released.
+// CREATE TABLE IF NOT EXISTS leaves existing databases alone, so these get
+// added to older databases by migrateSchema. The defaults must reproduce the
+// behavior from before the column existed.
+var schemaColumns = []struct {
+	table  string
+	column string
+	decl   string
+}{
+	{"populations", "eval_machine_overflow_mode", "INTEGER DEFAULT 0"},
+	{"populations", "eval_machine_cell_width", "INTEGER DEFAULT 0"},
+	{"populations", "eval_machine_bookmark_registers", "INTEGER DEFAULT 0"},
+	{"populations", "eval_machine_cmp_swap", "INTEGER DEFAULT 0"},
+	{"populations", "sel_static_analysis", "INTEGER DEFAULT 0"},
+	{"populations", "eval_optimize", "INTEGER DEFAULT 0"},
+	{"populations", "eval_backend", "INTEGER DEFAULT 0"},
+	{"populations", "eval_input_offset", "INTEGER DEFAULT 0"},
+	{"populations", "eval_output_offset", "INTEGER DEFAULT 0"},
+	{"populations", "eval_scratch_offset", "INTEGER DEFAULT 0"},
+	{"populations", "eval_scratch_cell_count", "INTEGER DEFAULT 0"},
+	{"populations", "eval_read_only_input", "INTEGER DEFAULT 0"},
+	{"evaluations", "machine_error_kind", "INTEGER DEFAULT 0"},
+	{"populations", "eval_machine_max_loop_iterations", "INTEGER DEFAULT 0"},
+	{"evaluations", "machine_error_loop", "INTEGER"},
+	{"units", "second_parent_id", "INTEGER"},
+	{"populations", "unit_crossover_rate", "REAL DEFAULT 0"},
+	{"populations", "unit_crossover", "INTEGER DEFAULT 0"},
+	{"populations", "unit_mutation", "TEXT"},
+	{"populations", "unit_gene_mutation_chance", "REAL DEFAULT 0"},
+	{"populations", "unit_adapt_mutation_chance", "INTEGER DEFAULT 0"},
+	{"populations", "unit_mutation_chance_step", "REAL DEFAULT 0"},
+	{"populations", "unit_mutation_chance_min", "REAL DEFAULT 0"},
+	{"populations", "unit_mutation_chance_max", "REAL DEFAULT 0"},
+	{"populations", "unit_mutation_mode", "INTEGER DEFAULT 0"},
+	{"populations", "unit_ins_prefabs", "TEXT"},
+	{"populations", "unit_ins_prefab_weights", "TEXT"},
+	{"populations", "unit_modules", "TEXT"},
+}
+
+// schemaBackfills update the rows a column was just added to, by
+// "table.column", where its default doesn't describe them. Errors recorded
+// before their kinds were can't be classified after the fact.
+var schemaBackfills = map[string]string{
+	"evaluations.machine_error_kind": fmt.Sprintf("UPDATE evaluations SET machine_error_kind = %d WHERE machine_error IS NOT NULL", bf.ERR_OTHER),
+}
+
+// migrateSchema adds any missing schemaColumns to an existing database.
+func migrateSchema(db *sql.DB) error {
+	existing := make(map[string]map[string]bool)
+	for _, sc := range schemaColumns {
+		cols, ok := existing[sc.table]
+		if !ok {
+			cols = make(map[string]bool)
+			rows, err := db.Query("SELECT name FROM pragma_table_info(?)", sc.table)
+			if err != nil {
+				return fmt.Errorf("failed to read columns of %s: %w", sc.table, err)
+			}
+			for rows.Next() {
+				var name string
+				if err := rows.Scan(&name); err != nil {
+					rows.Close()
+					return err
+				}
+				cols[name] = true
+			}
+			rows.Close()
+			existing[sc.table] = cols
+		}
+		if cols[sc.column] {
+			continue
+		}
+		stmts := []string{"ALTER TABLE " + sc.table + " ADD COLUMN " + sc.column + " " + sc.decl}
+		if backfill, ok := schemaBackfills[sc.table+"."+sc.column]; ok {
+			stmts = append(stmts, backfill)
+		}
+		// Together, so a failed backfill doesn't leave the column behind
+		err := withTx(db, func(tx *sql.Tx) error {
+			for _, stmt := range stmts {
+				if _, err := tx.Exec(stmt); err != nil {
+					return fmt.Errorf("schema migration failed: %w\nStatement: %s", err, stmt)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		cols[sc.column] = true
 	}
 	return nil
 }
@@ -283,6 +443,11 @@ func (p *Persistence) Create(config *PopulationConfig) (*Population, error) {
 	if config == nil {
 		return nil, fmt.Errorf("PopulationConfig cannot be nil")
 	}
+	if ec := config.EvaluatorConfig; ec != nil && ec.MachineConfig != nil {
+		if err := ec.MachineConfig.Check(); err != nil {
+			return nil, err
+		}
+	}
 
 	pop := NewPopulationFromConfig(config)
 	pop.ID = p.PopIDs.Next()
@@ -297,19 +462,28 @@ func (p *Persistence) Create(config *PopulationConfig) (*Population, error) {
 	return pop, nil
 }
 
+// populationSelect selects every populations column in the order
+// scanPopulation expects.
+const populationSelect = `SELECT id, current_generation,
+	unit_count, synthesis_pool, carrying_capacity, elitism, max_offspring,
+	unit_mutation_chance, unit_instruction_count, unit_ins_op_set_count, unit_lifespan,
+	unit_crossover_rate, unit_crossover, unit_mutation, unit_gene_mutation_chance,
+	unit_adapt_mutation_chance, unit_mutation_chance_step, unit_mutation_chance_min, unit_mutation_chance_max, unit_mutation_mode,
+	unit_ins_prefabs, unit_ins_prefab_weights, unit_modules,
+	eval_machine_max_instruction_execution_count, eval_machine_memory_cell_count, eval_machine_overflow_mode, eval_machine_cell_width,
+	eval_machine_bookmark_registers, eval_machine_cmp_swap, eval_machine_max_loop_iterations,
+	eval_input_cell_count, eval_output_cell_count, eval_synthesis_input_cell_count,
+	eval_input_cell_start, eval_input_cell_step, eval_eval_rounds, eval_optimize, eval_backend,
+	eval_input_offset, eval_output_offset, eval_scratch_offset, eval_scratch_cell_count, eval_read_only_input,
+	sel_machine_run, sel_set_fidelity, sel_sortedness,
+	sel_set_fidelity_start, sel_set_fidelity_step, sel_sortedness_start, sel_sortedness_step,
+	sel_instruction_count, sel_instructions_executed, sel_static_analysis,
+	fit_sortedness_priority, fit_set_fidelity_priority, fit_efficiency_priority
+	FROM populations`
+
 func (p *Persistence) LoadShallow(id uint) (*Population, error) {
 	pop := &Population{}
-	row := p.shard0().QueryRow(`SELECT id, current_generation,
-		unit_count, synthesis_pool, carrying_capacity, elitism, max_offspring,
-		unit_mutation_chance, unit_instruction_count, unit_ins_op_set_count, unit_lifespan,
-		eval_machine_max_instruction_execution_count, eval_machine_memory_cell_count,
-		eval_input_cell_count, eval_output_cell_count, eval_synthesis_input_cell_count,
-		eval_input_cell_start, eval_input_cell_step, eval_eval_rounds,
-		sel_machine_run, sel_set_fidelity, sel_sortedness,
-		sel_set_fidelity_start, sel_set_fidelity_step, sel_sortedness_start, sel_sortedness_step,
-		sel_instruction_count, sel_instructions_executed,
-		fit_sortedness_priority, fit_set_fidelity_priority, fit_efficiency_priority
-		FROM populations WHERE id = ?`, id)
+	row := p.shard0().QueryRow(populationSelect+" WHERE id = ?", id)
 
 	if err := scanPopulation(row, pop); err != nil {
 		if err == sql.ErrNoRows {
@@ -318,6 +492,11 @@ func (p *Persistence) LoadShallow(id uint) (*Population, error) {
 		return nil, fmt.Errorf("Failed to find population [%d]: %w", id, err)
 	}
 
+	modules, err := queryModules(p.shard0(), id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load modules of population [%d]: %w", id, err)
+	}
+	pop.Modules = modules
 	pop.persist = p
 	return pop, nil
 }
@@ -344,9 +523,9 @@ func (p *Persistence) SaveUnits(units []*Unit) error {
 
 	return p.writeSharded(units, func(tx *sql.Tx, batch []*Unit) error {
 		for _, u := range batch {
-			if _, err := tx.Exec(`INSERT INTO units (id, population_id, parent_id, age, generation, lifespan, mutation_chance, alive)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-				u.ID, u.PopulationID, nullableUint(u.ParentID), u.Age, u.Generation, u.Lifespan, u.MutationChance, u.Alive); err != nil {
+			if _, err := tx.Exec(`INSERT INTO units (id, population_id, parent_id, second_parent_id, age, generation, lifespan, mutation_chance, alive)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				u.ID, u.PopulationID, nullableUint(u.ParentID), nullableUint(u.SecondParentID), u.Age, u.Generation, u.Lifespan, u.MutationChance, u.Alive); err != nil {
 				return fmt.Errorf("failed to insert unit: %w", err)
 			}
 			for _, ins := range u.Instructions {
@@ -363,6 +542,12 @@ func (p *Persistence) SaveUnits(units []*Unit) error {
 					}
 				}
 			}
+			for _, mut := range u.GeneMutations {
+				if _, err := tx.Exec(`INSERT INTO gene_mutations (unit_id, meta_op, position1, position2, chance) VALUES (?, ?, ?, ?, ?)`,
+					u.ID, mut.MetaOP, nullableUint(mut.Position1), nullableUint(mut.Position2), mut.Chance); err != nil {
+					return fmt.Errorf("failed to insert gene mutation: %w", err)
+				}
+			}
 		}
 		return nil
 	})
@@ -392,11 +577,11 @@ func (p *Persistence) PersistEvaluatedBatch(units []*Unit) error {
 						machineRun = 1
 					}
 					if _, err := tx.Exec(`INSERT INTO evaluations (id, unit_id, machine_run, set_fidelity, sortedness,
-						instruction_count, instructions_executed, machine_error)
-						VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+						instruction_count, instructions_executed, machine_error, machine_error_kind, machine_error_loop)
+						VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 						e.ID, e.UnitID, machineRun, e.SetFidelity, e.Sortedness,
 						e.InstructionCount, e.InstructionsExecuted,
-						nullableString(e.MachineError)); err != nil {
+						nullableString(e.MachineError), e.MachineErrorKind, nullableUint(e.MachineErrorLoop)); err != nil {
 						return err
 					}
 				}
@@ -513,6 +698,47 @@ func placeholders(n int) string {
 	return strings.Repeat("?,", n-1) + "?"
 }
 
+// mutationConfigText stores a MutationConfig as JSON, or NULL for nil.
+func mutationConfigText(mc *MutationConfig) interface{} {
+	if mc == nil {
+		return nil
+	}
+	return jsonText("mutation config", mc)
+}
+
+// prefabsText stores a prefab library as JSON, or NULL for nil.
+func prefabsText(prefabs map[string]string) interface{} {
+	if prefabs == nil {
+		return nil
+	}
+	return jsonText("prefab library", prefabs)
+}
+
+// prefabWeightsText stores a prefab library's weights as JSON, or NULL for
+// nil.
+func prefabWeightsText(weights map[string]float32) interface{} {
+	if weights == nil {
+		return nil
+	}
+	return jsonText("prefab weights", weights)
+}
+
+// moduleConfigText stores a ModuleConfig as JSON, or NULL for nil.
+func moduleConfigText(mc *ModuleConfig) interface{} {
+	if mc == nil {
+		return nil
+	}
+	return jsonText("module config", mc)
+}
+
+func jsonText(what string, v interface{}) string {
+	text, err := json.Marshal(v)
+	if err != nil {
+		log.Fatalf("Failed to store %s. %v", what, err)
+	}
+	return string(text)
+}
+
 // nullableUint converts *uint to a value suitable for sql driver (nil or int64).
 func nullableUint(p *uint) interface{} {
 	if p == nil {
@@ -529,13 +755,13 @@ func nullableString(p *string) interface{} {
 	return *p
 }
 
-// bulkInsertUnits inserts units and their instructions using multi-row INSERT
+// bulkInsertUnits inserts units, their instructions and gene mutations using multi-row INSERT
 // statements for dramatically better throughput. Batches rows to avoid hitting
 // SQLite limits (max 500 variables per statement to stay safe).
 func bulkInsertUnits(tx *sql.Tx, units []*Unit) error {
 	// Bulk insert units in chunks
-	const unitCols = 8 // id, population_id, parent_id, age, generation, lifespan, mutation_chance, alive
-	const maxRowsPerStmt = 60 // 60 * 8 = 480 variables, under SQLite's limit
+	const unitCols = 9 // id, population_id, parent_id, second_parent_id, age, generation, lifespan, mutation_chance, alive
+	const maxRowsPerStmt = 55 // 55 * 9 = 495 variables, under SQLite's limit
 
 	for start := 0; start < len(units); start += maxRowsPerStmt {
 		end := start + maxRowsPerStmt
@@ -545,14 +771,14 @@ func bulkInsertUnits(tx *sql.Tx, units []*Unit) error {
 		chunk := units[start:end]
 
 		var sb strings.Builder
-		sb.WriteString("INSERT INTO units (id, population_id, parent_id, age, generation, lifespan, mutation_chance, alive) VALUES ")
+		sb.WriteString("INSERT INTO units (id, population_id, parent_id, second_parent_id, age, generation, lifespan, mutation_chance, alive) VALUES ")
 		args := make([]interface{}, 0, len(chunk)*unitCols)
 		for i, u := range chunk {
 			if i > 0 {
 				sb.WriteByte(',')
 			}
-			sb.WriteString("(?,?,?,?,?,?,?,?)")
-			args = append(args, u.ID, u.PopulationID, nullableUint(u.ParentID), u.Age, u.Generation, u.Lifespan, u.MutationChance, u.Alive)
+			sb.WriteString("(?,?,?,?,?,?,?,?,?)")
+			args = append(args, u.ID, u.PopulationID, nullableUint(u.ParentID), nullableUint(u.SecondParentID), u.Age, u.Generation, u.Lifespan, u.MutationChance, u.Alive)
 		}
 		if _, err := tx.Exec(sb.String(), args...); err != nil {
 			return fmt.Errorf("bulk insert units failed: %w", err)
@@ -590,6 +816,37 @@ func bulkInsertUnits(tx *sql.Tx, units []*Unit) error {
 		}
 	}
 
+	// Then their gene mutations, the lineage of their gene structure
+	const geneMutCols = 5 // unit_id, meta_op, position1, position2, chance
+	const maxGeneMutsPerStmt = 100 // 100 * 5 = 500 variables
+
+	var allGeneMuts []*GeneMutation
+	for _, u := range units {
+		allGeneMuts = append(allGeneMuts, u.GeneMutations...)
+	}
+
+	for start := 0; start < len(allGeneMuts); start += maxGeneMutsPerStmt {
+		end := start + maxGeneMutsPerStmt
+		if end > len(allGeneMuts) {
+			end = len(allGeneMuts)
+		}
+		chunk := allGeneMuts[start:end]
+
+		var sb strings.Builder
+		sb.WriteString("INSERT INTO gene_mutations (unit_id, meta_op, position1, position2, chance) VALUES ")
+		args := make([]interface{}, 0, len(chunk)*geneMutCols)
+		for i, mut := range chunk {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString("(?,?,?,?,?)")
+			args = append(args, mut.UnitID, mut.MetaOP, nullableUint(mut.Position1), nullableUint(mut.Position2), mut.Chance)
+		}
+		if _, err := tx.Exec(sb.String(), args...); err != nil {
+			return fmt.Errorf("bulk insert gene mutations failed: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -603,33 +860,59 @@ func populationInsertValues(pop *Population) ([]string, []interface{}) {
 	sc := c.SelectorConfig
 	fc := c.FitnessConfig
 
-	var selMachineRun int
+	var machineCmpSwap, evalOptimize, evalReadOnlyInput, selMachineRun, selStaticAnalysis int
+	var unitAdaptMutationChance int
+	if uc.AdaptMutationChance {
+		unitAdaptMutationChance = 1
+	}
+	if mc.CmpSwap {
+		machineCmpSwap = 1
+	}
+	if ec.Optimize {
+		evalOptimize = 1
+	}
+	if ec.ReadOnlyInput {
+		evalReadOnlyInput = 1
+	}
 	if sc.MachineRun {
 		selMachineRun = 1
 	}
+	if sc.StaticAnalysis {
+		selStaticAnalysis = 1
+	}
 
 	cols := []string{
 		"id", "current_generation",
 		"unit_count", "synthesis_pool", "carrying_capacity", "elitism", "max_offspring",
 		"unit_mutation_chance", "unit_instruction_count", "unit_ins_op_set_count", "unit_lifespan",
-		"eval_machine_max_instruction_execution_count", "eval_machine_memory_cell_count",
+		"unit_crossover_rate", "unit_crossover", "unit_mutation", "unit_gene_mutation_chance",
+		"unit_adapt_mutation_chance", "unit_mutation_chance_step", "unit_mutation_chance_min", "unit_mutation_chance_max", "unit_mutation_mode",
+		"unit_ins_prefabs", "unit_ins_prefab_weights", "unit_modules",
+		"eval_machine_max_instruction_execution_count", "eval_machine_memory_cell_count", "eval_machine_overflow_mode", "eval_machine_cell_width",
+		"eval_machine_bookmark_registers", "eval_machine_cmp_swap", "eval_machine_max_loop_iterations",
 		"eval_input_cell_count", "eval_output_cell_count", "eval_synthesis_input_cell_count",
-		"eval_input_cell_start", "eval_input_cell_step", "eval_eval_rounds",
+		"eval_input_cell_start", "eval_input_cell_step", "eval_eval_rounds", "eval_optimize", "eval_backend",
+		"eval_input_offset", "eval_output_offset", "eval_scratch_offset", "eval_scratch_cell_count", "eval_read_only_input",
 		"sel_machine_run", "sel_set_fidelity", "sel_sortedness",
 		"sel_set_fidelity_start", "sel_set_fidelity_step", "sel_sortedness_start", "sel_sortedness_step",
-		"sel_instruction_count", "sel_instructions_executed",
+		"sel_instruction_count", "sel_instructions_executed", "sel_static_analysis",
 		"fit_sortedness_priority", "fit_set_fidelity_priority", "fit_efficiency_priority",
 	}
 	vals := []interface{}{
 		pop.ID, pop.CurrentGeneration,
 		c.UnitCount, c.SynthesisPool, c.CarryingCapacity, c.Elitism, c.MaxOffspring,
 		uc.MutationChance, uc.InstructionCount, ic.OpSetCount, uc.Lifespan,
-		mc.MaxInstructionExecutionCount, mc.MemoryCellCount,
+		uc.CrossoverRate, uint8(uc.Crossover), mutationConfigText(uc.MutationConfig), uc.GeneMutationChance,
+		unitAdaptMutationChance, uc.MutationChanceStep, uc.MutationChanceMin, uc.MutationChanceMax, uint8(uc.MutationMode),
+		prefabsText(ic.Prefabs), prefabWeightsText(ic.PrefabWeights), moduleConfigText(uc.ModuleConfig),
+		mc.MaxInstructionExecutionCount, mc.MemoryCellCount, uint8(mc.OverflowMode), uint8(mc.CellWidth),
+		mc.BookmarkRegisters, machineCmpSwap, mc.MaxLoopIterations,
 		ec.InputCellCount, ec.OutputCellCount, ec.SynthesisInputCellCount,
-		ec.InputCellStart, ec.InputCellStep, ec.EvalRounds,
+		ec.InputCellStart, ec.InputCellStep, ec.EvalRounds, evalOptimize, uint8(ec.Backend),
+		ec.InputOffset, ec.OutputOffset, ec.ScratchOffset, ec.ScratchCellCount, evalReadOnlyInput,
 		selMachineRun, sc.SetFidelity, sc.Sortedness,
 		sc.SetFidelityStart, sc.SetFidelityStep, sc.SortednessStart, sc.SortednessStep,
-		sc.InstructionCount, sc.InstructionsExecuted,
+		sc.InstructionCount, sc.InstructionsExecuted, selStaticAnalysis,
 		fc.SortednessPriority, fc.SetFidelityPriority, fc.EfficiencyPriority,
 	}
 	return cols, vals
@@ -638,7 +921,7 @@ func populationInsertValues(pop *Population) ([]string, []interface{}) {
 // queryUnitsBatch returns up to `limit` alive units on this shard with id > afterID
 // and id <= maxID, ordered by id. Used for cursor-paginated streaming.
 func queryUnitsBatch(db *sql.DB, popID uint, afterID, maxID uint, limit int) ([]*Unit, error) {
-	rows, err := db.Query(`SELECT id, population_id, parent_id, age, generation, lifespan, mutation_chance, alive
+	rows, err := db.Query(`SELECT id, population_id, parent_id, second_parent_id, age, generation, lifespan, mutation_chance, alive
 		FROM units WHERE population_id = ? AND alive = ? AND id > ? AND id <= ?
 		ORDER BY id LIMIT ?`, popID, Alive, afterID, maxID, limit)
 	if err != nil {
@@ -649,14 +932,18 @@ func queryUnitsBatch(db *sql.DB, popID uint, afterID, maxID uint, limit int) ([]
 	var units []*Unit
 	for rows.Next() {
 		u := &Unit{}
-		var parentID sql.NullInt64
-		if err := rows.Scan(&u.ID, &u.PopulationID, &parentID, &u.Age, &u.Generation, &u.Lifespan, &u.MutationChance, &u.Alive); err != nil {
+		var parentID, secondParentID sql.NullInt64
+		if err := rows.Scan(&u.ID, &u.PopulationID, &parentID, &secondParentID, &u.Age, &u.Generation, &u.Lifespan, &u.MutationChance, &u.Alive); err != nil {
 			return nil, err
 		}
 		if parentID.Valid {
 			pid := uint(parentID.Int64)
 			u.ParentID = &pid
 		}
+		if secondParentID.Valid {
+			pid := uint(secondParentID.Int64)
+			u.SecondParentID = &pid
+		}
 		units = append(units, u)
 	}
 	return units, rows.Err()
@@ -740,16 +1027,16 @@ func queryAliveUnitIDs(db *sql.DB, popID uint) ([]uint, error) {
 	return ids, rows.Err()
 }
 
-// queryUnitParents returns a map of unitID → parentID for the given unit IDs on this shard.
-// Units with NULL parent_id are omitted from the result. Chunks the IN clause to stay
+// queryUnitParents returns a map of unitID → parent IDs for the given unit IDs on this shard,
+// the mate second for crossover children. Units with NULL parent_id are omitted from the result. Chunks the IN clause to stay
 // under SQLite's 999-variable limit.
-func queryUnitParents(db *sql.DB, unitIDs []uint) (map[uint]uint, error) {
+func queryUnitParents(db *sql.DB, unitIDs []uint) (map[uint][]uint, error) {
 	if len(unitIDs) == 0 {
 		return nil, nil
 	}
 
 	const chunkSize = 900
-	result := make(map[uint]uint, len(unitIDs))
+	result := make(map[uint][]uint, len(unitIDs))
 
 	for start := 0; start < len(unitIDs); start += chunkSize {
 		end := start + chunkSize
@@ -763,7 +1050,7 @@ func queryUnitParents(db *sql.DB, unitIDs []uint) (map[uint]uint, error) {
 			args[i] = id
 		}
 
-		query := "SELECT id, parent_id FROM units WHERE id IN (" + placeholders(len(chunk)) + ") AND parent_id IS NOT NULL"
+		query := "SELECT id, parent_id, second_parent_id FROM units WHERE id IN (" + placeholders(len(chunk)) + ") AND parent_id IS NOT NULL"
 		rows, err := db.Query(query, args...)
 		if err != nil {
 			return nil, err
@@ -772,11 +1059,15 @@ func queryUnitParents(db *sql.DB, unitIDs []uint) (map[uint]uint, error) {
 		for rows.Next() {
 			var id uint
 			var parentID uint
-			if err := rows.Scan(&id, &parentID); err != nil {
+			var secondParentID sql.NullInt64
+			if err := rows.Scan(&id, &parentID, &secondParentID); err != nil {
 				rows.Close()
 				return nil, err
 			}
-			result[id] = parentID
+			result[id] = []uint{parentID}
+			if secondParentID.Valid {
+				result[id] = append(result[id], uint(secondParentID.Int64))
+			}
 		}
 		if err := rows.Err(); err != nil {
 			rows.Close()
@@ -791,10 +1082,19 @@ func queryUnitParents(db *sql.DB, unitIDs []uint) (map[uint]uint, error) {
 // scanPopulation scans a row into a Population, reconstructing nested config structs.
 func scanPopulation(row *sql.Row, pop *Population) error {
 	var (
-		selMachineRun                              int
+		evalOptimize, selMachineRun                int
+		evalReadOnlyInput, unitAdaptMutationChance int
+		selStaticAnalysis, machineCmpSwap          int
 		unitCount, synthesisPool, carryingCapacity uint
 		elitism, maxOffspring                      uint
 		machineMaxExec, machineCellCount           uint
+		machineBookmarks, machineMaxLoopIterations uint
+		machineOverflowMode, machineCellWidth      uint8
+		evalBackend, unitCrossover                 uint8
+		unitMutationMode                           uint8
+		unitMutation                               sql.NullString
+		unitInsPrefabs, unitInsPrefabWeights       sql.NullString
+		unitModules                                sql.NullString
 	)
 	uc := &UnitConfig{InstructionConfig: &InstructionConfig{}}
 	ec := &EvaluatorConfig{}
@@ -805,23 +1105,65 @@ func scanPopulation(row *sql.Row, pop *Population) error {
 		&pop.ID, &pop.CurrentGeneration,
 		&unitCount, &synthesisPool, &carryingCapacity, &elitism, &maxOffspring,
 		&uc.MutationChance, &uc.InstructionCount, &uc.InstructionConfig.OpSetCount, &uc.Lifespan,
-		&machineMaxExec, &machineCellCount,
+		&uc.CrossoverRate, &unitCrossover, &unitMutation, &uc.GeneMutationChance,
+		&unitAdaptMutationChance, &uc.MutationChanceStep, &uc.MutationChanceMin, &uc.MutationChanceMax, &unitMutationMode,
+		&unitInsPrefabs, &unitInsPrefabWeights, &unitModules,
+		&machineMaxExec, &machineCellCount, &machineOverflowMode, &machineCellWidth,
+		&machineBookmarks, &machineCmpSwap, &machineMaxLoopIterations,
 		&ec.InputCellCount, &ec.OutputCellCount, &ec.SynthesisInputCellCount,
-		&ec.InputCellStart, &ec.InputCellStep, &ec.EvalRounds,
+		&ec.InputCellStart, &ec.InputCellStep, &ec.EvalRounds, &evalOptimize, &evalBackend,
+		&ec.InputOffset, &ec.OutputOffset, &ec.ScratchOffset, &ec.ScratchCellCount, &evalReadOnlyInput,
 		&selMachineRun, &sc.SetFidelity, &sc.Sortedness,
 		&sc.SetFidelityStart, &sc.SetFidelityStep, &sc.SortednessStart, &sc.SortednessStep,
-		&sc.InstructionCount, &sc.InstructionsExecuted,
+		&sc.InstructionCount, &sc.InstructionsExecuted, &selStaticAnalysis,
 		&fc.SortednessPriority, &fc.SetFidelityPriority, &fc.EfficiencyPriority,
 	)
 	if err != nil {
 		return err
 	}
 
+	uc.Crossover = CrossoverMode(unitCrossover)
+	uc.AdaptMutationChance = unitAdaptMutationChance != 0
+	uc.MutationMode = MutationMode(unitMutationMode)
+	if unitMutation.Valid {
+		uc.MutationConfig = &MutationConfig{}
+		if err := json.Unmarshal([]byte(unitMutation.String), uc.MutationConfig); err != nil {
+			return fmt.Errorf("bad unit_mutation: %w", err)
+		}
+	}
+	if unitInsPrefabs.Valid {
+		if err := json.Unmarshal([]byte(unitInsPrefabs.String), &uc.InstructionConfig.Prefabs); err != nil {
+			return fmt.Errorf("bad unit_ins_prefabs: %w", err)
+		}
+	}
+	if unitInsPrefabWeights.Valid {
+		if err := json.Unmarshal([]byte(unitInsPrefabWeights.String), &uc.InstructionConfig.PrefabWeights); err != nil {
+			return fmt.Errorf("bad unit_ins_prefab_weights: %w", err)
+		}
+	}
+	if unitModules.Valid {
+		uc.ModuleConfig = &ModuleConfig{}
+		if err := json.Unmarshal([]byte(unitModules.String), uc.ModuleConfig); err != nil {
+			return fmt.Errorf("bad unit_modules: %w", err)
+		}
+	}
+	ec.Optimize = evalOptimize != 0
+	ec.Backend = bf.Backend(evalBackend)
+	ec.ReadOnlyInput = evalReadOnlyInput != 0
 	sc.MachineRun = selMachineRun != 0
+	sc.StaticAnalysis = selStaticAnalysis != 0
 
 	ec.MachineConfig = &bf.MachineConfig{
 		MaxInstructionExecutionCount: machineMaxExec,
 		MemoryCellCount:             machineCellCount,
+		OverflowMode:                bf.OverflowMode(machineOverflowMode),
+		CellWidth:                   bf.CellWidth(machineCellWidth),
+		BookmarkRegisters:           machineBookmarks,
+		CmpSwap:                     machineCmpSwap != 0,
+		MaxLoopIterations:           machineMaxLoopIterations,
+	}
+	if err := ec.MachineConfig.Check(); err != nil {
+		return fmt.Errorf("bad eval_machine_cell_width: %w", err)
 	}
 
 	pop.PopulationConfig = &PopulationConfig{
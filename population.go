@@ -17,6 +17,7 @@ type Population struct {
 	CurrentGeneration uint
 	Units             []*Unit
 	PopulationConfig  *PopulationConfig
+	Modules           []Module // mined from the elite, best first
 	persist           *Persistence
 }
 
@@ -74,19 +75,23 @@ func (p *Population) SynthesizeUnitsWithTimeout(timeout time.Duration) error {
 			defer wg.Done()
 			evaluator := NewEvaluator(p.PopulationConfig.EvaluatorConfig)
 			selector := NewSelector(p.PopulationConfig.SelectorConfig)
+			mutator := p.unitMutator()
 			var local []rankedUnit
 			start := time.Now()
 			var tested uint
 
 			for found.Load() < uint64(keep) && !timedOut.Load() {
 				tested++
-				unit := NewUnitFromConfig(p.PopulationConfig.UnitConfig)
+				unit := mutator.NewUnit(p.PopulationConfig.UnitConfig)
 
 				var eval *Evaluation
-				if rounds > 1 {
-					eval = evaluator.EvaluateMultiRoundWithCellCounts(unit, rounds, synthInput, synthInput)
-				} else {
-					eval = evaluator.EvaluateWithCellCounts(unit, synthInput, synthInput)
+				reason := selector.Prescreen(unit)
+				if reason == 0 {
+					if rounds > 1 {
+						eval = evaluator.EvaluateMultiRoundWithCellCounts(unit, rounds, synthInput, synthInput)
+					} else {
+						eval = evaluator.EvaluateWithCellCounts(unit, synthInput, synthInput)
+					}
 				}
 
 				if tested%10000 == 0 {
@@ -100,7 +105,10 @@ func (p *Population) SynthesizeUnitsWithTimeout(timeout time.Duration) error {
 					}
 				}
 
-				if reason := selector.Select(unit, eval, 0); reason != 0 {
+				if reason == 0 {
+					reason = selector.Select(unit, eval, 0)
+				}
+				if reason != 0 {
 					continue
 				}
 
@@ -241,6 +249,10 @@ func (p *Population) ProcessGenerationInMemory(units []*Unit) []*Unit {
 			defer wg.Done()
 			evaluator := NewEvaluator(config.EvaluatorConfig)
 			for _, unit := range chunk {
+				if selector.Prescreen(unit) != 0 {
+					unit.Alive = Dead
+					continue
+				}
 				var eval *Evaluation
 				if rounds > 1 {
 					eval = evaluator.EvaluateMultiRoundWithCellCounts(unit, rounds, effectiveInput, effectiveOutput)
@@ -301,6 +313,17 @@ func (p *Population) ProcessGenerationInMemory(units []*Unit) []*Unit {
 		log.Printf("Phase 2: culled %d, %d alive (%v)", culled, len(alive), time.Since(cullStart))
 	}
 
+	// Module mining — promote snippets recurring in the elite to modules
+	if p.miningDue() {
+		miningStart := time.Now()
+		programs := elitePrograms(alive, ranker, config.UnitConfig.ModuleConfig.EliteCount)
+		if err := p.PromoteModules(programs); err != nil {
+			log.Printf("Warning: module mining failed: %v", err)
+		} else {
+			log.Printf("Module mining: %d modules from %d elite units (%v)", len(p.Modules), len(programs), time.Since(miningStart))
+		}
+	}
+
 	// Phase 3 — Reproduce (parallel Mitosis, pure CPU)
 	mitosisStart := time.Now()
 	maxOffspring := config.MaxOffspring
@@ -335,6 +358,17 @@ func (p *Population) ProcessGenerationInMemory(units []*Unit) []*Unit {
 	// Parallel Mitosis — pass ID generators for permanent ID assignment
 	unitIDs := p.persist.UnitIDs
 	insIDs := p.persist.InstructionIDs
+	mutator := p.unitMutator()
+
+	// Crossover mates, best-first
+	uc := config.UnitConfig
+	var pool mates
+	if uc.CrossoverRate > 0 {
+		pool = make(mates, len(rankedUnits))
+		for i, ru := range rankedUnits {
+			pool[i] = ru.unit
+		}
+	}
 
 	type chunk struct {
 		units []*Unit
@@ -359,7 +393,7 @@ func (p *Population) ProcessGenerationInMemory(units []*Unit) []*Unit {
 			var local []*Unit
 			for j := s; j < e; j++ {
 				for n := uint(0); n < offspringCounts[j]; n++ {
-					local = append(local, rankedUnits[j].unit.Mitosis(unitIDs, insIDs))
+					local = append(local, breed(rankedUnits[j].unit, pool, uc.CrossoverRate, uc.Crossover, mutator, unitIDs, insIDs))
 				}
 			}
 			chunks[idx].units = local
@@ -482,7 +516,7 @@ func (p *Population) LoadUnitsIntoMemory() ([]*Unit, error) {
 }
 
 func queryUnits(db *sql.DB, popID uint) ([]*Unit, error) {
-	rows, err := db.Query(`SELECT id, population_id, parent_id, age, generation, lifespan, mutation_chance, alive
+	rows, err := db.Query(`SELECT id, population_id, parent_id, second_parent_id, age, generation, lifespan, mutation_chance, alive
 		FROM units WHERE population_id = ? AND alive = ?`, popID, Alive)
 	if err != nil {
 		return nil, err
@@ -492,14 +526,18 @@ func queryUnits(db *sql.DB, popID uint) ([]*Unit, error) {
 	var units []*Unit
 	for rows.Next() {
 		u := &Unit{}
-		var parentID sql.NullInt64
-		if err := rows.Scan(&u.ID, &u.PopulationID, &parentID, &u.Age, &u.Generation, &u.Lifespan, &u.MutationChance, &u.Alive); err != nil {
+		var parentID, secondParentID sql.NullInt64
+		if err := rows.Scan(&u.ID, &u.PopulationID, &parentID, &secondParentID, &u.Age, &u.Generation, &u.Lifespan, &u.MutationChance, &u.Alive); err != nil {
 			return nil, err
 		}
 		if parentID.Valid {
 			pid := uint(parentID.Int64)
 			u.ParentID = &pid
 		}
+		if secondParentID.Valid {
+			pid := uint(secondParentID.Int64)
+			u.SecondParentID = &pid
+		}
 		units = append(units, u)
 	}
 	return units, rows.Err()
@@ -548,6 +586,7 @@ func (p *Population) PersistPopulationState(units []*Unit) error {
 		u.ID = p.persist.UnitIDs.Next()
 		u.PopulationID = p.ID
 		u.ParentID = nil
+		u.SecondParentID = nil
 		u.Parent = nil
 		for _, ins := range u.Instructions {
 			ins.ID = p.persist.InstructionIDs.Next()
@@ -565,9 +604,9 @@ func (p *Population) PersistPopulationState(units []*Unit) error {
 
 		// Insert current generation as new records
 		for _, u := range batch {
-			if _, err := tx.Exec(`INSERT INTO units (id, population_id, parent_id, age, generation, lifespan, mutation_chance, alive)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-				u.ID, u.PopulationID, nullableUint(u.ParentID), u.Age, u.Generation, u.Lifespan, u.MutationChance, u.Alive); err != nil {
+			if _, err := tx.Exec(`INSERT INTO units (id, population_id, parent_id, second_parent_id, age, generation, lifespan, mutation_chance, alive)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				u.ID, u.PopulationID, nullableUint(u.ParentID), nullableUint(u.SecondParentID), u.Age, u.Generation, u.Lifespan, u.MutationChance, u.Alive); err != nil {
 				return fmt.Errorf("failed to insert unit: %w", err)
 			}
 
@@ -627,6 +666,10 @@ func (p *Population) ProcessGeneration() error {
 			evaluator := NewEvaluator(config.EvaluatorConfig)
 			rounds := config.EvaluatorConfig.EvalRounds
 			for _, unit := range chunk {
+				if reason := selector.Prescreen(unit); reason != 0 {
+					unit.Die(reason)
+					continue
+				}
 				var eval *Evaluation
 				if rounds > 1 {
 					eval = evaluator.EvaluateMultiRoundWithCellCounts(unit, rounds, effectiveInput, effectiveOutput)
@@ -661,6 +704,14 @@ func (p *Population) ProcessGeneration() error {
 	log.Printf("Phase 1 complete: %d/%d alive (load: %v, eval: %v, persist: %v)",
 		alive, len(allUnits), loadTime, evalTime, persistTime)
 
+	// Phase 1.5 — Rank the children evaluated for the first time against their parents
+	phaseStart = time.Now()
+	if ranked, err := p.RecordMutationEffects(ranker); err != nil {
+		log.Printf("Warning: recording mutation effects failed: %v", err)
+	} else {
+		log.Printf("Phase 1.5 complete: recorded mutation effects of %d children (%v)", ranked, time.Since(phaseStart))
+	}
+
 	// Phase 2 — Competitive Cull (uses in-memory evals, no DB query)
 	if config.CarryingCapacity > 0 {
 		phaseStart = time.Now()
@@ -674,11 +725,24 @@ func (p *Population) ProcessGeneration() error {
 		log.Printf("Phase 2 complete: culled %d, %d alive (%v)", culled, alive, time.Since(phaseStart))
 	}
 
+	// Module mining — promote snippets recurring in the elite to modules
+	if p.miningDue() {
+		phaseStart = time.Now()
+		programs := elitePrograms(allUnits, ranker, config.UnitConfig.ModuleConfig.EliteCount)
+		if err := p.PromoteModules(programs); err != nil {
+			return fmt.Errorf("module mining failed: %w", err)
+		}
+		log.Printf("Module mining: %d modules from %d elite units (%v)", len(p.Modules), len(programs), time.Since(phaseStart))
+	}
+
 	// Phase 3 — Reproduce (uses in-memory units, no DB reload)
 	phaseStart = time.Now()
 	log.Printf("Phase 3: Reproduce")
 	reproducer := NewReproducer(p.persist, p.ID, config.MaxOffspring, p.persist.Config.BatchSize, ranker,
 		p.persist.UnitIDs, p.persist.InstructionIDs)
+	reproducer.Mutator = p.unitMutator()
+	reproducer.CrossoverRate = config.UnitConfig.CrossoverRate
+	reproducer.Crossover = config.UnitConfig.Crossover
 	offspring, err := reproducer.ReproduceFromUnits(allUnits)
 	if err != nil {
 		return fmt.Errorf("reproduction failed: %w", err)
@@ -878,6 +942,10 @@ func evaluateAndSelectBatch(units []*Unit, config *PopulationConfig, effectiveIn
 			defer wg.Done()
 			evaluator := NewEvaluator(config.EvaluatorConfig)
 			for _, unit := range chunk {
+				if reason := selector.Prescreen(unit); reason != 0 {
+					unit.Die(reason)
+					continue
+				}
 				var eval *Evaluation
 				if rounds > 1 {
 					eval = evaluator.EvaluateMultiRoundWithCellCounts(unit, rounds, effectiveInput, effectiveOutput)
@@ -954,6 +1022,14 @@ func (p *Population) ProcessGenerationStreaming() error {
 	alive := p.GetAliveCount()
 	log.Printf("Phase 1 complete: %d/%d alive (%v)", alive, totalUnits.Load(), time.Since(phaseStart))
 
+	// Phase 1.5 — Rank the children evaluated for the first time against their parents
+	phaseStart = time.Now()
+	if ranked, err := p.RecordMutationEffects(ranker); err != nil {
+		log.Printf("Warning: recording mutation effects failed: %v", err)
+	} else {
+		log.Printf("Phase 1.5 complete: recorded mutation effects of %d children (%v)", ranked, time.Since(phaseStart))
+	}
+
 	// Phase 2 — Competitive Cull (lightweight — queries eval scores only)
 	if config.CarryingCapacity > 0 {
 		phaseStart = time.Now()
@@ -977,11 +1053,27 @@ func (p *Population) ProcessGenerationStreaming() error {
 			dEval, dIns, dMut, dTomb, time.Since(phaseStart))
 	}
 
+	// Module mining — promote snippets recurring in the elite to modules
+	if p.miningDue() {
+		phaseStart = time.Now()
+		programs, err := p.queryElitePrograms(ranker, config.UnitConfig.ModuleConfig.EliteCount)
+		if err == nil {
+			err = p.PromoteModules(programs)
+		}
+		if err != nil {
+			return fmt.Errorf("module mining failed: %w", err)
+		}
+		log.Printf("Module mining: %d modules from %d elite units (%v)", len(p.Modules), len(programs), time.Since(phaseStart))
+	}
+
 	// Phase 3 — Streaming Reproduce
 	phaseStart = time.Now()
 	log.Printf("Phase 3: Streaming reproduce")
 	reproducer := NewReproducer(p.persist, p.ID, config.MaxOffspring, p.persist.Config.BatchSize, ranker,
 		p.persist.UnitIDs, p.persist.InstructionIDs)
+	reproducer.Mutator = p.unitMutator()
+	reproducer.CrossoverRate = config.UnitConfig.CrossoverRate
+	reproducer.Crossover = config.UnitConfig.Crossover
 	offspring, err := reproducer.ReproduceStreaming(batchSize)
 	if err != nil {
 		return fmt.Errorf("streaming reproduction failed: %w", err)
@@ -1059,7 +1151,7 @@ func (p *Population) Prune(dryRun bool) (*PruneResult, error) {
 	log.Printf("Prune: %d alive units found", result.AliveUnits)
 
 	// Step 2: Trace ancestry iteratively — find all ancestors of alive units
-	// Each iteration: collect parent_ids not yet in keepSet, group by shard, query parents
+	// Each iteration: collect parent_ids (and second_parent_ids) not yet in keepSet, group by shard, query parents
 	for iteration := 1; ; iteration++ {
 		// Collect IDs we need parents for, grouped by shard
 		needParents := make([][]uint, persist.NumShards)
@@ -1069,7 +1161,7 @@ func (p *Population) Prune(dryRun bool) (*PruneResult, error) {
 		}
 
 		// Query parent_ids from each shard in parallel
-		shardParentMaps := make([]map[uint]uint, persist.NumShards)
+		shardParentMaps := make([]map[uint][]uint, persist.NumShards)
 		for i := uint(0); i < persist.NumShards; i++ {
 			errs[i] = nil
 		}
@@ -1096,10 +1188,12 @@ func (p *Population) Prune(dryRun bool) (*PruneResult, error) {
 		// Add newly discovered parents to keepSet
 		newParents := 0
 		for _, m := range shardParentMaps {
-			for _, parentID := range m {
-				if parentID != 0 && !keepSet[parentID] {
-					keepSet[parentID] = true
-					newParents++
+			for _, parentIDs := range m {
+				for _, parentID := range parentIDs {
+					if parentID != 0 && !keepSet[parentID] {
+						keepSet[parentID] = true
+						newParents++
+					}
 				}
 			}
 		}
@@ -1180,6 +1274,11 @@ func (p *Population) Prune(dryRun bool) (*PruneResult, error) {
 					}
 					counts[shard].deletedMut = uint(cnt)
 
+					if err := tx.QueryRow("SELECT COUNT(*) FROM gene_mutations WHERE unit_id NOT IN (SELECT id FROM prune_keep) AND unit_id IN (SELECT id FROM units WHERE population_id = ?)", p.ID).Scan(&cnt); err != nil {
+						return err
+					}
+					counts[shard].deletedMut += uint(cnt)
+
 					_, _ = tx.Exec("DROP TABLE IF EXISTS prune_keep")
 					return nil
 				}); err != nil {
@@ -1242,6 +1341,14 @@ func pruneShardData(db *sql.DB, popID uint, keepIDs []uint, counts *pruneShardCo
 		n, _ := res.RowsAffected()
 		counts.deletedMut = uint(n)
 
+		// Delete gene mutations of pruned units
+		res, err = tx.Exec("DELETE FROM gene_mutations WHERE unit_id NOT IN (SELECT id FROM prune_keep) AND unit_id IN (SELECT id FROM units WHERE population_id = ?)", popID)
+		if err != nil {
+			return fmt.Errorf("delete gene mutations: %w", err)
+		}
+		n, _ = res.RowsAffected()
+		counts.deletedMut += uint(n)
+
 		// Delete instructions for pruned units
 		res, err = tx.Exec("DELETE FROM instructions WHERE unit_id NOT IN (SELECT id FROM prune_keep) AND unit_id IN (SELECT id FROM units WHERE population_id = ?)", popID)
 		if err != nil {
@@ -1397,14 +1504,38 @@ func createTestSchema(db *sql.DB) error {
 			unit_instruction_count INTEGER,
 			unit_ins_op_set_count INTEGER,
 			unit_lifespan INTEGER,
+			unit_crossover_rate REAL DEFAULT 0,
+			unit_crossover INTEGER DEFAULT 0,
+			unit_mutation TEXT,
+			unit_gene_mutation_chance REAL DEFAULT 0,
+			unit_adapt_mutation_chance INTEGER DEFAULT 0,
+			unit_mutation_chance_step REAL DEFAULT 0,
+			unit_mutation_chance_min REAL DEFAULT 0,
+			unit_mutation_chance_max REAL DEFAULT 0,
+			unit_mutation_mode INTEGER DEFAULT 0,
+			unit_ins_prefabs TEXT,
+			unit_ins_prefab_weights TEXT,
+			unit_modules TEXT,
 			eval_machine_max_instruction_execution_count INTEGER,
 			eval_machine_memory_cell_count INTEGER,
+			eval_machine_overflow_mode INTEGER DEFAULT 0,
+			eval_machine_cell_width INTEGER DEFAULT 0,
+			eval_machine_bookmark_registers INTEGER DEFAULT 0,
+			eval_machine_cmp_swap INTEGER DEFAULT 0,
+			eval_machine_max_loop_iterations INTEGER DEFAULT 0,
 			eval_input_cell_count INTEGER,
 			eval_output_cell_count INTEGER,
 			eval_synthesis_input_cell_count INTEGER,
 			eval_input_cell_start INTEGER,
 			eval_input_cell_step INTEGER,
 			eval_eval_rounds INTEGER,
+			eval_optimize INTEGER DEFAULT 0,
+			eval_backend INTEGER DEFAULT 0,
+			eval_input_offset INTEGER DEFAULT 0,
+			eval_output_offset INTEGER DEFAULT 0,
+			eval_scratch_offset INTEGER DEFAULT 0,
+			eval_scratch_cell_count INTEGER DEFAULT 0,
+			eval_read_only_input INTEGER DEFAULT 0,
 			sel_machine_run INTEGER,
 			sel_set_fidelity INTEGER,
 			sel_sortedness INTEGER,
@@ -1414,6 +1545,7 @@ func createTestSchema(db *sql.DB) error {
 			sel_sortedness_step INTEGER DEFAULT 0,
 			sel_instruction_count INTEGER,
 			sel_instructions_executed INTEGER,
+			sel_static_analysis INTEGER DEFAULT 0,
 			fit_sortedness_priority INTEGER,
 			fit_set_fidelity_priority INTEGER,
 			fit_efficiency_priority INTEGER
@@ -1422,6 +1554,7 @@ func createTestSchema(db *sql.DB) error {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			population_id INTEGER,
 			parent_id INTEGER,
+			second_parent_id INTEGER,
 			age INTEGER DEFAULT 0,
 			generation INTEGER DEFAULT 0,
 			lifespan INTEGER,
@@ -1444,6 +1577,38 @@ func createTestSchema(db *sql.DB) error {
 			op INTEGER,
 			chance REAL
 		)`,
+		`CREATE TABLE IF NOT EXISTS gene_mutations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			unit_id INTEGER,
+			meta_op INTEGER,
+			position1 INTEGER,
+			position2 INTEGER,
+			chance REAL
+		)`,
+		`CREATE TABLE IF NOT EXISTS mutation_effects (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			population_id INTEGER,
+			unit_id INTEGER,
+			parent_id INTEGER,
+			gene INTEGER DEFAULT 0,
+			meta_op INTEGER,
+			op INTEGER,
+			parent_machine_run INTEGER,
+			parent_sortedness INTEGER,
+			parent_set_fidelity INTEGER,
+			parent_instructions_executed INTEGER,
+			outcome INTEGER,
+			sortedness_delta INTEGER,
+			set_fidelity_delta INTEGER,
+			instructions_executed_delta INTEGER
+		)`,
+		`CREATE TABLE IF NOT EXISTS modules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			population_id INTEGER,
+			generation INTEGER,
+			ops TEXT,
+			support INTEGER
+		)`,
 		`CREATE TABLE IF NOT EXISTS evaluations (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			unit_id INTEGER,
@@ -1453,6 +1618,8 @@ func createTestSchema(db *sql.DB) error {
 			instruction_count INTEGER,
 			instructions_executed INTEGER,
 			machine_error TEXT,
+			machine_error_kind INTEGER DEFAULT 0,
+			machine_error_loop INTEGER,
 			input BLOB,
 			output BLOB
 		)`,
@@ -1466,6 +1633,10 @@ func createTestSchema(db *sql.DB) error {
 		`CREATE INDEX IF NOT EXISTS idx_evaluations_unit_id ON evaluations(unit_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_tombstones_unit_id ON tombstones(unit_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_mutations_instruction_id ON mutations(instruction_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_gene_mutations_unit_id ON gene_mutations(unit_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_mutation_effects_unit_id ON mutation_effects(unit_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_mutation_effects_pop_outcome ON mutation_effects(population_id, outcome)`,
+		`CREATE INDEX IF NOT EXISTS idx_modules_population_id ON modules(population_id)`,
 	}
 
 	for _, stmt := range stmts {
@@ -30,19 +30,55 @@ func TestPopulationConfigRoundTrip(t *t.T) {
 			MutationChance:   0.35,
 			InstructionCount: 15,
 			InstructionConfig: &InstructionConfig{
-				OpSetCount: 8,
+				OpSetCount:    8,
+				Prefabs:       map[string]string{"clear": "[-]", "swap": ">[-<+>]<"},
+				PrefabWeights: map[string]float32{"clear": 2.5},
+			},
+			Lifespan:      50,
+			CrossoverRate: 0.5,
+			Crossover:     CROSSOVER_TWO_POINT,
+			MutationConfig: &MutationConfig{
+				MetaOpWeights:     map[string]float32{"push": 0.5, "replace": 4},
+				OpWeights:         map[string]float32{"[": 0, "]": 0},
+				GeneMetaOpWeights: map[string]float32{"move": 2},
+			},
+			MutationMode:        MUTATION_SYNTACTIC,
+			GeneMutationChance:  0.05,
+			AdaptMutationChance: true,
+			MutationChanceStep:  0.2,
+			MutationChanceMin:   0.01,
+			MutationChanceMax:   0.5,
+			ModuleConfig: &ModuleConfig{
+				MineEvery:  10,
+				EliteCount: 50,
+				MinLength:  3,
+				MaxLength:  12,
+				MinSupport: 5,
+				MaxModules: 8,
+				Chance:     0.3,
 			},
-			Lifespan: 50,
 		},
 		EvaluatorConfig: &EvaluatorConfig{
 			MachineConfig: &bf.MachineConfig{
 				MaxInstructionExecutionCount: 5000,
 				MemoryCellCount:              30,
+				OverflowMode:                 bf.OVERFLOW_WRAP,
+				CellWidth:                    bf.CELL_WIDTH_16,
+				BookmarkRegisters:            3,
+				CmpSwap:                      true,
+				MaxLoopIterations:            40,
 			},
-			InputCellCount:  12,
-			OutputCellCount: 12,
-			InputCellStart:  2,
-			InputCellStep:   10,
+			InputCellCount:   12,
+			OutputCellCount:  12,
+			InputCellStart:   2,
+			InputCellStep:    10,
+			Optimize:         true,
+			Backend:          bf.BACKEND_CLOSURE,
+			InputOffset:      2,
+			OutputOffset:     16,
+			ScratchOffset:    28,
+			ScratchCellCount: 2,
+			ReadOnlyInput:    true,
 		},
 		SelectorConfig: &SelectorConfig{
 			MachineRun:           true,
@@ -50,6 +86,7 @@ func TestPopulationConfigRoundTrip(t *t.T) {
 			Sortedness:           40,
 			InstructionCount:     200,
 			InstructionsExecuted: 8000,
+			StaticAnalysis:       true,
 		},
 		FitnessConfig: &FitnessConfig{
 			SortednessPriority:  1,
@@ -66,17 +103,7 @@ func TestPopulationConfigRoundTrip(t *t.T) {
 
 	// Load it back
 	loaded := &Population{}
-	row := db.QueryRow(`SELECT id, current_generation,
-		unit_count, synthesis_pool, carrying_capacity, elitism, max_offspring,
-		unit_mutation_chance, unit_instruction_count, unit_ins_op_set_count, unit_lifespan,
-		eval_machine_max_instruction_execution_count, eval_machine_memory_cell_count,
-		eval_input_cell_count, eval_output_cell_count, eval_synthesis_input_cell_count,
-		eval_input_cell_start, eval_input_cell_step, eval_eval_rounds,
-		sel_machine_run, sel_set_fidelity, sel_sortedness,
-		sel_set_fidelity_start, sel_set_fidelity_step, sel_sortedness_start, sel_sortedness_step,
-		sel_instruction_count, sel_instructions_executed,
-		fit_sortedness_priority, fit_set_fidelity_priority, fit_efficiency_priority
-		FROM populations WHERE id = ?`, pop.ID)
+	row := db.QueryRow(populationSelect+" WHERE id = ?", pop.ID)
 
 	if err := scanPopulation(row, loaded); err != nil {
 		t.Fatalf("Failed to load population: %v", err)
@@ -98,3 +125,58 @@ func TestPopulationConfigRoundTrip(t *t.T) {
 		}
 	}
 }
+
+func TestMigrateSchemaAddsMissingColumns(t *t.T) {
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory DB: %v", err)
+	}
+	defer db.Close()
+
+	// A populations table from before eval_machine_overflow_mode existed
+	if _, err := db.Exec(`CREATE TABLE populations (id INTEGER PRIMARY KEY AUTOINCREMENT)`); err != nil {
+		t.Fatalf("Failed to create old table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO populations (id) VALUES (1)`); err != nil {
+		t.Fatalf("Failed to insert old population: %v", err)
+	}
+	// And an evaluations table from before machine_error_kind existed
+	if _, err := db.Exec(`CREATE TABLE evaluations (id INTEGER PRIMARY KEY AUTOINCREMENT, machine_error TEXT)`); err != nil {
+		t.Fatalf("Failed to create old table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO evaluations (id, machine_error) VALUES (1, 'OP_DEC failed'), (2, NULL)`); err != nil {
+		t.Fatalf("Failed to insert old evaluation: %v", err)
+	}
+
+	// And a units table from before crossover
+	if _, err := db.Exec(`CREATE TABLE units (id INTEGER PRIMARY KEY AUTOINCREMENT, parent_id INTEGER)`); err != nil {
+		t.Fatalf("Failed to create old table: %v", err)
+	}
+
+	if err := migrateSchema(db); err != nil {
+		t.Fatalf("Failed to migrate schema: %v", err)
+	}
+	// Migrating twice must be a no-op
+	if err := migrateSchema(db); err != nil {
+		t.Fatalf("Failed to migrate schema a second time: %v", err)
+	}
+
+	var mode uint8
+	if err := db.QueryRow(`SELECT eval_machine_overflow_mode FROM populations WHERE id = 1`).Scan(&mode); err != nil {
+		t.Fatalf("Failed to read migrated column: %v", err)
+	}
+	if bf.OverflowMode(mode) != bf.OVERFLOW_ERROR {
+		t.Errorf("Migrated overflow mode [%v] is not [%v]", bf.OverflowMode(mode), bf.OVERFLOW_ERROR)
+	}
+
+	// Errors from before typed errors are unclassified, the rest are none
+	for id, expected := range map[int]bf.ErrorKind{1: bf.ERR_OTHER, 2: bf.ERR_NONE} {
+		var kind uint8
+		if err := db.QueryRow(`SELECT machine_error_kind FROM evaluations WHERE id = ?`, id).Scan(&kind); err != nil {
+			t.Fatalf("Failed to read migrated column: %v", err)
+		}
+		if bf.ErrorKind(kind) != expected {
+			t.Errorf("Migrated machine error kind [%v] of evaluation %d is not [%v]", bf.ErrorKind(kind), id, expected)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package genetic_sort
+
+import (
+	"fmt"
+	"sort"
+	str "strings"
+
+	bf "nickandperla.net/brainfuck"
+)
+
+// UsePrefabs makes mu's new genes string together the named prefabs of a
+// library instead of its dialect's, drawn with the odds of weights. Prefabs
+// that weights leaves out weigh 1, and nil weights draw uniformly. Every
+// prefab must be ops of mu's dialect with balanced loops. A nil library
+// leaves the dialect's prefabs in place.
+func (mu *Mutator) UsePrefabs(prefabs map[string]string, weights map[string]float32) error {
+	if prefabs == nil {
+		if weights != nil {
+			return fmt.Errorf("Prefab weights without prefabs")
+		}
+		return nil
+	}
+	if len(prefabs) == 0 {
+		return fmt.Errorf("The prefab library is empty")
+	}
+
+	names := make([]string, 0, len(prefabs))
+	for name := range prefabs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dialect := string(mu.Ops)
+	library := make([]string, len(names))
+	for i, name := range names {
+		ops := prefabs[name]
+		if ops == "" {
+			return fmt.Errorf("Prefab [%s] has no ops", name)
+		}
+		for _, op := range []byte(ops) {
+			if str.IndexByte(dialect, op) < 0 {
+				return fmt.Errorf("Prefab [%s] op [%c] is not one of %s", name, op, dialect)
+			}
+		}
+		if !balanced(ops) {
+			return fmt.Errorf("Prefab [%s] [%s] has unbalanced loops", name, ops)
+		}
+		library[i] = ops
+	}
+
+	prefabWeights, err := newWeights("prefab", weights, names)
+	if err != nil {
+		return err
+	}
+	mu.Prefabs, mu.prefabWeights = library, prefabWeights
+	return nil
+}
+
+// balanced reports whether every loop of ops opens and closes.
+func balanced(ops string) bool {
+	depth := 0
+	for _, op := range []byte(ops) {
+		switch op {
+		case bf.OP_WHILE:
+			depth++
+		case bf.OP_WHILE_END:
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// Mutator returns a Mutator that draws c's genes on a Machine configured by
+// mc: from c's prefab library, which may only use ops mc enables, or from
+// mc's dialect when c has none. nil means the default MachineConfig. Build it
+// once and draw every gene with it.
+func (c *InstructionConfig) Mutator(mc *bf.MachineConfig) (*Mutator, error) {
+	mu := NewMutator(mc)
+	if c == nil {
+		return mu, nil
+	}
+	if err := mu.UsePrefabs(c.Prefabs, c.PrefabWeights); err != nil {
+		return nil, err
+	}
+	if c.OpSetCount > 0 {
+		mu.GeneOpSetCount = c.OpSetCount
+	}
+	return mu, nil
+}
+
+// configMutator returns the Mutator the config constructors draw with: the
+// default one, or one with config's prefab library on the default machine.
+func configMutator(config *InstructionConfig) (*Mutator, error) {
+	if config == nil || config.Prefabs == nil && config.PrefabWeights == nil {
+		return defaultMutator, nil
+	}
+	return config.Mutator(nil)
+}
@@ -10,6 +10,7 @@ type Processor struct {
 	Persistor  UnitPersistor
 	Evaluator  *Evaluator
 	Selector   *Selector
+	Mutator    *Mutator
 	Generation uint
 }
 
@@ -27,6 +28,10 @@ FOR:
 			}
 			var offspring []*Unit
 			for _, unit := range units {
+				if reason := p.Selector.Prescreen(unit); reason != 0 {
+					unit.Die(reason)
+					continue
+				}
 				eval := p.Evaluator.Evaluate(unit)
 				reason := p.Selector.Select(unit, eval, p.Generation)
 				if reason != 0 {
@@ -39,7 +44,7 @@ FOR:
 					unit.Die(FailedLifespan)
 					continue
 				}
-				offspring = append(offspring, unit.Mitosis(nil, nil))
+				offspring = append(offspring, unit.Mitosis(p.Mutator, nil, nil))
 			}
 			p.Persistor(&units)
 			if len(offspring) > 0 {
@@ -56,6 +61,7 @@ func NewProcessor(loader UnitLoader, persistor UnitPersistor, evaluator *Evaluat
 		Input:     loader,
 		Evaluator: evaluator,
 		Selector:  selector,
+		Mutator:   NewMutator(evaluator.Config.MachineConfig),
 		Persistor: persistor,
 	}
 }
@@ -86,6 +92,10 @@ FOR:
 				break FOR
 			}
 			for _, unit := range units {
+				if reason := p.Selector.Prescreen(unit); reason != 0 {
+					unit.Die(reason)
+					continue
+				}
 				var eval *Evaluation
 				if p.InputCellCount > 0 {
 					eval = p.Evaluator.EvaluateWithCellCounts(unit, p.InputCellCount, p.OutputCellCount)
@@ -21,6 +21,11 @@ type Reproducer struct {
 	BatchSize    uint
 	UnitIDs      *IDGenerator
 	InsIDs       *IDGenerator
+	Mutator      *Mutator // nil means the single bookmark dialect
+	// CrossoverRate is the chance an offspring is bred from two parents by
+	// Crossover, 0 for Mitosis only
+	CrossoverRate float32
+	Crossover     CrossoverMode
 }
 
 func NewReproducer(persist *Persistence, popID, maxOffspring, batchSize uint, ranker *FitnessRanker,
@@ -240,6 +245,8 @@ func (r *Reproducer) ReproduceStreaming(evalBatchSize int) (uint, error) {
 		}
 		offspringMap[eval.UnitID] = count
 	}
+	ranks := r.ranks(evals)
+	parents := latestEvals(evals)
 
 	// Step 2: Capture MAX(id) per shard before reproduction starts
 	maxIDs := make([]uint, r.persist.NumShards)
@@ -263,7 +270,8 @@ func (r *Reproducer) ReproduceStreaming(evalBatchSize int) (uint, error) {
 	pop := &Population{ID: r.PopulationID, persist: r.persist}
 
 	err := pop.ForEachUnitBatch(evalBatchSize, maxIDs, func(units []*Unit) error {
-		// Parallel Mitosis for this batch
+		// Parallel Mitosis for this batch, crossing with mates from the batch
+		pool := r.matePool(units, ranks)
 		cpus := runtime.NumCPU()
 		chunkSize := len(units) / cpus
 		if chunkSize == 0 {
@@ -295,7 +303,7 @@ func (r *Reproducer) ReproduceStreaming(evalBatchSize int) (uint, error) {
 						count = 1
 					}
 					for n := uint(0); n < count; n++ {
-						local = append(local, unit.Mitosis(r.UnitIDs, r.InsIDs))
+						local = append(local, breed(unit, pool, r.CrossoverRate, r.Crossover, r.Mutator, r.UnitIDs, r.InsIDs))
 					}
 				}
 				chunks[idx].units = local
@@ -323,7 +331,10 @@ func (r *Reproducer) ReproduceStreaming(evalBatchSize int) (uint, error) {
 
 		// Persist this batch of offspring immediately using bulk inserts
 		err := r.persist.writeSharded(batchOffspring, func(tx *sql.Tx, batch []*Unit) error {
-			return bulkInsertUnits(tx, batch)
+			if err := bulkInsertUnits(tx, batch); err != nil {
+				return err
+			}
+			return bulkInsertMutationEffects(tx, batch, parents)
 		})
 		if err != nil {
 			return fmt.Errorf("failed to save offspring batch: %w", err)
@@ -343,6 +354,46 @@ func (r *Reproducer) ReproduceStreaming(evalBatchSize int) (uint, error) {
 	return result, nil
 }
 
+// ranks maps each unit of evals, sorted best-first, to its rank. Only
+// crossover needs ranks, so it's nil when crossover is off.
+func (r *Reproducer) ranks(evals []Evaluation) map[uint]int {
+	if r.CrossoverRate <= 0 {
+		return nil
+	}
+	ranks := make(map[uint]int, len(evals))
+	for rank, eval := range evals {
+		ranks[eval.UnitID] = rank
+	}
+	return ranks
+}
+
+// latestEvals maps each unit of evals to its evaluation, the baseline its
+// Mitosis children's mutation effects are ranked against.
+func latestEvals(evals []Evaluation) map[uint]*Evaluation {
+	latest := make(map[uint]*Evaluation, len(evals))
+	for i := range evals {
+		latest[evals[i].UnitID] = &evals[i]
+	}
+	return latest
+}
+
+// matePool returns the ranked units among units best-first, as crossover mates.
+func (r *Reproducer) matePool(units []*Unit, ranks map[uint]int) mates {
+	if ranks == nil {
+		return nil
+	}
+	pool := make(mates, 0, len(units))
+	for _, u := range units {
+		if _, ok := ranks[u.ID]; ok {
+			pool = append(pool, u)
+		}
+	}
+	sort.Slice(pool, func(i, j int) bool {
+		return ranks[pool[i].ID] < ranks[pool[j].ID]
+	})
+	return pool
+}
+
 func (r *Reproducer) reproduceFromData(allUnits []*Unit, evals []Evaluation, maxOffspring uint) (uint, error) {
 	if len(evals) == 0 {
 		return 0, nil
@@ -363,8 +414,11 @@ func (r *Reproducer) reproduceFromData(allUnits []*Unit, evals []Evaluation, max
 		}
 		offspringMap[eval.UnitID] = count
 	}
+	ranks := r.ranks(evals)
+	parents := latestEvals(evals)
 
 	// Parallel Mitosis: split units across CPUs
+	pool := r.matePool(allUnits, ranks)
 	cpus := runtime.NumCPU()
 	chunkSize := len(allUnits) / cpus
 	if chunkSize == 0 {
@@ -395,7 +449,7 @@ func (r *Reproducer) reproduceFromData(allUnits []*Unit, evals []Evaluation, max
 					count = 1
 				}
 				for n := uint(0); n < count; n++ {
-					local = append(local, unit.Mitosis(r.UnitIDs, r.InsIDs))
+					local = append(local, breed(unit, pool, r.CrossoverRate, r.Crossover, r.Mutator, r.UnitIDs, r.InsIDs))
 				}
 			}
 			chunks[idx].units = local
@@ -421,7 +475,10 @@ func (r *Reproducer) reproduceFromData(allUnits []*Unit, evals []Evaluation, max
 	// Persist using sharded bulk inserts
 	if totalOffspring > 0 {
 		err := r.persist.writeSharded(allOffspring, func(tx *sql.Tx, batch []*Unit) error {
-			return bulkInsertUnits(tx, batch)
+			if err := bulkInsertUnits(tx, batch); err != nil {
+				return err
+			}
+			return bulkInsertMutationEffects(tx, batch, parents)
 		})
 		if err != nil {
 			return 0, fmt.Errorf("failed to save offspring: %w", err)
@@ -25,7 +25,7 @@ func seedUnitsForReproduction(t *test.T, db *sql.DB, popID uint, n int, unitIDs,
 	rng = newPooledRand(99)
 	units := make([]*Unit, n)
 	for i := 0; i < n; i++ {
-		u := NewUnitFromConfig(&UnitConfig{
+		u := defaultMutator.NewUnit(&UnitConfig{
 			MutationChance:    0.1,
 			InstructionCount:  2,
 			InstructionConfig: &InstructionConfig{OpSetCount: 2},
@@ -181,3 +181,120 @@ func TestReproduceOffspringHaveParent(t *test.T) {
 		t.Errorf("Offspring generation should be %d, got %d", parents[0].Generation+1, generation)
 	}
 }
+
+func TestReproduceCrossoverRecordsBothParents(t *test.T) {
+	db, persist := setupReproducerTestDB(t)
+	defer db.Close()
+	rng = newPooledRand(42)
+
+	pop := insertReproducerTestPopulation(t, db, 5)
+	parents := seedUnitsForReproduction(t, db, pop.ID, 5, persist.UnitIDs, persist.InstructionIDs)
+	isParent := make(map[uint]bool, len(parents))
+	for _, u := range parents {
+		isParent[u.ID] = true
+	}
+
+	reproducer := NewReproducer(persist, pop.ID, 3, 100, NewFitnessRanker(nil), persist.UnitIDs, persist.InstructionIDs)
+	reproducer.CrossoverRate = 1
+	reproducer.Crossover = CROSSOVER_UNIFORM
+	if _, err := reproducer.Reproduce(); err != nil {
+		t.Fatalf("Reproduce returned error: %v", err)
+	}
+
+	rows, err := db.Query("SELECT id, parent_id, second_parent_id FROM units WHERE population_id = ? AND parent_id IS NOT NULL", pop.ID)
+	if err != nil {
+		t.Fatalf("Failed to query offspring: %v", err)
+	}
+	var crossed []uint
+	for rows.Next() {
+		var id, parentID uint
+		var secondParentID sql.NullInt64
+		if err := rows.Scan(&id, &parentID, &secondParentID); err != nil {
+			t.Fatalf("Failed to scan offspring: %v", err)
+		}
+		if !secondParentID.Valid {
+			continue
+		}
+		second := uint(secondParentID.Int64)
+		if !isParent[parentID] || !isParent[second] || parentID == second {
+			t.Errorf("Offspring [%d] has parents [%d %d], expected two different seeded units", id, parentID, second)
+		}
+		crossed = append(crossed, id)
+	}
+	rows.Close()
+	if len(crossed) == 0 {
+		t.Fatalf("No offspring with a second parent at crossover rate 1")
+	}
+
+	// Lineage tracing follows both parents
+	lineage, err := queryUnitParents(db, crossed)
+	if err != nil {
+		t.Fatalf("Failed to query unit parents: %v", err)
+	}
+	for _, id := range crossed {
+		if len(lineage[id]) != 2 {
+			t.Errorf("Offspring [%d] traces to parents %v, expected two", id, lineage[id])
+		}
+	}
+
+	u, err := loadSingleUnit(db, crossed[0])
+	if err != nil {
+		t.Fatalf("Failed to load offspring: %v", err)
+	}
+	if u.SecondParentID == nil || *u.SecondParentID != lineage[crossed[0]][1] {
+		t.Errorf("Loaded offspring second parent [%v] is not [%d]", u.SecondParentID, lineage[crossed[0]][1])
+	}
+}
+
+func TestReproduceRecordsGeneMutations(t *test.T) {
+	db, persist := setupReproducerTestDB(t)
+	defer db.Close()
+	rng = newPooledRand(42)
+
+	pop := insertReproducerTestPopulation(t, db, 5)
+	seedUnitsForReproduction(t, db, pop.ID, 5, persist.UnitIDs, persist.InstructionIDs)
+
+	reproducer := NewReproducer(persist, pop.ID, 1, 100, NewFitnessRanker(nil), persist.UnitIDs, persist.InstructionIDs)
+	reproducer.Mutator = NewUnitMutator(&UnitConfig{
+		GeneMutationChance: 1,
+		InstructionConfig:  &InstructionConfig{OpSetCount: 2},
+	}, &EvaluatorConfig{})
+	if _, err := reproducer.Reproduce(); err != nil {
+		t.Fatalf("Reproduce returned error: %v", err)
+	}
+
+	// Every offspring took one gene mutation, and its gene count shows it
+	rows, err := db.Query(`SELECT g.meta_op, g.position1, g.position2,
+		(SELECT COUNT(*) FROM instructions WHERE unit_id = u.id)
+		FROM units u LEFT JOIN gene_mutations g ON g.unit_id = u.id
+		WHERE u.population_id = ? AND u.parent_id IS NOT NULL`, pop.ID)
+	if err != nil {
+		t.Fatalf("Failed to query gene mutations: %v", err)
+	}
+	defer rows.Close()
+	offspring := 0
+	for rows.Next() {
+		var metaOp sql.NullInt64
+		var position1, position2 sql.NullInt64
+		var genes int
+		if err := rows.Scan(&metaOp, &position1, &position2, &genes); err != nil {
+			t.Fatalf("Failed to scan gene mutation: %v", err)
+		}
+		offspring++
+		if !metaOp.Valid {
+			t.Errorf("Offspring has no gene mutation recorded")
+			continue
+		}
+		// The seeded parents have 2 genes
+		expected := map[byte]int{DUPLICATE_GENE_OP: 3, DELETE_GENE_OP: 1, INSERT_GENE_OP: 3, MOVE_GENE_OP: 2}[byte(metaOp.Int64)]
+		if genes != expected {
+			t.Errorf("Offspring of gene mutation [%s] has [%d] genes, expected [%d]", GeneMetaOpName(byte(metaOp.Int64)), genes, expected)
+		}
+		if !position2.Valid && byte(metaOp.Int64) != DELETE_GENE_OP {
+			t.Errorf("Gene mutation [%s] recorded no position", GeneMetaOpName(byte(metaOp.Int64)))
+		}
+	}
+	if offspring != 5 {
+		t.Errorf("Found %d offspring, expected 5", offspring)
+	}
+}
@@ -1,5 +1,9 @@
 package genetic_sort
 
+import (
+	"nickandperla.net/brainfuck/analyze"
+)
+
 type Selector struct {
 	Config *SelectorConfig
 }
@@ -11,6 +15,10 @@ type SelectorConfig struct {
 	InstructionCount     uint `toml:"instruction_count"`
 	InstructionsExecuted uint `toml:"instructions_executed"`
 
+	// If set along with MachineRun, programs the static analyzer proves can
+	// never complete a run are rejected by Prescreen without running them.
+	StaticAnalysis bool `toml:"static_analysis"`
+
 	// Curriculum fields: if set, thresholds ramp from *Start to SetFidelity/Sortedness
 	// over generations, increasing by 1 every *Step generations.
 	SetFidelityStart byte `toml:"set_fidelity_start"`
@@ -51,6 +59,20 @@ func (c *SelectorConfig) EffectiveSortedness(generation uint) byte {
 
 type SelectFailReason uint
 
+// Prescreen rejects hopeless units before they are evaluated. Only programs
+// that would fail Select on MachineRun anyway are rejected, so enabling it
+// never changes which units survive, only how much it costs to find out.
+// Returns 0 when the unit still needs to be evaluated.
+func (s *Selector) Prescreen(u *Unit) SelectFailReason {
+	if !s.Config.StaticAnalysis || !s.Config.MachineRun {
+		return 0
+	}
+	if analyze.Analyze(Instructions(u.Instructions).ToProgram()).AlwaysFails {
+		return FailedStaticAnalysis
+	}
+	return 0
+}
+
 func (s *Selector) Select(u *Unit, e *Evaluation, generation uint) SelectFailReason {
 
 	if s.Config.MachineRun && !e.MachineRun {
@@ -77,3 +77,27 @@ func TestSelectFailures(t *test.T) {
 		t.Errorf("Selector.Select() unexpectedly succeeded at FailedInstructionsExecuted")
 	}
 }
+
+func TestPrescreen(t *test.T) {
+	config := makeSelectorConfig()
+	s := NewSelector(config)
+	hopeless := &Unit{Instructions: []*Instruction{NewInstruction("[-]+"), NewInstruction("[]")}}
+	fine := &Unit{Instructions: []*Instruction{NewInstruction("[-]+"), NewInstruction("[-]")}}
+
+	if result := s.Prescreen(hopeless); result != 0 {
+		t.Errorf("Selector.Prescreen() rejected a unit with static analysis disabled: %v", result)
+	}
+
+	config.StaticAnalysis = true
+	if result := s.Prescreen(hopeless); result != FailedStaticAnalysis {
+		t.Errorf("Selector.Prescreen() unexpectedly succeeded at FailedStaticAnalysis")
+	}
+	if result := s.Prescreen(fine); result != 0 {
+		t.Errorf("Selector.Prescreen() unexpectedly rejected a runnable unit: %v", result)
+	}
+
+	config.MachineRun = false
+	if result := s.Prescreen(hopeless); result != 0 {
+		t.Errorf("Selector.Prescreen() rejected a unit without requiring a machine run: %v", result)
+	}
+}
@@ -0,0 +1,208 @@
+package genetic_sort
+
+import (
+	"fmt"
+
+	bf "nickandperla.net/brainfuck"
+)
+
+// MutationMode picks how Mutations treat loop brackets.
+type MutationMode uint8
+
+const (
+	// Any meta op may add, drop or move any op, brackets included
+	MUTATION_FREE MutationMode = iota
+	// Mutations keep every gene's loops as they are: brackets only come and
+	// go in matched pairs, by WRAP_OP and UNWRAP_OP, and every other meta op
+	// only adds, drops, moves or replaces ops that aren't brackets
+	MUTATION_SYNTACTIC
+)
+
+// SYNTACTIC_META_OP_SET is what MUTATION_SYNTACTIC Mutations draw from.
+var SYNTACTIC_META_OP_SET = []byte{
+	PUSH_OP,
+	POP_OP,
+	SHIFT_OP,
+	UNSHIFT_OP,
+	INSERT_OP,
+	DELETE_OP,
+	SWAP_OP,
+	REPLACE_OP,
+	META_NO_OP,
+	WRAP_OP,
+	UNWRAP_OP,
+}
+
+func (mm MutationMode) String() string {
+	switch mm {
+	case MUTATION_FREE:
+		return "free"
+	case MUTATION_SYNTACTIC:
+		return "syntactic"
+	}
+	return fmt.Sprintf("MutationMode(%d)", uint8(mm))
+}
+
+func (mm MutationMode) MarshalText() ([]byte, error) {
+	return []byte(mm.String()), nil
+}
+
+func (mm *MutationMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "free":
+		*mm = MUTATION_FREE
+	case "syntactic":
+		*mm = MUTATION_SYNTACTIC
+	default:
+		return fmt.Errorf("Unknown mutation mode [%s]. Expected one of free, syntactic", text)
+	}
+	return nil
+}
+
+// SetMode makes mu's Mutations work in mode. Syntactic Mutations never draw
+// brackets as the op they add. It drops mu's weights, so Weigh after it.
+func (mu *Mutator) SetMode(mode MutationMode) {
+	ops := mu.Ops
+	metaOps := META_OP_SET
+	if mode == MUTATION_SYNTACTIC {
+		ops = make([]byte, 0, len(mu.Ops))
+		for _, op := range mu.Ops {
+			if !isBracket(op) {
+				ops = append(ops, op)
+			}
+		}
+		metaOps = SYNTACTIC_META_OP_SET
+	}
+	mu.Ops, mu.MetaOps, mu.Mode = ops, metaOps, mode
+	mu.metaOpWeights, mu.opWeights = nil, nil
+}
+
+func isBracket(op byte) bool {
+	return op == bf.OP_WHILE || op == bf.OP_WHILE_END
+}
+
+// applySyntactic applies m to the ops of i, raw, without unbalancing a loop.
+// Meta ops with nothing they may change leave the ops alone.
+func (m *Mutation) applySyntactic(i *Instruction) {
+	raw := i.cachedOps
+
+	switch m.MetaOP {
+	case PUSH_OP:
+		raw = append(raw, m.Op)
+	case POP_OP:
+		if !isBracket(raw[len(raw)-1]) {
+			raw = raw[:len(raw)-1]
+		}
+	case SHIFT_OP:
+		if !isBracket(raw[0]) {
+			raw = raw[1:]
+		}
+	case UNSHIFT_OP:
+		raw = append([]byte{m.Op}, raw...)
+	case INSERT_OP:
+		pos1 := uint(rng.Intn(len(raw)))
+		raw = append(raw[:pos1], append([]byte{m.Op}, raw[pos1:]...)...)
+		m.Position1 = &pos1
+	case DELETE_OP:
+		if pos1, ok := pickNonBracket(raw); ok {
+			raw = append(raw[:pos1], raw[pos1+1:]...)
+			m.Position1 = &pos1
+		}
+	case SWAP_OP:
+		pos1, ok1 := pickNonBracket(raw)
+		pos2, ok2 := pickNonBracket(raw)
+		if ok1 && ok2 {
+			raw[pos1], raw[pos2] = raw[pos2], raw[pos1]
+			m.Position1, m.Position2 = &pos1, &pos2
+		}
+	case REPLACE_OP:
+		if pos1, ok := pickNonBracket(raw); ok {
+			raw[pos1] = m.Op
+			m.Position1 = &pos1
+		}
+	case WRAP_OP:
+		if from, to, ok := pickBalancedSpan(raw); ok {
+			wrapped := make([]byte, 0, len(raw)+2)
+			wrapped = append(wrapped, raw[:from]...)
+			wrapped = append(wrapped, bf.OP_WHILE)
+			wrapped = append(wrapped, raw[from:to]...)
+			wrapped = append(wrapped, bf.OP_WHILE_END)
+			raw = append(wrapped, raw[to:]...)
+			m.Position1, m.Position2 = &from, &to
+		}
+	case INSERT_MODULE_OP:
+		if m.Module != nil {
+			pos1 := uint(rng.Intn(len(raw)))
+			raw = insertModule(raw, pos1, m.Module)
+			m.Position1 = &pos1
+		}
+	case UNWRAP_OP:
+		if open, end, ok := pickLoop(raw); ok {
+			raw = append(raw[:end], raw[end+1:]...)
+			raw = append(raw[:open], raw[open+1:]...)
+			m.Position1, m.Position2 = &open, &end
+		}
+	}
+
+	i.cachedOps = raw
+	i.Ops = nil // mark compressed form as stale
+	i.Mutations = append(i.Mutations, m)
+}
+
+// pickNonBracket picks the position of an op of raw that isn't a bracket.
+func pickNonBracket(raw []byte) (uint, bool) {
+	var positions []uint
+	for pos, op := range raw {
+		if !isBracket(op) {
+			positions = append(positions, uint(pos))
+		}
+	}
+	if len(positions) == 0 {
+		return 0, false
+	}
+	return positions[rng.Intn(len(positions))], true
+}
+
+// pickBalancedSpan picks a non empty span [from, to) of raw whose brackets
+// all match inside it, so a loop around it nests with every other loop.
+func pickBalancedSpan(raw []byte) (uint, uint, bool) {
+	from := rng.Intn(len(raw))
+	var ends []uint
+	depth := 0
+	for pos := from; pos < len(raw) && depth >= 0; pos++ {
+		switch raw[pos] {
+		case bf.OP_WHILE:
+			depth++
+		case bf.OP_WHILE_END:
+			depth--
+		}
+		if depth == 0 {
+			ends = append(ends, uint(pos+1))
+		}
+	}
+	if len(ends) == 0 {
+		return 0, 0, false
+	}
+	return uint(from), ends[rng.Intn(len(ends))], true
+}
+
+// pickLoop picks the positions of a matched bracket pair of raw.
+func pickLoop(raw []byte) (uint, uint, bool) {
+	var opens, loops [][2]uint
+	for pos, op := range raw {
+		switch {
+		case op == bf.OP_WHILE:
+			opens = append(opens, [2]uint{uint(pos)})
+		case op == bf.OP_WHILE_END && len(opens) > 0:
+			loop := opens[len(opens)-1]
+			loop[1] = uint(pos)
+			opens = opens[:len(opens)-1]
+			loops = append(loops, loop)
+		}
+	}
+	if len(loops) == 0 {
+		return 0, 0, false
+	}
+	loop := loops[rng.Intn(len(loops))]
+	return loop[0], loop[1], true
+}
@@ -0,0 +1,100 @@
+package genetic_sort
+
+import (
+	"strings"
+	"testing"
+)
+
+// bracketResidue drops matched bracket pairs from the brackets of program
+// until only the unmatched ones are left.
+func bracketResidue(program string) string {
+	var brackets []byte
+	for i := 0; i < len(program); i++ {
+		if program[i] == '[' || program[i] == ']' {
+			if program[i] == ']' && len(brackets) > 0 && brackets[len(brackets)-1] == '[' {
+				brackets = brackets[:len(brackets)-1]
+				continue
+			}
+			brackets = append(brackets, program[i])
+		}
+	}
+	return string(brackets)
+}
+
+func TestSyntacticMutations(t *testing.T) {
+	rng = newPooledRand(42)
+
+	mu := NewMutator(nil)
+	mu.SetMode(MUTATION_SYNTACTIC)
+	for _, op := range mu.Ops {
+		if isBracket(op) {
+			t.Fatalf("Syntactic Mutator draws bracket [%c]", op)
+		}
+	}
+
+	genes := []string{"+[->+<]>", "]+[-", "[[", "]]-", "+", "[-][<]>*[>]^[-^+^]", "[]"}
+	applied := map[byte]int{}
+	for _, ops := range genes {
+		gene := NewInstruction(ops)
+		residue := bracketResidue(ops)
+		for n := 0; n < 300; n++ {
+			before := string(gene.ToProgram())
+			m := mu.NewMutation(0)
+			m.Apply(gene)
+			after := string(gene.ToProgram())
+
+			if got := bracketResidue(after); got != residue {
+				t.Fatalf("%s took [%s] to [%s], its unmatched brackets went from [%s] to [%s]", MetaOpName(m.MetaOP), before, after, residue, got)
+			}
+			if m.MetaOP != WRAP_OP && m.MetaOP != UNWRAP_OP && strings.Count(after, "[") != strings.Count(before, "[") {
+				t.Fatalf("%s took [%s] to [%s], changing its brackets", MetaOpName(m.MetaOP), before, after)
+			}
+			if before != after {
+				applied[m.MetaOP]++
+			}
+			if len(after) == 0 {
+				gene = NewInstruction(ops)
+			}
+		}
+	}
+	for _, metaOp := range SYNTACTIC_META_OP_SET {
+		if metaOp != META_NO_OP && applied[metaOp] == 0 {
+			t.Errorf("%s never changed a gene", MetaOpName(metaOp))
+		}
+	}
+
+	// A wrap spans whole loops only
+	for n := 0; n < 50; n++ {
+		gene := NewInstruction("+]-")
+		m := &Mutation{MetaOP: WRAP_OP, syntactic: true}
+		m.Apply(gene)
+		if m.Position1 != nil && *m.Position1 == 1 {
+			t.Fatalf("Wrapped a span starting at an unmatched bracket: %s", gene.ToProgram())
+		}
+	}
+
+	// Wrap and unwrap take weights in syntactic mode only
+	if err := mu.Weigh(&MutationConfig{MetaOpWeights: map[string]float32{"wrap": 2, "unwrap": 0.5}}); err != nil {
+		t.Errorf("Syntactic Mutator rejected wrap weights: %v", err)
+	}
+	if err := mu.Weigh(&MutationConfig{OpWeights: map[string]float32{"[": 1}}); err == nil {
+		t.Errorf("Syntactic Mutator accepted a bracket weight")
+	}
+	if err := NewMutator(nil).Weigh(&MutationConfig{MetaOpWeights: map[string]float32{"wrap": 1}}); err == nil {
+		t.Errorf("Free Mutator accepted a wrap weight")
+	}
+}
+
+func TestMutationModeText(t *testing.T) {
+	for _, mode := range []MutationMode{MUTATION_FREE, MUTATION_SYNTACTIC} {
+		text, _ := mode.MarshalText()
+		var parsed MutationMode
+		if err := parsed.UnmarshalText(text); err != nil || parsed != mode {
+			t.Errorf("Mutation mode [%v] parsed back as [%v], error [%v]", mode, parsed, err)
+		}
+	}
+	var parsed MutationMode
+	if err := parsed.UnmarshalText([]byte("strict")); err == nil {
+		t.Errorf("Unknown mutation mode parsed without error")
+	}
+}
@@ -1,6 +1,7 @@
 package genetic_sort
 
 import (
+	"fmt"
 	"log"
 )
 
@@ -9,6 +10,45 @@ type UnitConfig struct {
 	InstructionCount  uint               `toml:"instruction_count"`
 	InstructionConfig *InstructionConfig `toml:"instruction"`
 	Lifespan          uint               `toml:"lifespan"`
+	// CrossoverRate is the chance an offspring is bred from two parents
+	// instead of by Mitosis.
+	CrossoverRate float32       `toml:"crossover_rate"`
+	Crossover     CrossoverMode `toml:"crossover"`
+	// MutationConfig weighs what mutations draw, nil draws uniformly
+	MutationConfig *MutationConfig `toml:"mutation"`
+	MutationMode   MutationMode    `toml:"mutation_mode"`
+	// GeneMutationChance is the chance an offspring gains, loses or
+	// reorders a gene
+	GeneMutationChance float32 `toml:"gene_mutation_chance"`
+	// AdaptMutationChance lets each offspring's MutationChance drift from
+	// its parents', log-normally by MutationChanceStep, within
+	// [MutationChanceMin, MutationChanceMax]. MutationChance seeds it.
+	AdaptMutationChance bool    `toml:"adapt_mutation_chance"`
+	MutationChanceStep  float32 `toml:"mutation_chance_step"`
+	MutationChanceMin   float32 `toml:"mutation_chance_min"`
+	MutationChanceMax   float32 `toml:"mutation_chance_max"`
+	// ModuleConfig mines modules from the elite for fresh genes and
+	// INSERT_MODULE_OP, nil mines nothing
+	ModuleConfig *ModuleConfig `toml:"modules"`
+}
+
+// ChanceAdaptation returns how offspring adapt their MutationChance, or nil
+// when they don't.
+func (uc *UnitConfig) ChanceAdaptation() (*ChanceAdaptation, error) {
+	if !uc.AdaptMutationChance {
+		return nil, nil
+	}
+	if uc.MutationChanceStep < 0 {
+		return nil, fmt.Errorf("Mutation chance step [%v] is negative", uc.MutationChanceStep)
+	}
+	if !(0 <= uc.MutationChanceMin && uc.MutationChanceMin <= uc.MutationChanceMax && uc.MutationChanceMax <= 1) {
+		return nil, fmt.Errorf("Mutation chance bounds [%v, %v] are not a range inside [0, 1]", uc.MutationChanceMin, uc.MutationChanceMax)
+	}
+	return &ChanceAdaptation{
+		Step: uc.MutationChanceStep,
+		Min:  uc.MutationChanceMin,
+		Max:  uc.MutationChanceMax,
+	}, nil
 }
 
 type Unit struct {
@@ -16,7 +56,9 @@ type Unit struct {
 	PopulationID   uint
 	Parent         *Unit
 	ParentID       *uint
+	SecondParentID *uint // the mate of a Crossover child
 	Instructions   []*Instruction
+	GeneMutations  []*GeneMutation
 	Age            uint
 	Generation     uint
 	Lifespan       uint
@@ -26,8 +68,21 @@ type Unit struct {
 	Tombstone      *Tombstone
 }
 
-func NewUnitFromConfig(config *UnitConfig) *Unit {
-	return NewUnitFromRandom(
+// NewUnitFromConfig creates a random Unit of config on the default machine.
+// A config with a prefab library draws its genes from it, and fails if it's
+// bad.
+func NewUnitFromConfig(config *UnitConfig) (*Unit, error) {
+	mu, err := configMutator(config.InstructionConfig)
+	if err != nil {
+		return nil, err
+	}
+	return mu.NewUnit(config), nil
+}
+
+// NewUnit creates a random Unit whose Instructions are drawn from the
+// Mutator's dialect.
+func (mu *Mutator) NewUnit(config *UnitConfig) *Unit {
+	return mu.newUnitFromRandom(
 		config.MutationChance,
 		config.InstructionCount,
 		config.InstructionConfig,
@@ -39,10 +94,18 @@ func NewUnitFromRandom(
 	instructionCount uint,
 	config *InstructionConfig,
 	lifeSpan uint) *Unit {
+	return defaultMutator.newUnitFromRandom(mutationChance, instructionCount, config, lifeSpan)
+}
+
+func (mu *Mutator) newUnitFromRandom(
+	mutationChance float32,
+	instructionCount uint,
+	config *InstructionConfig,
+	lifeSpan uint) *Unit {
 
 	var ins Instructions = make(Instructions, instructionCount)
 	for i := 0; uint(i) < instructionCount; i++ {
-		ins[i] = NewInstructionFromConfig(config)
+		ins[i] = mu.NewInstruction(config.OpSetCount)
 	}
 
 	return &Unit{
@@ -76,6 +139,10 @@ func (u *Unit) Clone() *Unit {
 		pid := *u.ParentID
 		clone.ParentID = &pid
 	}
+	if u.SecondParentID != nil {
+		pid := *u.SecondParentID
+		clone.SecondParentID = &pid
+	}
 	clone.Instructions = make([]*Instruction, len(u.Instructions))
 	for i, ins := range u.Instructions {
 		clone.Instructions[i] = ins.Clone()
@@ -95,37 +162,61 @@ func (u *Unit) Die(reason SelectFailReason) *Tombstone {
 	return u.Tombstone
 }
 
-// Asexual reproduction. unitIDs and insIDs assign permanent IDs to the
-// child and its instructions. Pass nil to leave IDs at 0.
-func (u *Unit) Mitosis(unitIDs, insIDs *IDGenerator) *Unit {
+// Asexual reproduction. mutator supplies the ops of new mutations, nil
+// means the single bookmark dialect. unitIDs and insIDs assign permanent IDs
+// to the child and its instructions. Pass nil to leave IDs at 0.
+func (u *Unit) Mitosis(mutator *Mutator, unitIDs, insIDs *IDGenerator) *Unit {
 	u2 := u.Clone()
+	u2.ParentID = &u.ID
+	u2.SecondParentID = nil
+	u2.Generation = u.Generation + 1
+	return u2.grow(mutator, unitIDs, insIDs)
+}
 
+// grow readies u, holding copies of its parents' genes, as a newborn: it gets
+// its IDs and starts life, its MutationChance may adapt, its genes may be
+// rearranged, and each gene ages and may mutate.
+func (u *Unit) grow(mutator *Mutator, unitIDs, insIDs *IDGenerator) *Unit {
+	if mutator == nil {
+		mutator = defaultMutator
+	}
 	if unitIDs != nil {
-		u2.ID = unitIDs.Next()
+		u.ID = unitIDs.Next()
 	} else {
-		u2.ID = 0
+		u.ID = 0
+	}
+	u.Age = 0
+	u.Alive = Alive
+	u.Evaluations = nil
+	u.Tombstone = nil
+	u.GeneMutations = nil
+
+	if mutator.Adaptation != nil {
+		u.MutationChance = mutator.Adaptation.Adapt(u.MutationChance)
+	}
+	if mutator.GeneMutationChance > 0 {
+		if chance := rng.Float32(); chance < mutator.GeneMutationChance {
+			mutator.NewGeneMutation(chance).Apply(u, mutator)
+		}
+	}
+	for _, m := range u.GeneMutations {
+		m.UnitID = u.ID
 	}
-	u2.ParentID = &u.ID
-	u2.Generation = u.Generation + 1
-	u2.Age = 0
-	u2.Alive = Alive
-	u2.Evaluations = nil
-	u2.Tombstone = nil
 
-	for _, gene := range u2.Instructions {
+	for _, gene := range u.Instructions {
 		if insIDs != nil {
 			gene.ID = insIDs.Next()
 		} else {
 			gene.ID = 0
 		}
-		gene.UnitID = u2.ID
+		gene.UnitID = u.ID
 		gene.Mutations = nil
 		gene.IncrementAge()
 		chance := rng.Float32()
-		if chance < u2.MutationChance {
-			NewMutation(chance).Apply(gene)
+		if chance < u.MutationChance {
+			mutator.NewMutation(chance).Apply(gene)
 		}
 	}
 
-	return u2
+	return u
 }
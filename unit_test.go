@@ -2,6 +2,8 @@ package genetic_sort
 
 import (
 	mop "reflect"
+	"regexp"
+	"strings"
 	test "testing"
 )
 
@@ -16,7 +18,10 @@ func TestNewUnitFuncs(t *test.T) {
 		InstructionConfig: &InstructionConfig{OpSetCount: 5},
 	}
 
-	unit1 := NewUnitFromConfig(config)
+	unit1, err := NewUnitFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewUnitFromConfig() failed. %v", err)
+	}
 
 	if SEED42_INSTRUCTION_STRING != Instructions(unit1.Instructions).ToProgram() {
 		t.Errorf("Unit instructions do not match expected:\nExpected: %v\nActual: %v ",
@@ -41,7 +46,10 @@ func TestUnitClone(t *test.T) {
 		InstructionConfig: &InstructionConfig{OpSetCount: 5},
 	}
 
-	unit1 := NewUnitFromConfig(config)
+	unit1, err := NewUnitFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewUnitFromConfig() failed. %v", err)
+	}
 
 	unit2 := unit1.Clone()
 
@@ -72,9 +80,12 @@ func TestMitosis(t *test.T) {
 		InstructionConfig: &InstructionConfig{OpSetCount: 5},
 	}
 
-	unit1 := NewUnitFromConfig(config)
+	unit1, err := NewUnitFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewUnitFromConfig() failed. %v", err)
+	}
 
-	unit2 := unit1.Mitosis(nil, nil)
+	unit2 := unit1.Mitosis(nil, nil, nil)
 
 	if mop.DeepEqual(unit1, unit2) {
 		t.Errorf("Unexpected DeepEqual between original and offspring units from Mitosis()")
@@ -88,6 +99,89 @@ func TestMitosis(t *test.T) {
 	}
 
 	if !mop.DeepEqual(expectedMutations, actualMutations) {
-		t.Errorf("Unexpected gene mutations from calling Unit.Mitosis(nil, nil)\nExpected: %v\nActual:%v", expectedMutations, actualMutations)
+		t.Errorf("Unexpected gene mutations from calling Unit.Mitosis(nil, nil, nil)\nExpected: %v\nActual:%v", expectedMutations, actualMutations)
+	}
+}
+
+func TestCrossover(t *test.T) {
+	rng = newPooledRand(42)
+
+	// Gene i of a is i+1 incs and of b i+1 decs, so every child gene tells
+	// which parent, and which index, it came from
+	a, b := &Unit{ID: 1, Generation: 3, Lifespan: 10}, &Unit{ID: 2, Generation: 5, Lifespan: 20}
+	for i := 0; i < 6; i++ {
+		a.Instructions = append(a.Instructions, NewInstruction(strings.Repeat("+", i+1)))
+	}
+	for i := 0; i < 4; i++ {
+		b.Instructions = append(b.Instructions, NewInstruction(strings.Repeat("-", i+1)))
+	}
+
+	tests := []struct {
+		mode    CrossoverMode
+		length  int
+		pattern *regexp.Regexp
+	}{
+		{CROSSOVER_SINGLE_POINT, 4, regexp.MustCompile(`^a*b*$`)},
+		{CROSSOVER_TWO_POINT, 6, regexp.MustCompile(`^a*b*a*aa$`)},
+		{CROSSOVER_UNIFORM, 6, regexp.MustCompile(`^[ab]{4}aa$`)},
+	}
+	for _, tt := range tests {
+		mixed := false
+		for trial := 0; trial < 100; trial++ {
+			child := a.Crossover(b, tt.mode, nil, nil, nil)
+
+			if child.ParentID == nil || *child.ParentID != a.ID || child.SecondParentID == nil || *child.SecondParentID != b.ID {
+				t.Fatalf("%v child parents [%v %v] are not [%d %d]", tt.mode, child.ParentID, child.SecondParentID, a.ID, b.ID)
+			}
+			if child.Generation != 6 || child.Lifespan != a.Lifespan || child.Alive != Alive {
+				t.Fatalf("%v child generation [%d], lifespan [%d], alive [%d] are not [6 %d %d]",
+					tt.mode, child.Generation, child.Lifespan, child.Alive, a.Lifespan, Alive)
+			}
+			if len(child.Instructions) != tt.length {
+				t.Fatalf("%v child has [%d] genes, expected [%d]", tt.mode, len(child.Instructions), tt.length)
+			}
+
+			var origin strings.Builder
+			for i, gene := range child.Instructions {
+				ops := Instructions{gene}.ToProgram()
+				if len(ops) != i+1 {
+					t.Fatalf("%v child gene [%d] is [%s], out of line with its parents", tt.mode, i, ops)
+				}
+				if ops[0] == '+' {
+					origin.WriteByte('a')
+				} else {
+					origin.WriteByte('b')
+				}
+				if gene == a.Instructions[i] || (i < len(b.Instructions) && gene == b.Instructions[i]) {
+					t.Fatalf("%v child gene [%d] is shared with a parent", tt.mode, i)
+				}
+			}
+			if !tt.pattern.MatchString(origin.String()) {
+				t.Fatalf("%v child genes came from [%s], expected %v", tt.mode, origin.String(), tt.pattern)
+			}
+			if strings.Contains(origin.String(), "a") && strings.Contains(origin.String(), "b") {
+				mixed = true
+			}
+		}
+		if !mixed {
+			t.Errorf("%v never mixed the genes of both parents", tt.mode)
+		}
+	}
+}
+
+func TestCrossoverModeText(t *test.T) {
+	for _, mode := range []CrossoverMode{CROSSOVER_SINGLE_POINT, CROSSOVER_TWO_POINT, CROSSOVER_UNIFORM} {
+		text, _ := mode.MarshalText()
+		var parsed CrossoverMode
+		if err := parsed.UnmarshalText(text); err != nil || parsed != mode {
+			t.Errorf("Crossover mode [%v] parsed back as [%v], error [%v]", mode, parsed, err)
+		}
+	}
+	var parsed CrossoverMode = CROSSOVER_UNIFORM
+	if err := parsed.UnmarshalText(nil); err != nil || parsed != CROSSOVER_SINGLE_POINT {
+		t.Errorf("Empty crossover mode parsed as [%v], error [%v], expected [%v]", parsed, err, CROSSOVER_SINGLE_POINT)
+	}
+	if err := parsed.UnmarshalText([]byte("three_point")); err == nil {
+		t.Errorf("Unknown crossover mode parsed without error")
 	}
 }